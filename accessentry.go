@@ -0,0 +1,136 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"golang.org/x/oauth2"
+)
+
+// ErrNoAccessEntry is returned by CheckAccessEntry when the cluster has no access entry for the principal,
+// tokens would only yield 401 Unauthorized from clusters using the API authentication mode.
+var ErrNoAccessEntry = errors.New("eksauth: no access entry for the principal")
+
+// PrincipalARN returns the IAM principal ARN of a caller ARN, converting assumed role sessions
+// (arn:aws:sts::123456789012:assumed-role/name/session) into the role (arn:aws:iam::123456789012:role/name).
+func PrincipalARN(callerARN string) string {
+	if role, ok := roleFromAssumedRole(callerARN); ok {
+		return role
+	}
+	return callerARN
+}
+
+// principalMatches reports if an access entry for entryARN applies to the principal.
+// Assumed role sessions do not include the path of the role, so role and user paths are ignored.
+func principalMatches(entryARN, principalARN string) bool {
+	if entryARN == principalARN {
+		return true
+	}
+	entry, err := arn.Parse(entryARN)
+	if err != nil {
+		return false
+	}
+	principal, err := arn.Parse(principalARN)
+	if err != nil {
+		return false
+	}
+	if entry.Partition != principal.Partition || entry.Service != principal.Service || entry.AccountID != principal.AccountID {
+		return false
+	}
+	entryType, _, _ := strings.Cut(entry.Resource, "/")
+	principalType, _, _ := strings.Cut(principal.Resource, "/")
+	entryName := entry.Resource[strings.LastIndex(entry.Resource, "/")+1:]
+	principalName := principal.Resource[strings.LastIndex(principal.Resource, "/")+1:]
+	return entryType == principalType && entryName == principalName
+}
+
+// CheckAccessEntry returns the ARN of the access entry of the cluster matching principalARN (see PrincipalARN)
+// using eks:ListAccessEntries, or an error wrapping ErrNoAccessEntry if there is none.
+// Clusters using the CONFIG_MAP authentication mode fail with an InvalidRequestException, see AuthenticationMode.
+func CheckAccessEntry(ctx context.Context, client eks.ListAccessEntriesAPIClient, clusterName, principalARN string) (string, error) {
+	name, region := splitClusterName(clusterName)
+	var optFns []func(*eks.Options)
+	if region != "" {
+		optFns = append(optFns, func(o *eks.Options) {
+			o.Region = region
+		})
+	}
+	principalARN = PrincipalARN(principalARN)
+	paginator := eks.NewListAccessEntriesPaginator(client, &eks.ListAccessEntriesInput{
+		ClusterName: &name,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx, optFns...)
+		if err != nil {
+			return "", &Error{Op: "ListAccessEntries", ClusterName: clusterName, Err: wrapThrottled("ListAccessEntries", err)}
+		}
+		for _, entryARN := range page.AccessEntries {
+			if principalMatches(entryARN, principalARN) {
+				return entryARN, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: %s in cluster %q", ErrNoAccessEntry, principalARN, clusterName)
+}
+
+// AccessEntryTokenSource is an oauth2.TokenSource that checks the cluster has an access entry for the caller
+// (see CheckAccessEntry) before returning tokens, failing fast with ErrNoAccessEntry instead of 401 Unauthorized.
+// The check is repeated only when the caller identity changes.
+type AccessEntryTokenSource struct {
+	Source      oauth2.TokenSource
+	Client      eks.ListAccessEntriesAPIClient
+	ClusterName string
+	// Identity provides the caller identity, it must use the same credentials as Source.
+	Identity *IdentityCache
+
+	mu      sync.Mutex
+	checked string
+}
+
+// NewAccessEntryTokenSource wraps src in an AccessEntryTokenSource.
+func NewAccessEntryTokenSource(src oauth2.TokenSource, client eks.ListAccessEntriesAPIClient, clusterName string, identity *IdentityCache) *AccessEntryTokenSource {
+	return &AccessEntryTokenSource{Source: src, Client: client, ClusterName: clusterName, Identity: identity}
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (ts *AccessEntryTokenSource) Token() (*oauth2.Token, error) {
+	return ts.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+func (ts *AccessEntryTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	identity, err := ts.Identity.Identity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ts.mu.Lock()
+	checked := ts.checked == identity.ARN
+	ts.mu.Unlock()
+	if !checked {
+		if _, err := CheckAccessEntry(ctx, ts.Client, ts.ClusterName, identity.ARN); err != nil {
+			return nil, err
+		}
+		ts.mu.Lock()
+		ts.checked = identity.ARN
+		ts.mu.Unlock()
+	}
+	return TokenWithContext(ctx, ts.Source)
+}
+
+// Invalidate implements the Invalidator interface, the access entry is checked again on the next call.
+func (ts *AccessEntryTokenSource) Invalidate() {
+	ts.mu.Lock()
+	ts.checked = ""
+	ts.mu.Unlock()
+	Invalidate(ts.Source)
+}
+
+// Close implements the io.Closer interface.
+func (ts *AccessEntryTokenSource) Close() error {
+	return closeTokenSource(ts.Source)
+}
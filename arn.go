@@ -0,0 +1,116 @@
+package eksauth
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// ClusterARN is a parsed EKS cluster ARN (arn:aws:eks:us-west-2:123456789012:cluster/foo).
+type ClusterARN struct {
+	Partition string
+	Region    string
+	AccountID string
+	Name      string
+}
+
+// String returns the ARN.
+func (a ClusterARN) String() string {
+	return arn.ARN{
+		Partition: a.Partition,
+		Service:   "eks",
+		Region:    a.Region,
+		AccountID: a.AccountID,
+		Resource:  "cluster/" + a.Name,
+	}.String()
+}
+
+// ParseClusterARN parses an EKS cluster ARN, ok is false if s is not a cluster ARN (ie a plain cluster name).
+// Every function accepting a cluster name also accepts a cluster ARN, the region of the ARN is used to sign tokens.
+func ParseClusterARN(s string) (a ClusterARN, ok bool) {
+	if !arn.IsARN(s) {
+		return a, false
+	}
+	parsed, err := arn.Parse(s)
+	if err != nil || parsed.Service != "eks" {
+		return a, false
+	}
+	name, ok := strings.CutPrefix(parsed.Resource, "cluster/")
+	if !ok || name == "" {
+		return a, false
+	}
+	return ClusterARN{
+		Partition: parsed.Partition,
+		Region:    parsed.Region,
+		AccountID: parsed.AccountID,
+		Name:      name,
+	}, true
+}
+
+// splitClusterName returns the cluster name and region (if any) of a cluster name or ARN.
+func splitClusterName(s string) (name string, region string) {
+	if a, ok := ParseClusterARN(s); ok {
+		return a.Name, a.Region
+	}
+	return s, ""
+}
+
+// ClusterNameError is returned when a cluster name (or ARN) is rejected by ValidateClusterName.
+// It matches ErrInvalidClusterName with errors.Is.
+type ClusterNameError struct {
+	// Name is the rejected cluster name or ARN.
+	Name string
+	// Reason describes why it was rejected.
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *ClusterNameError) Error() string {
+	if e.Name == "" {
+		return ErrInvalidClusterName.Error() + ": " + e.Reason
+	}
+	return ErrInvalidClusterName.Error() + " " + strconv.Quote(e.Name) + ": " + e.Reason
+}
+
+// Is implements matching ErrInvalidClusterName.
+func (e *ClusterNameError) Is(target error) bool {
+	return target == ErrInvalidClusterName
+}
+
+// accountIDRegexp matches AWS account IDs.
+var accountIDRegexp = regexp.MustCompile(`^\d{12}$`)
+
+// ValidateClusterName checks a cluster name or ARN before any token is generated, so typos fail with a descriptive
+// *ClusterNameError instead of a token the API server rejects with an unhelpful 401: names must follow the EKS naming
+// rules (1-100 alphanumerics, hyphens and underscores starting with an alphanumeric) and ARNs must be EKS cluster
+// ARNs (arn:<partition>:eks:<region>:<account>:cluster/<name>).
+func ValidateClusterName(name string) error {
+	if name == "" {
+		return &ClusterNameError{Reason: "is empty"}
+	}
+	clusterName := name
+	if arn.IsARN(name) {
+		parsed, err := arn.Parse(name)
+		if err != nil {
+			return &ClusterNameError{Name: name, Reason: err.Error()}
+		}
+		resource, ok := strings.CutPrefix(parsed.Resource, "cluster/")
+		switch {
+		case parsed.Service != "eks" || !ok:
+			return &ClusterNameError{Name: name, Reason: "is not an EKS cluster ARN (arn:<partition>:eks:<region>:<account>:cluster/<name>)"}
+		case !regionRegexp.MatchString(parsed.Region):
+			return &ClusterNameError{Name: name, Reason: "has an invalid region " + strconv.Quote(parsed.Region)}
+		case PartitionForRegion(parsed.Region) != parsed.Partition:
+			return &ClusterNameError{Name: name, Reason: "region " + parsed.Region + " is not in partition " + parsed.Partition}
+		case !accountIDRegexp.MatchString(parsed.AccountID):
+			return &ClusterNameError{Name: name, Reason: "has an invalid account ID " + strconv.Quote(parsed.AccountID)}
+		}
+		clusterName = resource
+	}
+	if !clusterNameRegexp.MatchString(clusterName) {
+		return &ClusterNameError{Name: name, Reason: "is not a valid EKS cluster name (1-100 alphanumerics, hyphens and underscores starting with an alphanumeric)"}
+	}
+	return nil
+}
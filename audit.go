@@ -0,0 +1,229 @@
+package eksauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// AuditEvent records the issuance (or failed issuance) of a token, see WithAudit.
+type AuditEvent struct {
+	Time        time.Time `json:"time"`
+	ClusterName string    `json:"clusterName"`
+	Region      string    `json:"region,omitempty"`
+	// CallerARN is the ARN of the signing principal, only known with WithCallerIdentity.
+	CallerARN string `json:"callerArn,omitempty"`
+	// RoleARN is the last IAM role assumed by this package to sign the token, if any.
+	RoleARN string `json:"roleArn,omitempty"`
+	// AccessKeyID is the access key ID of the signing credentials.
+	AccessKeyID string `json:"accessKeyId,omitempty"`
+	// CredentialSource is the source of the signing credentials, base credentials first.
+	CredentialSource string    `json:"credentialSource,omitempty"`
+	Expiry           time.Time `json:"expiry"`
+	// Error is the failure to generate the token, empty if it was issued.
+	Error string `json:"error,omitempty"`
+	// Hostname, PID and Process describe the process generating the token.
+	Hostname string `json:"hostname,omitempty"`
+	PID      int    `json:"pid"`
+	Process  string `json:"process,omitempty"`
+}
+
+// AuditSink records AuditEvents, ie to a log, a file or a remote collector.
+type AuditSink interface {
+	Audit(ctx context.Context, event AuditEvent) error
+}
+
+// AuditSinkFunc is a function implementing the AuditSink interface.
+type AuditSinkFunc func(ctx context.Context, event AuditEvent) error
+
+// Audit implements the AuditSink interface.
+func (f AuditSinkFunc) Audit(ctx context.Context, event AuditEvent) error {
+	return f(ctx, event)
+}
+
+// processHostname and processName are the process metadata of every AuditEvent.
+var (
+	processHostname, _ = os.Hostname()
+	processName        = filepath.Base(os.Args[0])
+)
+
+// newAuditEvent creates the AuditEvent of a token issuance.
+func newAuditEvent(clusterName string, t *oauth2.Token, err error) AuditEvent {
+	event := AuditEvent{
+		Time:        time.Now().UTC(),
+		ClusterName: clusterName,
+		Hostname:    processHostname,
+		PID:         os.Getpid(),
+		Process:     processName,
+	}
+	if err != nil {
+		event.Error = err.Error()
+		return event
+	}
+	event.Expiry = t.Expiry.UTC()
+	if p, ok := TokenProvenance(t); ok {
+		event.Region, event.AccessKeyID = p.Region, p.AccessKeyID
+		event.CredentialSource = strings.Join(p.CredentialSources, credentialSourceSeparator)
+		if len(p.RoleChain) > 0 {
+			event.RoleARN = p.RoleChain[len(p.RoleChain)-1]
+		}
+		if p.Identity != nil {
+			event.CallerARN = p.Identity.ARN
+		}
+	}
+	return event
+}
+
+// AuditTokenSource is an oauth2.TokenSource recording every token it returns (or fails to) to Sink.
+// Sink failures do not fail Token, they are reported to OnSinkError.
+type AuditTokenSource struct {
+	Source      oauth2.TokenSource
+	Sink        AuditSink
+	ClusterName string
+	// OnSinkError is called when Sink fails, it may be nil.
+	OnSinkError func(err error)
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (s *AuditTokenSource) Token() (*oauth2.Token, error) {
+	return s.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+func (s *AuditTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	t, err := TokenWithContext(ctx, s.Source)
+	if sinkErr := s.Sink.Audit(ctx, newAuditEvent(s.ClusterName, t, err)); sinkErr != nil && s.OnSinkError != nil {
+		s.OnSinkError(sinkErr)
+	}
+	return t, err
+}
+
+// Invalidate implements the Invalidator interface.
+func (s *AuditTokenSource) Invalidate() {
+	Invalidate(s.Source)
+}
+
+// Close implements the io.Closer interface.
+func (s *AuditTokenSource) Close() error {
+	return closeTokenSource(s.Source)
+}
+
+// SlogAuditSink returns an AuditSink logging every event at info level (warn for failures) with logger.
+func SlogAuditSink(logger *slog.Logger) AuditSink {
+	return AuditSinkFunc(func(ctx context.Context, e AuditEvent) error {
+		level, msg := slog.LevelInfo, "eks token issued"
+		if e.Error != "" {
+			level, msg = slog.LevelWarn, "eks token issuance failed"
+		}
+		logger.LogAttrs(ctx, level, msg,
+			slog.String("cluster", e.ClusterName),
+			slog.String("region", e.Region),
+			slog.String("caller_arn", e.CallerARN),
+			slog.String("role_arn", e.RoleARN),
+			slog.String("access_key_id", e.AccessKeyID),
+			slog.String("credential_source", e.CredentialSource),
+			slog.Time("expiry", e.Expiry),
+			slog.String("error", e.Error),
+			slog.String("hostname", e.Hostname),
+			slog.Int("pid", e.PID),
+			slog.String("process", e.Process),
+		)
+		return nil
+	})
+}
+
+// FileAuditSink appends events as JSON lines to a file, it is safe for concurrent use within a process.
+type FileAuditSink struct {
+	Path string
+	// Perm is the permissions of a created file, DefaultTokenFilePerm if zero.
+	Perm os.FileMode
+
+	mu sync.Mutex
+}
+
+// NewFileAuditSink creates a FileAuditSink appending to path.
+func NewFileAuditSink(path string) *FileAuditSink {
+	return &FileAuditSink{Path: path}
+}
+
+// Audit implements the AuditSink interface.
+func (s *FileAuditSink) Audit(ctx context.Context, event AuditEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	perm := s.Perm
+	if perm == 0 {
+		perm = DefaultTokenFilePerm
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// DefaultAuditTimeout bounds each request of an HTTPAuditSink, so an unresponsive collector cannot block token issuance.
+var DefaultAuditTimeout = 5 * time.Second
+
+// HTTPAuditSink POSTs every event as JSON to URL, ie a log collector.
+type HTTPAuditSink struct {
+	URL string
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+	// Header is added to every request, ie an Authorization header.
+	Header http.Header
+	// Timeout bounds each request (including with a custom Client), DefaultAuditTimeout if zero.
+	Timeout time.Duration
+}
+
+// Audit implements the AuditSink interface.
+func (s *HTTPAuditSink) Audit(ctx context.Context, event AuditEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = DefaultAuditTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for name, values := range s.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("eksauth: audit sink %s returned %s", s.URL, resp.Status)
+	}
+	return nil
+}
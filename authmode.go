@@ -0,0 +1,78 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+)
+
+// ErrAuthenticationModeMismatch is returned by CheckAuthenticationMode when the cluster authentication
+// mode cannot support how the caller is mapped to Kubernetes identities.
+var ErrAuthenticationModeMismatch = errors.New("eksauth: cluster authentication mode does not support the configured identity mapping")
+
+// IdentityMapping is how the caller's IAM principal is mapped to a Kubernetes identity.
+type IdentityMapping int
+
+const (
+	// IdentityMappingAny works with any authentication mode.
+	IdentityMappingAny IdentityMapping = iota
+	// IdentityMappingConfigMap relies on the kube-system/aws-auth ConfigMap.
+	IdentityMappingConfigMap
+	// IdentityMappingAccessEntries relies on EKS access entries.
+	IdentityMappingAccessEntries
+)
+
+// AuthenticationModeResult is the result of CheckAuthenticationMode.
+type AuthenticationModeResult struct {
+	// Mode is the authentication mode of the cluster.
+	Mode ekstypes.AuthenticationMode
+	// Warnings are non-fatal problems with the configuration.
+	Warnings []string
+}
+
+// AuthenticationMode returns the authenticationMode of the cluster using eks:DescribeCluster.
+// Clusters that predate access entries do not report a mode and are treated as CONFIG_MAP.
+func AuthenticationMode(ctx context.Context, client eks.DescribeClusterAPIClient, clusterName string) (ekstypes.AuthenticationMode, error) {
+	out, err := client.DescribeCluster(ctx, &eks.DescribeClusterInput{
+		Name: aws.String(clusterName),
+	})
+	if err != nil {
+		return "", &Error{Op: "DescribeCluster", ClusterName: clusterName, Err: wrapThrottled("DescribeCluster", err)}
+	}
+	if out.Cluster == nil || out.Cluster.AccessConfig == nil || out.Cluster.AccessConfig.AuthenticationMode == "" {
+		return ekstypes.AuthenticationModeConfigMap, nil
+	}
+	return out.Cluster.AccessConfig.AuthenticationMode, nil
+}
+
+// CheckAuthenticationMode queries the authenticationMode of the cluster and checks it is compatible with mapping.
+// It returns an error wrapping ErrAuthenticationModeMismatch if tokens would never be mapped to an identity.
+func CheckAuthenticationMode(ctx context.Context, client eks.DescribeClusterAPIClient, clusterName string, mapping IdentityMapping) (*AuthenticationModeResult, error) {
+	mode, err := AuthenticationMode(ctx, client, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	result := &AuthenticationModeResult{Mode: mode}
+	switch mode {
+	case ekstypes.AuthenticationModeApi:
+		if mapping == IdentityMappingConfigMap {
+			return result, fmt.Errorf("%w: cluster %q uses authentication mode %s which ignores the aws-auth ConfigMap", ErrAuthenticationModeMismatch, clusterName, mode)
+		}
+	case ekstypes.AuthenticationModeConfigMap:
+		if mapping == IdentityMappingAccessEntries {
+			return result, fmt.Errorf("%w: cluster %q uses authentication mode %s which ignores access entries", ErrAuthenticationModeMismatch, clusterName, mode)
+		}
+		result.Warnings = append(result.Warnings, fmt.Sprintf("cluster %q uses authentication mode %s, the aws-auth ConfigMap is deprecated in favor of access entries", clusterName, mode))
+	case ekstypes.AuthenticationModeApiAndConfigMap:
+		if mapping == IdentityMappingConfigMap {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("cluster %q uses authentication mode %s, access entries take precedence over the aws-auth ConfigMap", clusterName, mode))
+		}
+	default:
+		result.Warnings = append(result.Warnings, fmt.Sprintf("cluster %q uses unknown authentication mode %s", clusterName, mode))
+	}
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package eksauth
+
+import (
+	"cmp"
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"golang.org/x/oauth2"
+)
+
+// Runtime is the detected runtime environment which determines how AWS credentials are obtained.
+type Runtime string
+
+// Runtimes detected by DetectRuntime, in order of preference.
+const (
+	// RuntimePodIdentity is an EKS Pod Identity agent (AWS_CONTAINER_CREDENTIALS_FULL_URI).
+	RuntimePodIdentity Runtime = "pod-identity"
+	// RuntimeIRSA is IAM roles for service accounts (AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN).
+	RuntimeIRSA Runtime = "irsa"
+	// RuntimeEnvironment is static credentials in the AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY environment variables.
+	RuntimeEnvironment Runtime = "environment"
+	// RuntimeProfile is a shared config profile (AWS_PROFILE or ~/.aws/config and ~/.aws/credentials).
+	RuntimeProfile Runtime = "profile"
+	// RuntimeIMDS is the EC2 instance metadata service.
+	RuntimeIMDS Runtime = "imds"
+	// RuntimeDefault is the default AWS SDK credential chain, used when nothing else was detected.
+	RuntimeDefault Runtime = "default"
+)
+
+// DefaultIMDSProbeTimeout is how long DetectRuntime waits for the EC2 instance metadata service to respond.
+var DefaultIMDSProbeTimeout = time.Second
+
+// RuntimeDecision is the result of DetectRuntime, suitable for logging.
+type RuntimeDecision struct {
+	Runtime Runtime
+	Reason  string
+}
+
+// fileExists reports if the path exists and is a regular file.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// sharedConfigExists reports if the default shared config or credentials files exist.
+func sharedConfigExists() bool {
+	for _, path := range []string{config.DefaultSharedConfigFilename(), config.DefaultSharedCredentialsFilename()} {
+		if fileExists(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectRuntime detects the runtime environment of the current process.
+func DetectRuntime(ctx context.Context) RuntimeDecision {
+	if os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" {
+		return RuntimeDecision{RuntimePodIdentity, "AWS_CONTAINER_CREDENTIALS_FULL_URI is set"}
+	}
+	if os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "" && os.Getenv("AWS_ROLE_ARN") != "" {
+		return RuntimeDecision{RuntimeIRSA, "AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN are set"}
+	}
+	if os.Getenv("AWS_ACCESS_KEY_ID") != "" && os.Getenv("AWS_SECRET_ACCESS_KEY") != "" {
+		return RuntimeDecision{RuntimeEnvironment, "AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are set"}
+	}
+	if profile := os.Getenv("AWS_PROFILE"); profile != "" {
+		return RuntimeDecision{RuntimeProfile, "AWS_PROFILE is set to " + profile}
+	}
+	if sharedConfigExists() {
+		return RuntimeDecision{RuntimeProfile, "shared config exists in " + filepath.Dir(config.DefaultSharedConfigFilename())}
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, DefaultIMDSProbeTimeout)
+	defer cancel()
+	if _, err := imds.New(imds.Options{}).GetRegion(probeCtx, &imds.GetRegionInput{}); err == nil {
+		return RuntimeDecision{RuntimeIMDS, "EC2 instance metadata service is reachable"}
+	}
+	return RuntimeDecision{RuntimeDefault, "no runtime environment detected"}
+}
+
+// NewAuto detects the runtime environment using DetectRuntime, loads an aws.Config using the best credential
+// path for that environment and creates a new oauth2.TokenSource from it and an EKS cluster name.
+// The returned RuntimeDecision describes which environment was detected and why.
+func NewAuto(ctx context.Context, clusterName string, optFns ...func(*config.LoadOptions) error) (oauth2.TokenSource, RuntimeDecision, error) {
+	if err := ValidateClusterName(cmp.Or(clusterName, os.Getenv(EnvClusterName))); err != nil {
+		return nil, RuntimeDecision{}, err
+	}
+	decision := DetectRuntime(ctx)
+	var loadOpts []func(*config.LoadOptions) error
+	switch decision.Runtime {
+	case RuntimeIMDS:
+		loadOpts = append(loadOpts, config.WithEC2IMDSRegion())
+	case RuntimeDefault:
+	default:
+		// If credentials come from elsewhere, failures should not fall through to slow IMDS timeouts
+		loadOpts = append(loadOpts, config.WithEC2IMDSClientEnableState(imds.ClientDisabled))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, append(loadOpts, optFns...)...)
+	if err != nil {
+		return nil, decision, err
+	}
+	return NewFromConfig(cfg, clusterName), decision, nil
+}
+
+// NewFromProfile loads the named AWS shared config profile (including its region, SSO session and role settings)
+// and creates a new oauth2.TokenSource from it and an EKS cluster name, see NewFromConfig.
+// The ctx is only used to load the config.
+func NewFromProfile(ctx context.Context, profileName, clusterName string, opts ...Option) (oauth2.TokenSource, error) {
+	if err := ValidateClusterName(cmp.Or(clusterName, os.Getenv(EnvClusterName))); err != nil {
+		return nil, err
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithSharedConfigProfile(profileName))
+	if err != nil {
+		return nil, err
+	}
+	return constructorError(NewFromConfig(cfg, clusterName, opts...))
+}
@@ -0,0 +1,253 @@
+// Package bundle exports time-limited, encrypted access bundles (a kubeconfig with an embedded EKS token)
+// that can be transferred to hosts without AWS credentials, such as jump hosts in air-gapped networks.
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/oauth2"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ErrExpired is returned when a bundle is opened after its token has expired.
+var ErrExpired = errors.New("bundle: token has expired")
+
+// ErrDecrypt is returned when a bundle cannot be decrypted (wrong passphrase or corrupted data).
+var ErrDecrypt = errors.New("bundle: failed to decrypt (wrong passphrase?)")
+
+// magic prefixes every encrypted bundle.
+var magic = []byte("EKSAUTH-BUNDLE-1\n")
+
+// scrypt parameters used to derive the encryption key from the passphrase.
+const (
+	saltSize = 16
+	scryptN  = 1 << 15
+	scryptR  = 8
+	scryptP  = 1
+	keySize  = 32
+)
+
+// Bundle is a time-limited access bundle for a single EKS cluster.
+type Bundle struct {
+	ClusterName              string    `json:"cluster_name"`
+	Server                   string    `json:"server"`
+	CertificateAuthorityData []byte    `json:"certificate_authority_data,omitempty"`
+	Token                    string    `json:"token"`
+	Expiry                   time.Time `json:"expiry"`
+	CreatedAt                time.Time `json:"created_at"`
+}
+
+// New creates a Bundle from a token source and the cluster endpoint/CA.
+func New(ts oauth2.TokenSource, clusterName string, server string, caData []byte) (*Bundle, error) {
+	token, err := ts.Token()
+	if err != nil {
+		return nil, err
+	}
+	return &Bundle{
+		ClusterName:              clusterName,
+		Server:                   server,
+		CertificateAuthorityData: caData,
+		Token:                    token.AccessToken,
+		Expiry:                   token.Expiry,
+		CreatedAt:                time.Now().UTC(),
+	}, nil
+}
+
+// NewFromCluster creates a Bundle, discovering the cluster endpoint and CA using eks:DescribeCluster.
+func NewFromCluster(ctx context.Context, ts oauth2.TokenSource, client eks.DescribeClusterAPIClient, clusterName string) (*Bundle, error) {
+	out, err := client.DescribeCluster(ctx, &eks.DescribeClusterInput{
+		Name: aws.String(clusterName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.Cluster == nil || out.Cluster.Endpoint == nil {
+		return nil, fmt.Errorf("bundle: cluster %q has no endpoint", clusterName)
+	}
+	var caData []byte
+	if out.Cluster.CertificateAuthority != nil && out.Cluster.CertificateAuthority.Data != nil {
+		caData, err = base64.StdEncoding.DecodeString(*out.Cluster.CertificateAuthority.Data)
+		if err != nil {
+			return nil, fmt.Errorf("bundle: invalid certificate authority data: %w", err)
+		}
+	}
+	return New(ts, clusterName, *out.Cluster.Endpoint, caData)
+}
+
+// Expired reports if the token in the bundle has expired.
+func (b *Bundle) Expired() bool {
+	return !b.Expiry.IsZero() && !time.Now().Before(b.Expiry)
+}
+
+// KubeconfigAPI returns the bundle as a kubeconfig with a single context named after the cluster.
+func (b *Bundle) KubeconfigAPI() *clientcmdapi.Config {
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters[b.ClusterName] = &clientcmdapi.Cluster{
+		Server:                   b.Server,
+		CertificateAuthorityData: b.CertificateAuthorityData,
+	}
+	cfg.AuthInfos[b.ClusterName] = &clientcmdapi.AuthInfo{
+		Token: b.Token,
+	}
+	cfg.Contexts[b.ClusterName] = &clientcmdapi.Context{
+		Cluster:  b.ClusterName,
+		AuthInfo: b.ClusterName,
+	}
+	cfg.CurrentContext = b.ClusterName
+	return cfg
+}
+
+// Kubeconfig returns the bundle as a serialized kubeconfig.
+func (b *Bundle) Kubeconfig() ([]byte, error) {
+	return clientcmd.Write(*b.KubeconfigAPI())
+}
+
+// WriteKubeconfig writes the bundle as a kubeconfig file (mode 0600).
+func (b *Bundle) WriteKubeconfig(path string) error {
+	data, err := b.Kubeconfig()
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// writeFile atomically replaces path with a 0600 file containing data, so an existing file with broader
+// permissions never holds the token.
+func writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// RESTConfig returns a rest.Config for the bundle, it fails with ErrExpired if the token has expired.
+func (b *Bundle) RESTConfig() (*rest.Config, error) {
+	if b.Expired() {
+		return nil, ErrExpired
+	}
+	return clientcmd.NewDefaultClientConfig(*b.KubeconfigAPI(), nil).ClientConfig()
+}
+
+// deriveKey derives the AES-256 key from the passphrase and salt.
+func deriveKey(passphrase []byte, salt []byte) ([]byte, error) {
+	return scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, keySize)
+}
+
+// Encrypt serializes and encrypts the bundle using AES-256-GCM with a key derived from the passphrase (scrypt).
+func (b *Bundle) Encrypt(passphrase []byte) ([]byte, error) {
+	plaintext, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(magic)+len(salt)+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(out, magic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, magic), nil
+}
+
+// WriteFile encrypts the bundle and writes it to path (mode 0600).
+func (b *Bundle) WriteFile(path string, passphrase []byte) error {
+	data, err := b.Encrypt(passphrase)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// Decrypt decrypts a bundle produced by Encrypt, it fails with ErrExpired if the token has expired.
+func Decrypt(data []byte, passphrase []byte) (*Bundle, error) {
+	if !bytes.HasPrefix(data, magic) {
+		return nil, errors.New("bundle: not an eks-auth bundle")
+	}
+	data = data[len(magic):]
+	if len(data) < saltSize {
+		return nil, ErrDecrypt
+	}
+	key, err := deriveKey(passphrase, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[saltSize:]
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], magic)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	var b Bundle
+	if err := json.Unmarshal(plaintext, &b); err != nil {
+		return nil, fmt.Errorf("bundle: invalid bundle: %w", err)
+	}
+	if b.Expired() {
+		return &b, ErrExpired
+	}
+	return &b, nil
+}
+
+// ReadFile reads and decrypts a bundle written by WriteFile.
+func ReadFile(path string, passphrase []byte) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decrypt(data, passphrase)
+}
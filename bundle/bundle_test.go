@@ -0,0 +1,107 @@
+package bundle_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/bored-engineer/aws-eks-auth/bundle"
+	"github.com/bored-engineer/aws-eks-auth/eksauthtest"
+)
+
+// newBundle returns a bundle whose token expires at expiry.
+func newBundle(t *testing.T, expiry time.Time) *bundle.Bundle {
+	t.Helper()
+	b, err := bundle.New(eksauthtest.NewStatic("k8s-aws-v1.token", expiry), "eks-cluster-name", "https://example.eks.amazonaws.com", []byte("ca"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	b := newBundle(t, time.Now().Add(time.Hour))
+	data, err := b.Encrypt([]byte("passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := bundle.Decrypt(data, []byte("passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ClusterName != b.ClusterName || got.Server != b.Server || string(got.CertificateAuthorityData) != "ca" ||
+		got.Token != b.Token || !got.Expiry.Equal(b.Expiry) {
+		t.Errorf("got %+v, want %+v", got, b)
+	}
+	restCfg, err := got.RESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restCfg.Host != b.Server || restCfg.BearerToken != b.Token {
+		t.Errorf("got host %q and token %q", restCfg.Host, restCfg.BearerToken)
+	}
+}
+
+func TestDecryptWrongPassphrase(t *testing.T) {
+	data, err := newBundle(t, time.Now().Add(time.Hour)).Encrypt([]byte("passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bundle.Decrypt(data, []byte("wrong")); !errors.Is(err, bundle.ErrDecrypt) {
+		t.Errorf("got %v, want %v", err, bundle.ErrDecrypt)
+	}
+	// Tampering with the ciphertext is detected as well.
+	data[len(data)-1] ^= 1
+	if _, err := bundle.Decrypt(data, []byte("passphrase")); !errors.Is(err, bundle.ErrDecrypt) {
+		t.Errorf("tampered: got %v, want %v", err, bundle.ErrDecrypt)
+	}
+	if _, err := bundle.Decrypt([]byte("not a bundle"), []byte("passphrase")); err == nil {
+		t.Error("got nil error for data that is not a bundle")
+	}
+}
+
+func TestDecryptExpired(t *testing.T) {
+	b := newBundle(t, time.Now().Add(-time.Minute))
+	data, err := b.Encrypt([]byte("passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := bundle.Decrypt(data, []byte("passphrase"))
+	if !errors.Is(err, bundle.ErrExpired) {
+		t.Fatalf("got %v, want %v", err, bundle.ErrExpired)
+	}
+	// The expired bundle is still returned, ie to report when it expired.
+	if got == nil || !got.Expiry.Equal(b.Expiry) {
+		t.Errorf("got %+v", got)
+	}
+	if _, err := got.RESTConfig(); !errors.Is(err, bundle.ErrExpired) {
+		t.Errorf("RESTConfig: got %v, want %v", err, bundle.ErrExpired)
+	}
+}
+
+func TestWriteFileTightensPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no unix permissions on windows")
+	}
+	path := filepath.Join(t.TempDir(), "cluster.bundle")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b := newBundle(t, time.Now().Add(time.Hour))
+	if err := b.WriteFile(path, []byte("passphrase")); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := fi.Mode().Perm(); mode != 0o600 {
+		t.Errorf("got mode %o, want 600", mode)
+	}
+	if _, err := bundle.ReadFile(path, []byte("passphrase")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,291 @@
+package eksauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"golang.org/x/oauth2"
+)
+
+// DefaultLockTimeout is how long a FileCache waits to acquire the lock of a cache entry.
+var DefaultLockTimeout = 30 * time.Second
+
+// lockPollInterval is how often a FileCache retries acquiring a held lock.
+const lockPollInterval = 50 * time.Millisecond
+
+// CacheKey returns the cache key of the token for a cluster, region and caller identity.
+// identity must uniquely identify the credentials used to sign the token. The key is only as precise as identity:
+// a role ARN or AWS profile name avoids resolving the credentials, but two credential sets sharing that name (ie
+// the same profile name in different config files) collide and get each other's tokens. Use CredentialsCacheKey
+// to key by the resolved access key ID instead.
+func CacheKey(clusterName, region, identity string) string {
+	sum := sha256.Sum256([]byte(clusterName + "\x00" + region + "\x00" + identity))
+	return hex.EncodeToString(sum[:])
+}
+
+// CredentialsCacheKey returns the CacheKey of the token for a cluster and region keyed by the access key ID of the
+// credentials, which are retrieved (but sts:GetCallerIdentity is not called). Keys of temporary credentials change
+// whenever they are renewed.
+func CredentialsCacheKey(ctx context.Context, clusterName, region string, credentials aws.CredentialsProvider) (string, error) {
+	if credentials == nil {
+		return "", errors.New("eksauth: CredentialsCacheKey requires credentials")
+	}
+	creds, err := credentials.Retrieve(ctx)
+	if err != nil {
+		return "", fmt.Errorf("eksauth: CredentialsCacheKey: %w", err)
+	}
+	return CacheKey(clusterName, region, "access-key-id:"+creds.AccessKeyID), nil
+}
+
+// TokenCache is a pluggable store of tokens shared across processes, keyed by CacheKey.
+// Load returns nil (and no error) if there is no cached token for key.
+type TokenCache interface {
+	Load(key string) (*oauth2.Token, error)
+	Store(key string, t *oauth2.Token) error
+}
+
+// TokenCacheLocker is implemented by a TokenCache that can serialize refreshes of an entry across processes.
+type TokenCacheLocker interface {
+	Lock(ctx context.Context, key string) (func(), error)
+}
+
+// TokenCacheDeleter is implemented by a TokenCache that can delete entries, see CachedTokenSource.Invalidate.
+type TokenCacheDeleter interface {
+	Delete(key string) error
+}
+
+// cachedToken is the on-disk representation of a cached token.
+type cachedToken struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type,omitempty"`
+	Expiry      time.Time `json:"expiry"`
+}
+
+// FileCache is an on-disk TokenCache that can be shared by multiple processes.
+// Each entry is a 0600 JSON file in Dir, writes are atomic and refreshes are serialized with an advisory lock
+// (flock or LockFileEx) of a lock file next to it, which the OS releases if its holder exits.
+type FileCache struct {
+	// Dir is the directory containing the cache entries, if empty it is "tokens" in CacheDir.
+	Dir string
+	// LockTimeout overrides DefaultLockTimeout, it only bounds the wait: a held lock is never stolen.
+	LockTimeout time.Duration
+}
+
+// NewFileCache creates a FileCache storing tokens in dir.
+func NewFileCache(dir string) *FileCache {
+	return &FileCache{Dir: dir}
+}
+
+// dir returns the cache directory.
+func (c *FileCache) dir() (string, error) {
+	if c.Dir != "" {
+		return c.Dir, nil
+	}
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tokens"), nil
+}
+
+// path returns the path of the cache entry for key.
+func (c *FileCache) path(key string) (string, error) {
+	dir, err := c.dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, key+".json"), nil
+}
+
+// Load returns the cached token for key, or nil if there is no cached token.
+func (c *FileCache) Load(key string) (*oauth2.Token, error) {
+	path, err := c.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("eksauth: failed to read cached token: %w", err)
+	}
+	var cached cachedToken
+	if err := json.Unmarshal(data, &cached); err != nil {
+		// A corrupt entry is treated as a cache miss, it is overwritten by the next Store.
+		return nil, nil
+	}
+	return &oauth2.Token{
+		AccessToken: cached.AccessToken,
+		TokenType:   cached.TokenType,
+		Expiry:      cached.Expiry,
+	}, nil
+}
+
+// Store writes the token to the cache entry for key.
+func (c *FileCache) Store(key string, t *oauth2.Token) error {
+	path, err := c.path(key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cachedToken{
+		AccessToken: t.AccessToken,
+		TokenType:   t.TokenType,
+		Expiry:      t.Expiry,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("eksauth: failed to create cache directory: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("eksauth: failed to write cached token: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return fmt.Errorf("eksauth: failed to write cached token: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("eksauth: failed to write cached token: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("eksauth: failed to write cached token: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("eksauth: failed to write cached token: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the cache entry for key, it is not an error if there is no cache entry.
+func (c *FileCache) Delete(key string) error {
+	path, err := c.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("eksauth: failed to delete cached token: %w", err)
+	}
+	return nil
+}
+
+// Lock acquires the lock of the cache entry for key, blocking until it is acquired, ctx is done or the
+// lock timeout elapses. The returned function releases the lock.
+func (c *FileCache) Lock(ctx context.Context, key string) (func(), error) {
+	path, err := c.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("eksauth: failed to create cache directory: %w", err)
+	}
+	timeout := c.LockTimeout
+	if timeout == 0 {
+		timeout = DefaultLockTimeout
+	}
+	lockPath := path + ".lock"
+	// The lock file is never removed, removing it would let a waiter lock a file that has already been replaced.
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("eksauth: failed to lock cached token: %w", err)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("eksauth: failed to lock cached token: %w", err)
+		} else if locked {
+			return func() {
+				_ = unlockFile(f)
+				f.Close()
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("eksauth: timed out waiting for lock %s", lockPath)
+		}
+		timer := time.NewTimer(lockPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			f.Close()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// CachedTokenSource is an oauth2.TokenSource that reuses still-valid tokens from a TokenCache, only calling
+// Source when the cached token is missing or (early) expired. It allows short-lived processes such as the CLI
+// to share tokens instead of presigning (and resolving credentials) on every invocation.
+type CachedTokenSource struct {
+	Source      oauth2.TokenSource
+	Cache       TokenCache
+	Key         string
+	EarlyExpiry time.Duration
+}
+
+// NewCachedTokenSource creates a CachedTokenSource using DefaultEarlyExpiry, see CacheKey for key.
+func NewCachedTokenSource(src oauth2.TokenSource, cache TokenCache, key string) *CachedTokenSource {
+	return &CachedTokenSource{
+		Source:      src,
+		Cache:       cache,
+		Key:         key,
+		EarlyExpiry: DefaultEarlyExpiry,
+	}
+}
+
+// load returns the cached token if it is valid for at least EarlyExpiry.
+func (s *CachedTokenSource) load() *oauth2.Token {
+	t, err := s.Cache.Load(s.Key)
+	if err != nil || t == nil || t.AccessToken == "" {
+		return nil
+	}
+	if !t.Expiry.IsZero() && !time.Now().Add(s.EarlyExpiry).Before(t.Expiry) {
+		return nil
+	}
+	return t
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (s *CachedTokenSource) Token() (*oauth2.Token, error) {
+	return s.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+// Cache errors are not fatal, if the cache cannot be read or written the token is generated from Source.
+func (s *CachedTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	if t := s.load(); t != nil {
+		return t, nil
+	}
+	if locker, ok := s.Cache.(TokenCacheLocker); ok {
+		unlock, err := locker.Lock(ctx, s.Key)
+		if err == nil {
+			defer unlock()
+			// Another process may have refreshed the token while we waited for the lock.
+			if t := s.load(); t != nil {
+				return t, nil
+			}
+		} else if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+	}
+	t, err := TokenWithContext(ctx, s.Source)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.Cache.Store(s.Key, t)
+	return t, nil
+}
@@ -0,0 +1,116 @@
+package eksauth_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+)
+
+// skipWithoutFileLocks skips the test on platforms without advisory file locking.
+func skipWithoutFileLocks(t *testing.T) {
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "windows":
+	default:
+		t.Skip("no advisory file locking on " + runtime.GOOS)
+	}
+}
+
+func TestFileCacheLockContention(t *testing.T) {
+	skipWithoutFileLocks(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	// Two FileCache values lock the same entry like two processes would.
+	a, b := eksauth.NewFileCache(dir), eksauth.NewFileCache(dir)
+	a.LockTimeout, b.LockTimeout = 100*time.Millisecond, 100*time.Millisecond
+
+	unlock, err := a.Lock(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A held lock is never stolen, no matter how old the lock file is.
+	entries, err := filepath.Glob(filepath.Join(dir, "*.lock"))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("got lock files %v, %v", entries, err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(entries[0], old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Lock(ctx, "key"); err == nil {
+		t.Fatal("acquired a held lock")
+	}
+
+	// Concurrent lockers are serialized.
+	unlock()
+	var (
+		mu      sync.Mutex
+		holders int
+		wg      sync.WaitGroup
+	)
+	for _, c := range []*eksauth.FileCache{a, b, a, b} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := *c
+			c.LockTimeout = 5 * time.Second
+			unlock, err := c.Lock(ctx, "key")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			holders++
+			if holders != 1 {
+				t.Errorf("%d lockers hold the lock", holders)
+			}
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			holders--
+			mu.Unlock()
+			unlock()
+		}()
+	}
+	wg.Wait()
+
+	// A waiter acquires the lock once it is released.
+	b.LockTimeout = 5 * time.Second
+	unlock, err = a.Lock(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		unlock, err := b.Lock(ctx, "key")
+		if err == nil {
+			unlock()
+		}
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	unlock()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileCacheLockContext(t *testing.T) {
+	skipWithoutFileLocks(t)
+	c := eksauth.NewFileCache(t.TempDir())
+	unlock, err := c.Lock(context.Background(), "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := c.Lock(ctx, "key"); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
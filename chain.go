@@ -0,0 +1,88 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// ChainTokenSource is an oauth2.TokenSource trying an ordered list of token sources (ie Pod Identity, then IRSA,
+// then a shared profile) and returning the token of the first that succeeds. The winning source is remembered and
+// used for all subsequent calls, Invalidate forgets it so the next call walks the chain again.
+// It is safe for concurrent use.
+type ChainTokenSource struct {
+	Sources []oauth2.TokenSource
+
+	mu sync.Mutex
+	// winner is the index of the remembered source plus one, zero if none.
+	winner int
+}
+
+// NewChainTokenSource creates a ChainTokenSource trying sources in order.
+func NewChainTokenSource(sources ...oauth2.TokenSource) *ChainTokenSource {
+	return &ChainTokenSource{Sources: sources}
+}
+
+// Winner returns the index of the remembered source in Sources, or -1 if no source succeeded yet.
+func (c *ChainTokenSource) Winner() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.winner - 1
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (c *ChainTokenSource) Token() (*oauth2.Token, error) {
+	return c.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+// Errors of the remembered source are returned as is, the chain is not walked again until Invalidate is called.
+func (c *ChainTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	c.mu.Lock()
+	winner := c.winner
+	c.mu.Unlock()
+	if winner > 0 && winner <= len(c.Sources) {
+		return TokenWithContext(ctx, c.Sources[winner-1])
+	}
+	if len(c.Sources) == 0 {
+		return nil, errors.New("eksauth: empty token source chain")
+	}
+	var errs []error
+	for idx, src := range c.Sources {
+		t, err := TokenWithContext(ctx, src)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("source %d: %w", idx, err))
+			continue
+		}
+		c.mu.Lock()
+		c.winner = idx + 1
+		c.mu.Unlock()
+		return t, nil
+	}
+	return nil, fmt.Errorf("eksauth: no token source in the chain succeeded: %w", errors.Join(errs...))
+}
+
+// Invalidate implements the Invalidator interface, it forgets the remembered source and invalidates every source.
+func (c *ChainTokenSource) Invalidate() {
+	c.mu.Lock()
+	c.winner = 0
+	c.mu.Unlock()
+	for _, src := range c.Sources {
+		Invalidate(src)
+	}
+}
+
+// Close implements the io.Closer interface, it closes every source.
+func (c *ChainTokenSource) Close() error {
+	var errs []error
+	for _, src := range c.Sources {
+		errs = append(errs, closeTokenSource(src))
+	}
+	return errors.Join(errs...)
+}
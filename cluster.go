@@ -0,0 +1,168 @@
+package eksauth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"golang.org/x/oauth2"
+)
+
+// MaxExpiration is the maximum lifetime of a token accepted by EKS.
+const MaxExpiration = 15 * time.Minute
+
+// FieldError is a validation error attributed to a specific field of a configuration struct.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+// Error implements the error interface.
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// clusterNameRegexp matches valid EKS cluster names.
+var clusterNameRegexp = regexp.MustCompile(`^[0-9A-Za-z][A-Za-z0-9\-_]{0,99}$`)
+
+// regionRegexp matches (loosely) valid AWS region names.
+var regionRegexp = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+// ClusterConfig is the configuration of a single EKS cluster.
+type ClusterConfig struct {
+	// Name is the EKS cluster name.
+	Name string `json:"name,omitempty"`
+	// Region is the AWS region used to sign tokens, it overrides the region of the aws.Config.
+	Region string `json:"region,omitempty"`
+	// RoleARN is the IAM role assumed before signing tokens.
+	RoleARN string `json:"role_arn,omitempty"`
+	// RoleDuration is the duration of the assumed role session, see WithRoleDuration.
+	RoleDuration Duration `json:"role_duration,omitempty"`
+	// ExternalID is the external ID passed when assuming the (last) role, see WithRoleExternalID.
+	ExternalID string `json:"external_id,omitempty"`
+	// MFASerial is the MFA device required when assuming the (first) role, the code is obtained from the function
+	// set with WithMFATokenProvider.
+	MFASerial string `json:"mfa_serial,omitempty"`
+	// STSEndpoint overrides the STS endpoint (BaseEndpoint) tokens are signed against.
+	STSEndpoint string `json:"sts_endpoint,omitempty"`
+	// Expiration overrides DefaultExpiration.
+	Expiration Duration `json:"expiration,omitempty"`
+	// EarlyExpiry overrides DefaultEarlyExpiry.
+	EarlyExpiry Duration `json:"early_expiry,omitempty"`
+}
+
+// Validate validates the ClusterConfig, returning all problems joined together as *FieldError values.
+func (c ClusterConfig) Validate() error {
+	var errs []error
+	if c.Name == "" {
+		errs = append(errs, &FieldError{Field: "name", Err: fmt.Errorf("%w: is required", ErrInvalidClusterName)})
+	} else if err := ValidateClusterName(c.Name); err != nil {
+		errs = append(errs, &FieldError{Field: "name", Err: err})
+	}
+	if c.Region != "" && !regionRegexp.MatchString(c.Region) {
+		errs = append(errs, &FieldError{Field: "region", Err: fmt.Errorf("%q is not a valid AWS region", c.Region)})
+	}
+	if c.RoleARN != "" {
+		for idx, spec := range parseRoleChain(c.RoleARN, 0) {
+			if err := validateRoleARN(spec.RoleARN); err != nil {
+				errs = append(errs, &FieldError{Field: fmt.Sprintf("role_arn[%d]", idx), Err: err})
+			}
+		}
+	}
+	if c.ExternalID != "" && c.RoleARN == "" {
+		errs = append(errs, &FieldError{Field: "external_id", Err: errors.New("requires a role_arn")})
+	}
+	if c.MFASerial != "" && c.RoleARN == "" {
+		errs = append(errs, &FieldError{Field: "mfa_serial", Err: errors.New("requires a role_arn")})
+	}
+	if d := time.Duration(c.RoleDuration); d != 0 && (d < MinRoleDuration || d > MaxRoleDuration) {
+		errs = append(errs, &FieldError{Field: "role_duration", Err: fmt.Errorf("%s is outside of [%s, %s]", d, MinRoleDuration, MaxRoleDuration)})
+	}
+	if c.STSEndpoint != "" {
+		if u, err := url.Parse(c.STSEndpoint); err != nil {
+			errs = append(errs, &FieldError{Field: "sts_endpoint", Err: err})
+		} else if u.Scheme != "https" || u.Host == "" {
+			errs = append(errs, &FieldError{Field: "sts_endpoint", Err: fmt.Errorf("%q must be an absolute https:// URL", c.STSEndpoint)})
+		}
+	}
+	if d := time.Duration(c.Expiration); d < 0 || d > MaxExpiration {
+		errs = append(errs, &FieldError{Field: "expiration", Err: fmt.Errorf("%s is outside of [0, %s]", d, MaxExpiration)})
+	}
+	if d := time.Duration(c.EarlyExpiry); d < 0 {
+		errs = append(errs, &FieldError{Field: "early_expiry", Err: fmt.Errorf("%s cannot be negative", d)})
+	} else if c.Expiration != 0 && d >= time.Duration(c.Expiration) {
+		errs = append(errs, &FieldError{Field: "early_expiry", Err: fmt.Errorf("%s must be less than the expiration", d)})
+	}
+	return errors.Join(errs...)
+}
+
+// validateRoleARN validates that s is an IAM role ARN.
+func validateRoleARN(s string) error {
+	parsed, err := arn.Parse(s)
+	if err != nil {
+		return err
+	}
+	if parsed.Service != "iam" || !strings.HasPrefix(parsed.Resource, "role/") {
+		return fmt.Errorf("%q is not an IAM role ARN", s)
+	}
+	return nil
+}
+
+// NewFromClusterConfig validates the ClusterConfig and creates a new oauth2.TokenSource from it and an aws.Config.
+// The ClusterConfig (and the region of the aws.Config) takes precedence over the EKSAUTH_* environment variables,
+// opts take precedence over both.
+func NewFromClusterConfig(cfg aws.Config, cluster ClusterConfig, opts ...Option) (oauth2.TokenSource, error) {
+	if err := cluster.Validate(); err != nil {
+		return nil, err
+	}
+	env := loadEnv()
+	if _, region := splitClusterName(cluster.Name); region != "" && cluster.Region == "" {
+		cluster.Region = region
+	}
+	if cluster.Region != "" {
+		cfg.Region = cluster.Region
+	} else if cfg.Region == "" {
+		cfg.Region = env.Region
+	}
+	var clusterOpts []Option
+	if cluster.STSEndpoint != "" {
+		clusterOpts = append(clusterOpts, WithSTSEndpoint(cluster.STSEndpoint))
+	}
+	if cluster.Expiration != 0 {
+		clusterOpts = append(clusterOpts, WithExpiration(time.Duration(cluster.Expiration)))
+	}
+	if cluster.EarlyExpiry != 0 {
+		clusterOpts = append(clusterOpts, WithEarlyExpiry(time.Duration(cluster.EarlyExpiry)))
+	}
+	o := newOptions(env, append(clusterOpts, opts...))
+	o.applyConfig(&cfg)
+	cfg.Credentials = ssoLoginCredentials(cfg, o.ssoLoginPrompt)
+	if roleARN := cluster.RoleARN; roleARN != "" {
+		chain := parseRoleChain(roleARN, time.Duration(cluster.RoleDuration))
+		if len(chain) > 0 {
+			chain[len(chain)-1].ExternalID = cluster.ExternalID
+			if cluster.MFASerial != "" {
+				chain[0].SerialNumber, chain[0].TokenProvider = cluster.MFASerial, o.mfaTokenProvider
+			}
+		}
+		cfg.Credentials = AssumeRoleChainCredentials(cfg, chain)
+	} else if env.RoleARN != "" {
+		cfg.Credentials = AssumeRoleChainCredentials(cfg, parseRoleChain(env.RoleARN, env.RoleDuration))
+	}
+	o.credentials = cfg.Credentials
+	o.ssoProfile, o.ssoStartURL = ssoSession(cfg)
+	client := sts.NewFromConfig(cfg, append([]func(*sts.Options){addUserAgent}, o.stsOptions...)...)
+	o.stsClient = client
+	return constructorError(newFromPresignClient(sts.NewPresignClient(client, o.presignOptions...), cluster.Name, env, o))
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"github.com/bored-engineer/aws-eks-auth/bundle"
+	"github.com/bored-engineer/aws-eks-auth/kube"
+	"golang.org/x/term"
+)
+
+// envBundlePassphrase is the environment variable the bundle passphrase is read from when --passphrase-file is
+// not set, before falling back to prompting for it.
+const envBundlePassphrase = "EKSAUTH_BUNDLE_PASSPHRASE"
+
+// runBundle implements the bundle subcommand, dispatching to export or import.
+func runBundle(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: eks-auth bundle export|import [flags]\n")
+		return flag.ErrHelp
+	}
+	switch args[0] {
+	case "export":
+		return runBundleExport(ctx, args[1:])
+	case "import":
+		return runBundleImport(ctx, args[1:])
+	default:
+		return fmt.Errorf("unknown bundle command %q, expected export or import", args[0])
+	}
+}
+
+// runBundleExport implements bundle export, it writes an encrypted bundle with a token for the cluster.
+func runBundleExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bundle export", flag.ContinueOnError)
+	var cf clusterFlags
+	cf.register(fs)
+	out := fs.String("out", "", "path of the encrypted bundle to write (required)")
+	passphraseFile := fs.String("passphrase-file", "", "file containing the passphrase (default $"+envBundlePassphrase+" or prompted for)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New("--out is required")
+	}
+	cluster, profile, err := cf.clusterConfig()
+	if err != nil {
+		return err
+	}
+	cfg, err := cf.loadAWSConfig(ctx, profile)
+	if err != nil {
+		return err
+	}
+	if cluster.Region != "" {
+		cfg.Region = cluster.Region
+	}
+	info, err := eksauth.NewDiscoveryFromConfig(cfg).Describe(ctx, cluster.Name)
+	if err != nil {
+		return err
+	}
+	ts, err := eksauth.NewFromClusterConfig(cfg, cluster, eksauth.WithContext(ctx), eksauth.WithMFATokenProvider(cf.mfaToken), cf.ssoLoginOption())
+	if err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase(*passphraseFile, true)
+	if err != nil {
+		return err
+	}
+	b, err := bundle.New(ts, info.Name, info.Endpoint, info.CertificateAuthorityData)
+	if err != nil {
+		return err
+	}
+	if err := b.WriteFile(*out, passphrase); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote bundle for %s to %s, it expires at %s\n", b.ClusterName, *out, b.Expiry.Format(time.RFC3339))
+	return nil
+}
+
+// runBundleImport implements bundle import, it decrypts a bundle and merges it into a kubeconfig file.
+func runBundleImport(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("bundle import", flag.ContinueOnError)
+	in := fs.String("in", "", "path of the encrypted bundle to read (required)")
+	path := fs.String("kubeconfig", "", "kubeconfig file to update (default the first file of $KUBECONFIG or ~/.kube/config)")
+	passphraseFile := fs.String("passphrase-file", "", "file containing the passphrase (default $"+envBundlePassphrase+" or prompted for)")
+	dryRun := fs.Bool("dry-run", false, "print the kubeconfig of the bundle to stdout instead of updating the kubeconfig file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return errors.New("--in is required")
+	}
+	passphrase, err := readPassphrase(*passphraseFile, false)
+	if err != nil {
+		return err
+	}
+	b, err := bundle.ReadFile(*in, passphrase)
+	if errors.Is(err, bundle.ErrExpired) {
+		return fmt.Errorf("bundle for %s expired at %s", b.ClusterName, b.Expiry.Format(time.RFC3339))
+	} else if err != nil {
+		return err
+	}
+	if *dryRun {
+		data, err := b.Kubeconfig()
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if *path == "" {
+		*path = kube.DefaultKubeconfigPath()
+	}
+	if err := kube.UpdateKubeconfig(*path, b.KubeconfigAPI()); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Updated context %s in %s, it expires at %s\n", b.ClusterName, *path, b.Expiry.Format(time.RFC3339))
+	return nil
+}
+
+// readPassphrase reads the bundle passphrase from file, $EKSAUTH_BUNDLE_PASSPHRASE or stdin (prompting on stderr
+// without echo if it is a terminal, twice if confirm is set).
+func readPassphrase(file string, confirm bool) ([]byte, error) {
+	var passphrase string
+	switch {
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		passphrase, _, _ = strings.Cut(string(data), "\n")
+	case os.Getenv(envBundlePassphrase) != "":
+		passphrase = os.Getenv(envBundlePassphrase)
+	case term.IsTerminal(int(os.Stdin.Fd())):
+		fmt.Fprint(os.Stderr, "Enter bundle passphrase: ")
+		data, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		if confirm {
+			fmt.Fprint(os.Stderr, "Confirm bundle passphrase: ")
+			again, err := term.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Fprintln(os.Stderr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read passphrase: %w", err)
+			}
+			if string(again) != string(data) {
+				return nil, errors.New("passphrases do not match")
+			}
+		}
+		passphrase = string(data)
+	default:
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return nil, fmt.Errorf("failed to read passphrase from stdin: %w", err)
+		}
+		passphrase = line
+	}
+	passphrase = strings.TrimRight(passphrase, "\r\n")
+	if passphrase == "" {
+		return nil, errors.New("the bundle passphrase cannot be empty")
+	}
+	return []byte(passphrase), nil
+}
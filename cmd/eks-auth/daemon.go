@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+)
+
+// runDaemon implements the daemon subcommand, it keeps a fresh token written to a file for applications that only
+// read bearer tokens from files. SIGHUP forces a refresh, SIGINT and SIGTERM stop it.
+func runDaemon(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	var cf clusterFlags
+	cf.register(fs)
+	out := fs.String("out", "", "path of the token file, replaced atomically on every refresh")
+	healthz := fs.String("healthz", "", "address serving /healthz (ie :8080), healthy while the token file holds a valid token")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New("--out is required")
+	}
+	// The daemon runs unattended, nothing may be read from stdin.
+	cf.interactive = false
+	cluster, ts, err := cf.tokenSource(ctx)
+	if err != nil {
+		return err
+	}
+	writer := eksauth.NewTokenFileWriter(ts, *out)
+	writer.OnError = func(err error) {
+		fmt.Fprintf(os.Stderr, "eks-auth daemon: failed to refresh the token: %v\n", err)
+	}
+	if *healthz != "" {
+		l, err := net.Listen("tcp", *healthz)
+		if err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			if !writer.Healthy() {
+				http.Error(w, "no valid token", http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprintf(w, "ok, token expires at %s\n", writer.Token().Expiry.UTC().Format(time.RFC3339))
+		})
+		srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+		go srv.Serve(l)
+		defer srv.Close()
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for range hup {
+			writer.Refresh()
+		}
+	}()
+	fmt.Fprintf(os.Stderr, "Writing tokens for %s to %s\n", cluster.Name, *out)
+	return writer.Run(ctx)
+}
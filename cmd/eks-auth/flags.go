@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+)
+
+// clusterFlags are the flags shared by every subcommand that generates tokens for a cluster.
+type clusterFlags struct {
+	clusterName  string
+	region       string
+	roleARN      string
+	externalID   string
+	mfaSerial    string
+	awsProfile   string
+	profile      string
+	profilesFile string
+	strict       bool
+	ssoLogin     bool
+	// interactive reports if the user may be prompted on stdin (ie for an MFA code).
+	interactive bool
+}
+
+// register registers the flags on the flag.FlagSet.
+func (f *clusterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.clusterName, "cluster-name", "", "name of the EKS cluster (default $EKSAUTH_CLUSTER_NAME or the profile default_cluster)")
+	fs.StringVar(&f.region, "region", "", "AWS region used to sign the token (default the region of the cluster ARN or profile, $EKSAUTH_REGION or the AWS config region)")
+	fs.StringVar(&f.roleARN, "role-arn", "", "IAM role (or comma separated role chain) assumed before signing the token")
+	fs.StringVar(&f.externalID, "external-id", "", "external ID passed when assuming the (last) role")
+	fs.StringVar(&f.mfaSerial, "mfa-serial", "", "MFA device required when assuming the (first) role, the code is prompted for")
+	fs.StringVar(&f.awsProfile, "aws-profile", "", "AWS shared config profile used to load credentials (default $AWS_PROFILE)")
+	fs.StringVar(&f.profile, "profile", "", "eks-auth profile to load cluster defaults from")
+	fs.StringVar(&f.profilesFile, "profiles-file", "", "path of the eks-auth profiles file (default profiles.json in the config directory)")
+	fs.BoolVar(&f.strict, "strict", false, "reject unknown keys and invalid values in the profiles file")
+	fs.BoolVar(&f.ssoLogin, "sso-login", false, "log in to AWS SSO in the browser when the SSO session is expired (interactive only)")
+	f.interactive = true
+}
+
+// mfaToken prompts for an MFA code on stderr and reads it from stdin, stdout is reserved for the output.
+func (f *clusterFlags) mfaToken() (string, error) {
+	if !f.interactive {
+		return "", errors.New("an MFA code is required but the terminal is not interactive")
+	}
+	fmt.Fprint(os.Stderr, "Enter MFA code: ")
+	code, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && code == "" {
+		return "", fmt.Errorf("failed to read MFA code: %w", err)
+	}
+	return strings.TrimSpace(code), nil
+}
+
+// ssoLoginOption returns the eksauth.WithSSOLogin option for --sso-login.
+func (f *clusterFlags) ssoLoginOption() eksauth.Option {
+	if !f.ssoLogin {
+		return eksauth.WithSSOLogin(nil)
+	}
+	return eksauth.WithSSOLogin(f.ssoLoginPrompt)
+}
+
+// ssoLoginPrompt prints the SSO device authorization on stderr and opens it in a browser if possible.
+func (f *clusterFlags) ssoLoginPrompt(ctx context.Context, auth eksauth.SSODeviceAuthorization) error {
+	if !f.interactive {
+		return errors.New("the AWS SSO session is expired but the terminal is not interactive")
+	}
+	uri := auth.VerificationURIComplete
+	if uri == "" {
+		uri = auth.VerificationURI
+	}
+	fmt.Fprintf(os.Stderr, "Attempting to open the SSO authorization page in your browser.\nIf it does not open, visit:\n\n%s\n\nand confirm the code: %s\n", uri, auth.UserCode)
+	_ = openBrowser(uri)
+	return nil
+}
+
+// loadProfile loads the selected eks-auth profile, it returns nil if no profile was selected or configured.
+func (f *clusterFlags) loadProfile() (*eksauth.Profile, error) {
+	path := f.profilesFile
+	if path == "" {
+		var err error
+		if path, err = eksauth.DefaultProfilesPath(); err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) && f.profile == "" {
+			return nil, nil
+		}
+	}
+	load := eksauth.LoadProfiles
+	if f.strict {
+		load = eksauth.LoadProfilesStrict
+	}
+	profiles, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	if f.profile == "" && profiles.Default == "" {
+		return nil, nil
+	}
+	return profiles.Get(f.profile)
+}
+
+// clusterConfig builds the eksauth.ClusterConfig from the selected profile and the flags, flags take precedence.
+func (f *clusterFlags) clusterConfig() (eksauth.ClusterConfig, *eksauth.Profile, error) {
+	profile, err := f.loadProfile()
+	if err != nil {
+		return eksauth.ClusterConfig{}, nil, err
+	}
+	cluster := eksauth.ClusterConfig{Name: f.clusterName}
+	if cluster.Name == "" {
+		cluster.Name = os.Getenv(eksauth.EnvClusterName)
+	}
+	if profile != nil {
+		if _, ok := profile.Clusters[cluster.Name]; ok || cluster.Name == "" {
+			if cluster, err = profile.Cluster(cluster.Name); err != nil {
+				return cluster, nil, err
+			}
+		} else {
+			cluster.Region, cluster.RoleARN = profile.Region, profile.RoleARN
+			cluster.RoleDuration, cluster.Expiration = profile.RoleDuration, profile.Expiration
+		}
+	}
+	if f.region != "" {
+		cluster.Region = f.region
+	} else if _, isARN := eksauth.ParseClusterARN(cluster.Name); cluster.Region == "" && !isARN {
+		// The region of the AWS config (ie AWS_REGION) would otherwise take precedence over EKSAUTH_REGION.
+		cluster.Region = os.Getenv(eksauth.EnvRegion)
+	}
+	if f.roleARN != "" {
+		cluster.RoleARN = f.roleARN
+	}
+	if f.externalID != "" {
+		cluster.ExternalID = f.externalID
+	}
+	if f.mfaSerial != "" {
+		cluster.MFASerial = f.mfaSerial
+	}
+	if cluster.Name == "" {
+		return cluster, nil, fmt.Errorf("--cluster-name is required")
+	}
+	return cluster, profile, nil
+}
+
+// loadAWSConfig loads the aws.Config for the flags (and selected profile).
+func (f *clusterFlags) loadAWSConfig(ctx context.Context, profile *eksauth.Profile) (aws.Config, error) {
+	// Profiles with an mfa_serial prompt for the code when their role is assumed
+	opts := []func(*config.LoadOptions) error{
+		config.WithAssumeRoleCredentialOptions(func(o *stscreds.AssumeRoleOptions) {
+			o.TokenProvider = f.mfaToken
+		}),
+	}
+	awsProfile := f.awsProfile
+	if awsProfile == "" && profile != nil {
+		awsProfile = profile.AWSProfile
+	}
+	if awsProfile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(awsProfile))
+	}
+	return config.LoadDefaultConfig(ctx, opts...)
+}
+
+// tokenSource builds the token source for the flags.
+func (f *clusterFlags) tokenSource(ctx context.Context, opts ...eksauth.Option) (eksauth.ClusterConfig, *eksauth.ReuseTokenSource, error) {
+	cluster, profile, err := f.clusterConfig()
+	if err != nil {
+		return cluster, nil, err
+	}
+	cfg, err := f.loadAWSConfig(ctx, profile)
+	if err != nil {
+		return cluster, nil, err
+	}
+	ts, err := eksauth.NewFromClusterConfig(cfg, cluster, append([]eksauth.Option{eksauth.WithContext(ctx), eksauth.WithMFATokenProvider(f.mfaToken), f.ssoLoginOption()}, opts...)...)
+	if err != nil {
+		return cluster, nil, err
+	}
+	reuse, ok := ts.(*eksauth.ReuseTokenSource)
+	if !ok {
+		reuse = eksauth.NewReuseTokenSource(nil, ts, eksauth.DefaultEarlyExpiry)
+	}
+	return cluster, reuse, nil
+}
+
+// cachedTokenSource wraps the token source for the flags in an eksauth.CachedTokenSource using the default
+// eksauth.FileCache, so repeated invocations (ie by kubectl) reuse the token until it (early) expires.
+func (f *clusterFlags) cachedTokenSource(ctx context.Context, opts ...eksauth.Option) (eksauth.ClusterConfig, *eksauth.CachedTokenSource, error) {
+	cluster, ts, err := f.tokenSource(ctx, opts...)
+	if err != nil {
+		return cluster, nil, err
+	}
+	return cluster, eksauth.NewCachedTokenSource(ts, eksauth.NewFileCache(""), f.cacheKey(cluster)), nil
+}
+
+// cacheKeyEnv are the environment variables selecting the credentials (or the signing region) of a token: the
+// EKSAUTH_* role and region, static credentials, IRSA, EKS Pod Identity (container credentials) and the shared files.
+var cacheKeyEnv = []string{
+	eksauth.EnvRoleARN,
+	eksauth.EnvRoleDuration,
+	eksauth.EnvRegion,
+	"AWS_REGION",
+	"AWS_DEFAULT_REGION",
+	"AWS_ACCESS_KEY_ID",
+	"AWS_ROLE_ARN",
+	"AWS_ROLE_SESSION_NAME",
+	"AWS_WEB_IDENTITY_TOKEN_FILE",
+	"AWS_CONTAINER_CREDENTIALS_FULL_URI",
+	"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI",
+	"AWS_CONTAINER_AUTHORIZATION_TOKEN",
+	"AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE",
+	"AWS_CONFIG_FILE",
+	"AWS_SHARED_CREDENTIALS_FILE",
+}
+
+// cacheKey returns the eksauth.CacheKey of the cluster, the identity is derived from everything that selects
+// the credentials: the eks-auth and AWS profiles, the role (chain) with its external ID and MFA device, and the
+// cacheKeyEnv environment variables.
+func (f *clusterFlags) cacheKey(cluster eksauth.ClusterConfig) string {
+	awsProfile := f.awsProfile
+	if awsProfile == "" {
+		awsProfile = os.Getenv("AWS_PROFILE")
+	}
+	fields := []string{f.profile, awsProfile, cluster.RoleARN, cluster.ExternalID, cluster.MFASerial, cluster.STSEndpoint}
+	for _, name := range cacheKeyEnv {
+		fields = append(fields, name+"="+os.Getenv(name))
+	}
+	return eksauth.CacheKey(cluster.Name, cluster.Region, strings.Join(fields, "\x00"))
+}
+
+// apiVersionFlag normalizes the --api-version flag value ("v1", "v1beta1" or a full apiVersion).
+func apiVersionFlag(value string) string {
+	if value != "" && !strings.Contains(value, "/") {
+		return "client.authentication.k8s.io/" + value
+	}
+	return value
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"golang.org/x/oauth2"
+)
+
+// runGetToken implements the get-token subcommand.
+// When executed by kubectl it honors the exec plugin contract: the ExecCredential apiVersion requested in
+// KUBERNETES_EXEC_INFO is printed (unless --api-version is set) and tokens are cached on disk between invocations
+// (unless --no-cache is set). --output selects other formats for scripts: the raw token, JSON with metadata or
+// shell exports. --debug prints the signing input of the token to stderr.
+func runGetToken(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("get-token", flag.ContinueOnError)
+	var cf clusterFlags
+	cf.register(fs)
+	apiVersion := fs.String("api-version", "", "ExecCredential apiVersion to print, v1 or v1beta1 (default the apiVersion in $KUBERNETES_EXEC_INFO or v1beta1)")
+	noCache := fs.Bool("no-cache", os.Getenv(eksauth.EnvCacheMode) == eksauth.CacheModeNone, "neither read nor write the on-disk token cache (default true if $EKSAUTH_CACHE_MODE is none)")
+	forceRefresh := fs.Bool("force-refresh", false, "ignore the cached token and cache a newly generated one")
+	output := fs.String("output", outputExecCredential, "output format: "+strings.Join(outputFormats, ", "))
+	debug := fs.Bool("debug", false, "print the SigV4 canonical request, string to sign and presigned URL to stderr (implies --no-cache)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	execInfo, err := eksauth.LoadExecInfo()
+	if err != nil {
+		return err
+	}
+	version := apiVersionFlag(*apiVersion)
+	if version == "" {
+		version = eksauth.ExecCredentialV1beta1
+		if execInfo != nil {
+			version = execInfo.APIVersion
+		}
+	}
+	// Without an interactive terminal (spec.interactive is false) nothing may be read from stdin.
+	cf.interactive = execInfo == nil || execInfo.Spec.Interactive
+	if !slices.Contains(outputFormats, *output) {
+		return fmt.Errorf("unsupported --output %q, must be one of %s", *output, strings.Join(outputFormats, ", "))
+	}
+	var ts oauth2.TokenSource
+	var cluster eksauth.ClusterConfig
+	if *noCache || *debug {
+		var opts []eksauth.Option
+		if *debug {
+			opts = append(opts, eksauth.WithSigningDebug())
+		}
+		var reuse *eksauth.ReuseTokenSource
+		if cluster, reuse, err = cf.tokenSource(ctx, opts...); err != nil {
+			return err
+		}
+		ts = reuse
+	} else {
+		var cached *eksauth.CachedTokenSource
+		if cluster, cached, err = cf.cachedTokenSource(ctx); err != nil {
+			return err
+		}
+		if *forceRefresh {
+			cached.Invalidate()
+		}
+		ts = cached
+	}
+	if *debug {
+		token, err := eksauth.TokenWithContext(ctx, ts)
+		if err != nil {
+			return err
+		}
+		if d, ok := eksauth.TokenSigningDebug(token); ok {
+			fmt.Fprint(os.Stderr, d)
+		}
+	}
+	if *output != outputExecCredential {
+		token, err := eksauth.TokenWithContext(ctx, ts)
+		if err != nil {
+			return err
+		}
+		return writeToken(os.Stdout, *output, cluster, token)
+	}
+	out, err := eksauth.ExecCredentialJSON(ctx, ts, version)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(os.Stdout, string(out))
+	return err
+}
@@ -0,0 +1,68 @@
+// Command eks-auth generates AWS EKS authentication tokens, it is a drop-in replacement for
+// `aws eks get-token` and `aws-iam-authenticator token` that does not require the AWS CLI.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+)
+
+// command is a subcommand of the CLI.
+type command struct {
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+// commands are the subcommands of the CLI, keyed by name.
+var commands = map[string]command{
+	"bundle":             {"export or import an encrypted access bundle (a kubeconfig with an embedded token)", runBundle},
+	"daemon":             {"keep a fresh token for a cluster written to a file", runDaemon},
+	"get-token":          {"print an ExecCredential containing a token for a cluster", runGetToken},
+	"migrate-kubeconfig": {"rewrite aws eks get-token and aws-iam-authenticator users of a kubeconfig to eks-auth", runMigrateKubeconfig},
+	"serve-socket":       {"serve tokens for a cluster over a local unix domain socket", runServeSocket},
+	"update-kubeconfig":  {"write or merge a kubeconfig context for a cluster", runUpdateKubeconfig},
+	"verify":             {"check a token offline and print the caller identity it was signed by", runVerify},
+}
+
+// usage prints the top-level usage to stderr.
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: eks-auth <command> [flags]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", name, commands[name].usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		if os.Args[1] != "-h" && os.Args[1] != "--help" && os.Args[1] != "help" {
+			fmt.Fprintf(os.Stderr, "eks-auth: unknown command %q\n\n", os.Args[1])
+		}
+		usage()
+		os.Exit(2)
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if err := cmd.run(ctx, os.Args[2:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "eks-auth %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bored-engineer/aws-eks-auth/kube"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// runMigrateKubeconfig implements the migrate-kubeconfig subcommand, it rewrites the `aws eks get-token` and
+// `aws-iam-authenticator token` exec plugin stanzas of a kubeconfig file to `eks-auth get-token`.
+func runMigrateKubeconfig(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("migrate-kubeconfig", flag.ContinueOnError)
+	path := fs.String("kubeconfig", "", "kubeconfig file to migrate (default the first file of $KUBECONFIG or ~/.kube/config)")
+	command := fs.String("command", kube.DefaultExecCommand, "command of the migrated exec plugin stanzas")
+	dryRun := fs.Bool("dry-run", false, "print the diff of the kubeconfig file to stdout instead of updating it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" {
+		*path = kube.DefaultKubeconfigPath()
+	}
+	before, err := os.ReadFile(*path)
+	if err != nil {
+		return err
+	}
+	cfg, err := clientcmd.Load(before)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", *path, err)
+	}
+	users := kube.MigrateKubeconfig(cfg, *command)
+	if len(users) == 0 {
+		fmt.Fprintf(os.Stderr, "No aws eks get-token or aws-iam-authenticator users to migrate in %s\n", *path)
+		return nil
+	}
+	if *dryRun {
+		// Diff against the re-serialized original so only the migrated stanzas differ, not the formatting.
+		orig, err := clientcmd.Load(before)
+		if err != nil {
+			return err
+		}
+		from, err := clientcmd.Write(*orig)
+		if err != nil {
+			return err
+		}
+		to, err := clientcmd.Write(*cfg)
+		if err != nil {
+			return err
+		}
+		return writeDiff(os.Stdout, *path, from, to)
+	}
+	if err := clientcmd.WriteToFile(*cfg, *path); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Migrated users %s in %s\n", strings.Join(users, ", "), *path)
+	return nil
+}
+
+// diffContext is the number of unchanged lines around every hunk printed by writeDiff.
+const diffContext = 3
+
+// writeDiff writes the unified diff of the lines of from and to (a kubeconfig is small, so a quadratic longest
+// common subsequence is fine).
+func writeDiff(w io.Writer, name string, from, to []byte) error {
+	a, b := strings.SplitAfter(string(from), "\n"), strings.SplitAfter(string(to), "\n")
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	type line struct {
+		op   byte
+		text string
+		i, j int // the (0-based) line numbers in a and b before the line
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', a[i], i, j})
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', a[i], i, j})
+			i++
+		default:
+			lines = append(lines, line{'+', b[j], i, j})
+			j++
+		}
+	}
+	if _, err := fmt.Fprintf(w, "--- %s\n+++ %s\n", name, name); err != nil {
+		return err
+	}
+	for start := 0; start < len(lines); {
+		if lines[start].op == ' ' {
+			start++
+			continue
+		}
+		// Extend the hunk until diffContext*2 unchanged lines separate it from the next change.
+		first, last := max(start-diffContext, 0), start
+		for idx := start; idx < len(lines) && idx-last <= 2*diffContext; idx++ {
+			if lines[idx].op != ' ' {
+				last = idx
+			}
+		}
+		end := min(last+diffContext+1, len(lines))
+		var fromLen, toLen int
+		for _, l := range lines[first:end] {
+			if l.op != '+' {
+				fromLen++
+			}
+			if l.op != '-' {
+				toLen++
+			}
+		}
+		if _, err := fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", lines[first].i+1, fromLen, lines[first].j+1, toLen); err != nil {
+			return err
+		}
+		for _, l := range lines[first:end] {
+			text := l.text
+			if !strings.HasSuffix(text, "\n") {
+				text += "\n"
+			}
+			if _, err := fmt.Fprintf(w, "%c%s", l.op, text); err != nil {
+				return err
+			}
+		}
+		start = end
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"golang.org/x/oauth2"
+)
+
+// Output formats of get-token (--output).
+const (
+	outputExecCredential = "exec-credential"
+	outputToken          = "token"
+	outputJSON           = "json"
+	outputEnv            = "env"
+)
+
+// outputFormats are the supported --output values.
+var outputFormats = []string{outputExecCredential, outputToken, outputJSON, outputEnv}
+
+// tokenMetadata is the --output=json document.
+type tokenMetadata struct {
+	Token               string     `json:"token"`
+	ExpirationTimestamp time.Time  `json:"expirationTimestamp"`
+	ClusterName         string     `json:"clusterName"`
+	Region              string     `json:"region,omitempty"`
+	AccessKeyID         string     `json:"accessKeyId,omitempty"`
+	SigningTime         *time.Time `json:"signingTime,omitempty"`
+	CredentialSources   []string   `json:"credentialSources,omitempty"`
+	RoleChain           []string   `json:"roleChain,omitempty"`
+}
+
+// writeToken writes the token in an output format other than exec-credential.
+func writeToken(w io.Writer, format string, cluster eksauth.ClusterConfig, token *oauth2.Token) error {
+	switch format {
+	case outputToken:
+		_, err := fmt.Fprintln(w, token.AccessToken)
+		return err
+	case outputEnv:
+		_, err := fmt.Fprintf(w, "export EKS_TOKEN=%s\nexport EKS_TOKEN_EXPIRATION=%s\n",
+			shellQuote(token.AccessToken), shellQuote(token.Expiry.UTC().Format(time.RFC3339)))
+		return err
+	case outputJSON:
+		meta := tokenMetadata{
+			Token:               token.AccessToken,
+			ExpirationTimestamp: token.Expiry.UTC(),
+			ClusterName:         cluster.Name,
+			Region:              cluster.Region,
+		}
+		// Tokens read from the on-disk cache have no provenance, the presigned URL has most of it.
+		if parsed, err := eksauth.ParseToken(token.AccessToken); err == nil {
+			meta.Region, meta.AccessKeyID, meta.SigningTime = parsed.Region, parsed.AccessKeyID, &parsed.SigningTime
+		}
+		if provenance, ok := eksauth.TokenProvenance(token); ok {
+			meta.CredentialSources, meta.RoleChain = provenance.CredentialSources, provenance.RoleChain
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(meta)
+	}
+	return fmt.Errorf("unsupported --output %q, must be one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+)
+
+// runServeSocket implements the serve-socket subcommand.
+func runServeSocket(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("serve-socket", flag.ContinueOnError)
+	var cf clusterFlags
+	cf.register(fs)
+	path := fs.String("socket", "", "path of the unix domain socket (default token.sock in the cache directory)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cluster, ts, err := cf.tokenSource(ctx)
+	if err != nil {
+		return err
+	}
+	if *path == "" {
+		dir, err := eksauth.CacheDir()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+		*path = filepath.Join(dir, "token.sock")
+	}
+	fmt.Fprintf(os.Stderr, "Serving tokens for %s on %s\n", cluster.Name, *path)
+	return eksauth.NewTokenServer(ts, cluster.Name).ListenAndServe(ctx, *path)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"github.com/bored-engineer/aws-eks-auth/kube"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// runUpdateKubeconfig implements the update-kubeconfig subcommand.
+func runUpdateKubeconfig(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("update-kubeconfig", flag.ContinueOnError)
+	var cf clusterFlags
+	cf.register(fs)
+	path := fs.String("kubeconfig", "", "kubeconfig file to update (default the first file of $KUBECONFIG or ~/.kube/config)")
+	alias := fs.String("alias", "", "name of the cluster and context (default the cluster ARN)")
+	userAlias := fs.String("user-alias", "", "name of the user (default the alias)")
+	staticToken := fs.Bool("static-token", false, "embed a static token (valid for at most 15 minutes) instead of an exec plugin")
+	dryRun := fs.Bool("dry-run", false, "print the generated kubeconfig entry to stdout instead of updating the kubeconfig file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cluster, profile, err := cf.clusterConfig()
+	if err != nil {
+		return err
+	}
+	cfg, err := cf.loadAWSConfig(ctx, profile)
+	if err != nil {
+		return err
+	}
+	if cluster.Region != "" {
+		cfg.Region = cluster.Region
+	}
+	info, err := eksauth.NewDiscoveryFromConfig(cfg).Describe(ctx, cluster.Name)
+	if err != nil {
+		return err
+	}
+	opts := kube.KubeconfigOptions{
+		Alias:      *alias,
+		UserAlias:  *userAlias,
+		Region:     cf.region,
+		RoleARN:    cf.roleARN,
+		ExternalID: cf.externalID,
+		MFASerial:  cf.mfaSerial,
+		SSOLogin:   cf.ssoLogin,
+		AWSProfile: cf.awsProfile,
+		Profile:    cf.profile,
+	}
+	if *staticToken {
+		ts, err := eksauth.NewFromClusterConfig(cfg, cluster, eksauth.WithContext(ctx), eksauth.WithMFATokenProvider(cf.mfaToken), cf.ssoLoginOption())
+		if err != nil {
+			return err
+		}
+		t, err := eksauth.TokenWithContext(ctx, ts)
+		if err != nil {
+			return err
+		}
+		opts.Token = t.AccessToken
+	}
+	entry := kube.NewKubeconfig(info, opts)
+	if *dryRun {
+		out, err := clientcmd.Write(*entry)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	if *path == "" {
+		*path = kube.DefaultKubeconfigPath()
+	}
+	if err := kube.UpdateKubeconfig(*path, entry); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Updated context %s in %s\n", entry.CurrentContext, *path)
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+)
+
+// runVerify implements the verify subcommand, it prints the contents of a token, its violations and (unless
+// --offline) the caller identity returned by STS.
+func runVerify(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	token := fs.String("token", "", "token to verify, - reads it from stdin")
+	clusterName := fs.String("cluster-name", "", "cluster ID the token must be signed for (default $EKSAUTH_CLUSTER_NAME)")
+	partition := fs.String("partition", "", "AWS partition the token must target (ie aws-cn)")
+	offline := fs.Bool("offline", false, "only run the offline checks, do not call sts:GetCallerIdentity")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *token == "-" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read token from stdin: %w", err)
+		}
+		*token = line
+	}
+	*token = strings.TrimSpace(*token)
+	if *token == "" {
+		return errors.New("--token is required")
+	}
+	if *clusterName == "" {
+		*clusterName = os.Getenv(eksauth.EnvClusterName)
+	}
+	if parsed, err := eksauth.ParseToken(*token); err == nil {
+		fmt.Printf("Access key ID: %s\n", parsed.AccessKeyID)
+		fmt.Printf("Region:        %s\n", parsed.Region)
+		fmt.Printf("Host:          %s\n", parsed.URL.Host)
+		fmt.Printf("Signed at:     %s\n", parsed.SigningTime.Format(time.RFC3339))
+		fmt.Printf("Expires at:    %s\n", parsed.Expiry().Format(time.RFC3339))
+	}
+	v := eksauth.NewVerifier(*clusterName)
+	v.Partition = *partition
+	if violations := v.Violations(*token); len(violations) > 0 {
+		fmt.Println("Violations:")
+		for _, violation := range violations {
+			fmt.Printf("  - %s\n", violation)
+		}
+		return errors.New("token is invalid")
+	}
+	if *offline {
+		fmt.Println("Token passed the offline checks")
+		return nil
+	}
+	if *clusterName == "" {
+		return errors.New("--cluster-name is required unless --offline is set")
+	}
+	identity, err := v.Verify(ctx, *token)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Caller ARN:    %s\n", identity.ARN)
+	fmt.Printf("Account:       %s\n", identity.Account)
+	fmt.Printf("User ID:       %s\n", identity.UserID)
+	return nil
+}
@@ -0,0 +1,70 @@
+package eksauth
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ContextTokenSource is an oauth2.TokenSource that can also generate tokens bound to a context.Context,
+// allowing timeouts, cancellation and trace propagation when credentials are retrieved (ie IMDS or SSO).
+// Every token source in this package implements it.
+type ContextTokenSource interface {
+	oauth2.TokenSource
+	TokenWithContext(ctx context.Context) (*oauth2.Token, error)
+}
+
+// TokenWithContext returns a token from ts using ctx if ts implements ContextTokenSource,
+// otherwise it falls back to ts.Token() (ignoring ctx).
+func TokenWithContext(ctx context.Context, ts oauth2.TokenSource) (*oauth2.Token, error) {
+	if cts, ok := ts.(ContextTokenSource); ok {
+		return cts.TokenWithContext(ctx)
+	}
+	return ts.Token()
+}
+
+// baseContext returns ctx, or context.Background() if it is nil.
+func baseContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// baseContextTokenSource is an oauth2.TokenSource whose Token calls Source with a base context, the New*
+// constructors use it when EKSAUTH_CACHE_MODE is "none" and the outermost wrapper would use context.Background().
+type baseContextTokenSource struct {
+	Source oauth2.TokenSource
+	ctx    context.Context
+}
+
+// Token implements the oauth2.TokenSource interface using the base context.
+func (s *baseContextTokenSource) Token() (*oauth2.Token, error) {
+	return TokenWithContext(s.ctx, s.Source)
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+func (s *baseContextTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	return TokenWithContext(ctx, s.Source)
+}
+
+// Invalidate implements the Invalidator interface.
+func (s *baseContextTokenSource) Invalidate() {
+	Invalidate(s.Source)
+}
+
+// Close implements the io.Closer interface.
+func (s *baseContextTokenSource) Close() error {
+	return closeTokenSource(s.Source)
+}
+
+// ExpiresAt implements the ExpiryReporter interface using Source.
+func (s *baseContextTokenSource) ExpiresAt() time.Time {
+	return expiresAt(s.Source)
+}
+
+// Remaining implements the ExpiryReporter interface.
+func (s *baseContextTokenSource) Remaining() time.Duration {
+	return remaining(s.ExpiresAt())
+}
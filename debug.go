@@ -0,0 +1,122 @@
+package eksauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// DebugTokenState is the state of a single token source reported by DebugHandler, it never contains the token.
+type DebugTokenState struct {
+	Cached            bool       `json:"cached"`
+	Valid             bool       `json:"valid"`
+	Expiry            *time.Time `json:"expiry,omitempty"`
+	NextRefresh       *time.Time `json:"next_refresh,omitempty"`
+	LastRefresh       *time.Time `json:"last_refresh,omitempty"`
+	LastError         string     `json:"last_error,omitempty"`
+	Hits              uint64     `json:"hits"`
+	Refreshes         uint64     `json:"refreshes"`
+	Failures          uint64     `json:"failures"`
+	CredentialSources []string   `json:"credential_sources,omitempty"`
+	RoleChain         []string   `json:"role_chain,omitempty"`
+	Region            string     `json:"region,omitempty"`
+	Host              string     `json:"host,omitempty"`
+}
+
+// timePtr returns nil for the zero time.Time, so it is omitted from JSON.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// DebugState returns the DebugTokenState of a token source, only *ReuseTokenSource reports cache details.
+func DebugState(ts oauth2.TokenSource) DebugTokenState {
+	var state DebugTokenState
+	reuse, ok := reuseTokenSource(ts)
+	if !ok {
+		return state
+	}
+	token, cached := reuse.Peek()
+	stats := reuse.Stats()
+	state.Cached = cached
+	state.Valid = reuse.Valid()
+	state.Expiry = timePtr(reuse.Expiry())
+	state.NextRefresh = timePtr(reuse.NextRefresh())
+	state.LastRefresh = timePtr(stats.LastRefresh)
+	if stats.LastError != nil {
+		state.LastError = stats.LastError.Error()
+	}
+	state.Hits, state.Refreshes, state.Failures = stats.Hits, stats.Refreshes, stats.Failures
+	if provenance, ok := TokenProvenance(token); ok {
+		state.CredentialSources = provenance.CredentialSources
+		state.RoleChain = provenance.RoleChain
+		state.Region = provenance.Region
+		state.Host = provenance.Host
+	}
+	return state
+}
+
+// DebugHandler is an http.Handler (typically mounted at /debug/eksauth) reporting the state of token sources as JSON.
+// Tokens themselves are never exposed.
+type DebugHandler struct {
+	// Sources returns the token sources to report on, keyed by cluster name.
+	Sources func() map[string]oauth2.TokenSource
+	// Registry (optional) returns the statistics of the registry the sources came from.
+	Registry func() RegistryStats
+}
+
+// NewDebugHandler creates a DebugHandler for a fixed set of token sources keyed by cluster name.
+func NewDebugHandler(sources map[string]oauth2.TokenSource) *DebugHandler {
+	return &DebugHandler{
+		Sources: func() map[string]oauth2.TokenSource {
+			return sources
+		},
+	}
+}
+
+// NewRegistryDebugHandler creates a DebugHandler for every entry in a Registry, keys are formatted with fmt.Sprint.
+func NewRegistryDebugHandler[K comparable](r *Registry[K]) *DebugHandler {
+	return &DebugHandler{
+		Sources: func() map[string]oauth2.TokenSource {
+			sources := make(map[string]oauth2.TokenSource)
+			r.Range(func(key K, ts oauth2.TokenSource) bool {
+				sources[fmt.Sprint(key)] = ts
+				return true
+			})
+			return sources
+		},
+		Registry: r.Stats,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *DebugHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	var resp struct {
+		Clusters map[string]DebugTokenState `json:"clusters"`
+		Registry *RegistryStats             `json:"registry,omitempty"`
+	}
+	resp.Clusters = make(map[string]DebugTokenState)
+	if h.Sources != nil {
+		for name, ts := range h.Sources() {
+			resp.Clusters[name] = DebugState(ts)
+		}
+	}
+	if h.Registry != nil {
+		stats := h.Registry()
+		resp.Registry = &stats
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(resp)
+}
@@ -0,0 +1,205 @@
+package eksauth
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+)
+
+// ErrClusterNameNotDetected is returned by DetectClusterName when none of the detectors found a cluster name.
+var ErrClusterNameNotDetected = errors.New("eksauth: unable to detect the EKS cluster name")
+
+// ClusterNameDetector discovers the name of the EKS cluster the current process is running in.
+// Implementations return ErrClusterNameNotDetected if the cluster name could not be found.
+type ClusterNameDetector interface {
+	DetectClusterName(ctx context.Context) (string, error)
+}
+
+// ClusterNameDetectorFunc is a function implementing the ClusterNameDetector interface.
+type ClusterNameDetectorFunc func(ctx context.Context) (string, error)
+
+// DetectClusterName implements the ClusterNameDetector interface.
+func (fn ClusterNameDetectorFunc) DetectClusterName(ctx context.Context) (string, error) {
+	return fn(ctx)
+}
+
+// EnvClusterNameDetector detects the cluster name from the EKSAUTH_CLUSTER_NAME environment variable.
+var EnvClusterNameDetector = ClusterNameDetectorFunc(func(ctx context.Context) (string, error) {
+	if name := os.Getenv(EnvClusterName); name != "" {
+		return name, nil
+	}
+	return "", ErrClusterNameNotDetected
+})
+
+// IMDSClusterNameTags are the EC2 instance tags (in order) that EKS applies to worker nodes containing the cluster name.
+var IMDSClusterNameTags = []string{"aws:eks:cluster-name", "eks:cluster-name"}
+
+// IMDSClusterNameDetector detects the cluster name from the tags of the EC2 instance the process is running on.
+// NOTE: This requires instance metadata tags to be enabled for the instance (InstanceMetadataTags=enabled).
+type IMDSClusterNameDetector struct {
+	Client *imds.Client
+}
+
+// DetectClusterName implements the ClusterNameDetector interface.
+func (d *IMDSClusterNameDetector) DetectClusterName(ctx context.Context) (string, error) {
+	client := d.Client
+	if client == nil {
+		client = imds.New(imds.Options{})
+	}
+	var errs []error
+	for _, tag := range IMDSClusterNameTags {
+		out, err := client.GetMetadata(ctx, &imds.GetMetadataInput{
+			Path: "tags/instance/" + tag,
+		})
+		var status interface{ HTTPStatusCode() int }
+		if errors.As(err, &status) && status.HTTPStatusCode() == http.StatusNotFound {
+			// The instance does not have this tag (or instance metadata tags are disabled).
+			continue
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("eksauth: failed to get instance tag %q: %w", tag, err))
+			continue
+		}
+		b, err := io.ReadAll(out.Content)
+		out.Content.Close()
+		if err != nil {
+			return "", fmt.Errorf("eksauth: failed to read instance tag %q: %w", tag, err)
+		}
+		if name := strings.TrimSpace(string(b)); name != "" {
+			return name, nil
+		}
+	}
+	return "", errors.Join(append([]error{ErrClusterNameNotDetected}, errs...)...)
+}
+
+// DefaultServiceAccountTokenFile is the Kubernetes service account token mounted in pods.
+const DefaultServiceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// EKSClusterAPIClient is the subset of the eks.Client used to find a cluster by its OIDC issuer.
+type EKSClusterAPIClient interface {
+	eks.ListClustersAPIClient
+	eks.DescribeClusterAPIClient
+}
+
+// EKSAPIClusterNameDetector detects the cluster name using the EKS API: the issuer of the service account token of
+// the pod is the OIDC issuer of its cluster, which is searched with eks:ListClusters and eks:DescribeCluster in the
+// region of Client. It requires those permissions in the account of the cluster.
+type EKSAPIClusterNameDetector struct {
+	// Client is the EKS client, one is created from the default AWS config if nil.
+	Client EKSClusterAPIClient
+	// TokenFile is the service account token, DefaultServiceAccountTokenFile if empty.
+	TokenFile string
+}
+
+// NewEKSAPIClusterNameDetector creates an EKSAPIClusterNameDetector using an eks.Client built from an aws.Config.
+func NewEKSAPIClusterNameDetector(cfg aws.Config) *EKSAPIClusterNameDetector {
+	return &EKSAPIClusterNameDetector{Client: eks.NewFromConfig(cfg, WithEKSUserAgent(""))}
+}
+
+// DetectClusterName implements the ClusterNameDetector interface.
+func (d *EKSAPIClusterNameDetector) DetectClusterName(ctx context.Context) (string, error) {
+	return detectClusterNameByIssuer(ctx, d.Client, cmp.Or(d.TokenFile, DefaultServiceAccountTokenFile))
+}
+
+// PodIdentityClusterNameDetector detects the cluster name of a pod using EKS Pod Identity: the issuer of the Pod
+// Identity token (AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE) is the OIDC issuer of its cluster, which is searched with
+// the EKS API like EKSAPIClusterNameDetector. It does not detect anything if the pod does not use Pod Identity.
+type PodIdentityClusterNameDetector struct {
+	// Client is the EKS client, one is created from the default AWS config if nil.
+	Client EKSClusterAPIClient
+	// TokenFile overrides AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE.
+	TokenFile string
+}
+
+// NewPodIdentityClusterNameDetector creates a PodIdentityClusterNameDetector using an eks.Client built from an aws.Config.
+func NewPodIdentityClusterNameDetector(cfg aws.Config) *PodIdentityClusterNameDetector {
+	return &PodIdentityClusterNameDetector{Client: eks.NewFromConfig(cfg, WithEKSUserAgent(""))}
+}
+
+// DetectClusterName implements the ClusterNameDetector interface.
+func (d *PodIdentityClusterNameDetector) DetectClusterName(ctx context.Context) (string, error) {
+	tokenFile := cmp.Or(d.TokenFile, os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"))
+	if tokenFile == "" {
+		return "", ErrClusterNameNotDetected
+	}
+	return detectClusterNameByIssuer(ctx, d.Client, tokenFile)
+}
+
+// detectClusterNameByIssuer returns the name of the cluster whose OIDC issuer issued the token in tokenFile.
+func detectClusterNameByIssuer(ctx context.Context, client EKSClusterAPIClient, tokenFile string) (string, error) {
+	token, err := os.ReadFile(tokenFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", ErrClusterNameNotDetected
+	} else if err != nil {
+		return "", fmt.Errorf("eksauth: failed to read service account token: %w", err)
+	}
+	issuer, ok := jwtIssuer(bytes.TrimSpace(token))
+	if !ok || !strings.HasPrefix(issuer, "https://oidc.eks.") {
+		// Not issued by an EKS cluster.
+		return "", ErrClusterNameNotDetected
+	}
+	if client == nil {
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return "", fmt.Errorf("eksauth: failed to load the AWS config for the EKS API: %w", err)
+		}
+		client = eks.NewFromConfig(cfg, WithEKSUserAgent(""))
+	}
+	paginator := eks.NewListClustersPaginator(client, &eks.ListClustersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", fmt.Errorf("eksauth: ListClusters: %w", err)
+		}
+		for _, name := range page.Clusters {
+			out, err := client.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(name)})
+			if err != nil {
+				return "", fmt.Errorf("eksauth: DescribeCluster %s: %w", name, err)
+			}
+			if out.Cluster != nil && out.Cluster.Identity != nil && out.Cluster.Identity.Oidc != nil &&
+				aws.ToString(out.Cluster.Identity.Oidc.Issuer) == issuer {
+				return name, nil
+			}
+		}
+	}
+	return "", ErrClusterNameNotDetected
+}
+
+// DefaultClusterNameDetectors are the detectors used by DetectClusterName when none are provided.
+var DefaultClusterNameDetectors = []ClusterNameDetector{
+	EnvClusterNameDetector,
+	&IMDSClusterNameDetector{},
+	&PodIdentityClusterNameDetector{},
+	&EKSAPIClusterNameDetector{},
+}
+
+// DetectClusterName returns the first cluster name found by the provided detectors (or DefaultClusterNameDetectors).
+// A failing detector does not stop the detection, if no detector found the cluster name the errors of the detectors
+// are joined to ErrClusterNameNotDetected.
+func DetectClusterName(ctx context.Context, detectors ...ClusterNameDetector) (string, error) {
+	if len(detectors) == 0 {
+		detectors = DefaultClusterNameDetectors
+	}
+	errs := []error{ErrClusterNameNotDetected}
+	for _, detector := range detectors {
+		name, err := detector.DetectClusterName(ctx)
+		if err == nil {
+			return name, nil
+		}
+		if err != ErrClusterNameNotDetected {
+			errs = append(errs, err)
+		}
+	}
+	return "", errors.Join(errs...)
+}
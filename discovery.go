@@ -0,0 +1,158 @@
+package eksauth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+)
+
+// ErrConnectorCluster is returned (wrapped) by ClusterInfoFromCluster and Discovery.Describe for clusters registered
+// with EKS Connector: their API server is not reachable through EKS and does not accept IAM tokens, use the
+// credentials of the registered cluster (ie its own kubeconfig) instead.
+var ErrConnectorCluster = errors.New("eksauth: cluster is registered with EKS Connector, IAM tokens are not accepted")
+
+// DefaultDiscoveryTTL is how long a Discovery caches the ClusterInfo of a cluster.
+var DefaultDiscoveryTTL = time.Hour
+
+// ClusterInfo are the connection details of an EKS cluster returned by eks:DescribeCluster.
+type ClusterInfo struct {
+	// Name is the EKS cluster name.
+	Name string `json:"name"`
+	// ARN is the EKS cluster ARN.
+	ARN string `json:"arn,omitempty"`
+	// Region is the AWS region of the cluster.
+	Region string `json:"region,omitempty"`
+	// Endpoint is the Kubernetes API server URL.
+	Endpoint string `json:"endpoint"`
+	// CertificateAuthorityData is the PEM encoded CA bundle of the API server.
+	CertificateAuthorityData []byte `json:"certificate_authority_data,omitempty"`
+	// AuthenticationMode is the authentication mode of the cluster, CONFIG_MAP for clusters that predate access entries.
+	AuthenticationMode ekstypes.AuthenticationMode `json:"authentication_mode,omitempty"`
+	// Version is the Kubernetes version of the cluster.
+	Version string `json:"version,omitempty"`
+}
+
+// ClusterInfoFromCluster converts the Cluster of an eks:DescribeCluster response into a ClusterInfo.
+// It fails with ErrConnectorCluster for clusters registered with EKS Connector.
+func ClusterInfoFromCluster(cluster *ekstypes.Cluster) (*ClusterInfo, error) {
+	if cluster != nil && cluster.ConnectorConfig != nil {
+		return nil, fmt.Errorf("%w (provider %s)", ErrConnectorCluster, aws.ToString(cluster.ConnectorConfig.Provider))
+	}
+	if cluster == nil || aws.ToString(cluster.Endpoint) == "" {
+		return nil, errors.New("eksauth: cluster has no endpoint")
+	}
+	info := &ClusterInfo{
+		Name:               aws.ToString(cluster.Name),
+		ARN:                aws.ToString(cluster.Arn),
+		Endpoint:           aws.ToString(cluster.Endpoint),
+		AuthenticationMode: ekstypes.AuthenticationModeConfigMap,
+		Version:            aws.ToString(cluster.Version),
+	}
+	if a, ok := ParseClusterARN(info.ARN); ok {
+		info.Region = a.Region
+	}
+	if cluster.CertificateAuthority != nil && cluster.CertificateAuthority.Data != nil {
+		data, err := base64.StdEncoding.DecodeString(*cluster.CertificateAuthority.Data)
+		if err != nil {
+			return nil, fmt.Errorf("eksauth: invalid certificate authority data: %w", err)
+		}
+		info.CertificateAuthorityData = data
+	}
+	if cluster.AccessConfig != nil && cluster.AccessConfig.AuthenticationMode != "" {
+		info.AuthenticationMode = cluster.AccessConfig.AuthenticationMode
+	}
+	return info, nil
+}
+
+// discoveryEntry is a cached ClusterInfo.
+type discoveryEntry struct {
+	info    *ClusterInfo
+	expires time.Time
+}
+
+// Discovery discovers (and caches) the ClusterInfo of EKS clusters using eks:DescribeCluster.
+// It is safe for concurrent use.
+type Discovery struct {
+	Client eks.DescribeClusterAPIClient
+	// TTL is how long results are cached, DefaultDiscoveryTTL if zero, negative disables caching.
+	TTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]discoveryEntry
+}
+
+// NewDiscovery creates a Discovery using client.
+func NewDiscovery(client eks.DescribeClusterAPIClient) *Discovery {
+	return &Discovery{Client: client}
+}
+
+// NewDiscoveryFromConfig creates a Discovery using an eks.Client built from an aws.Config.
+func NewDiscoveryFromConfig(cfg aws.Config) *Discovery {
+	return NewDiscovery(eks.NewFromConfig(cfg, func(o *eks.Options) {
+		// The retryer of cfg, if configured, takes precedence.
+		if cfg.Retryer == nil {
+			o.Retryer = NewRetryer()
+		}
+	}, WithEKSUserAgent("")))
+}
+
+// ttl returns the configured TTL or DefaultDiscoveryTTL.
+func (d *Discovery) ttl() time.Duration {
+	if d.TTL != 0 {
+		return d.TTL
+	}
+	return DefaultDiscoveryTTL
+}
+
+// Describe returns the ClusterInfo of the cluster, clusterName may be a cluster ARN in which case DescribeCluster
+// is called in the region of the ARN. Clusters registered with EKS Connector fail with ErrConnectorCluster.
+func (d *Discovery) Describe(ctx context.Context, clusterName string) (*ClusterInfo, error) {
+	d.mu.Lock()
+	entry, ok := d.cache[clusterName]
+	d.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.info, nil
+	}
+
+	name, region := splitClusterName(clusterName)
+	var optFns []func(*eks.Options)
+	if region != "" {
+		optFns = append(optFns, func(o *eks.Options) {
+			o.Region = region
+		})
+	}
+	out, err := d.Client.DescribeCluster(ctx, &eks.DescribeClusterInput{
+		Name: aws.String(name),
+	}, optFns...)
+	if err != nil {
+		return nil, &Error{Op: "DescribeCluster", ClusterName: clusterName, Err: wrapThrottled("DescribeCluster", err)}
+	}
+	info, err := ClusterInfoFromCluster(out.Cluster)
+	if err != nil {
+		return nil, &Error{Op: "DescribeCluster", ClusterName: clusterName, Err: err}
+	}
+
+	if ttl := d.ttl(); ttl > 0 {
+		d.mu.Lock()
+		if d.cache == nil {
+			d.cache = make(map[string]discoveryEntry)
+		}
+		d.cache[clusterName] = discoveryEntry{info: info, expires: time.Now().Add(ttl)}
+		d.mu.Unlock()
+	}
+	return info, nil
+}
+
+// Invalidate removes the cached ClusterInfo of the cluster, ie after the API server endpoint or CA rotated.
+func (d *Discovery) Invalidate(clusterName string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.cache, clusterName)
+}
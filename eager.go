@@ -0,0 +1,37 @@
+package eksauth
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"golang.org/x/oauth2"
+)
+
+// Prefetch fetches the first token of ts using ctx and returns ts, so misconfiguration (a bad role, missing
+// credentials or the wrong region) fails at startup instead of on the first Kubernetes request.
+// The token is reused by the returned token source if it caches tokens (ie the New* constructors).
+func Prefetch(ctx context.Context, ts oauth2.TokenSource) (oauth2.TokenSource, error) {
+	if _, err := TokenWithContext(ctx, ts); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// NewFromConfigEager is NewFromConfig fetching the first token using ctx, see Prefetch.
+func NewFromConfigEager(ctx context.Context, cfg aws.Config, clusterName string, opts ...Option) (oauth2.TokenSource, error) {
+	return Prefetch(ctx, NewFromConfig(cfg, clusterName, opts...))
+}
+
+// NewFromClusterConfigEager is NewFromClusterConfig fetching the first token using ctx, see Prefetch.
+func NewFromClusterConfigEager(ctx context.Context, cfg aws.Config, cluster ClusterConfig, opts ...Option) (oauth2.TokenSource, error) {
+	ts, err := NewFromClusterConfig(cfg, cluster, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return Prefetch(ctx, ts)
+}
+
+// NewFromRoleEager is NewFromRole fetching the first token using ctx, see Prefetch.
+func NewFromRoleEager(ctx context.Context, cfg aws.Config, roleARN, clusterName string, opts ...Option) (oauth2.TokenSource, error) {
+	return Prefetch(ctx, NewFromRole(cfg, roleARN, clusterName, opts...))
+}
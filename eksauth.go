@@ -2,28 +2,58 @@ package eksauth
 
 import (
 	"context"
-	"encoding/base64"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go/middleware"
 	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/oauth2"
 )
 
 // DefaultExpiration is the default expiration time for a generated EKS token.
+// The New* constructors read it (and DefaultEarlyExpiry) once at construction, prefer WithExpiration over mutating it.
 var DefaultExpiration = 15 * time.Minute
 
+// DefaultPresignExpires is the default X-Amz-Expires of the presigned sts:GetCallerIdentity URL.
+var DefaultPresignExpires = 60 * time.Second
+
 // DefaultEarlyExpiry is the delta added to expire generated tokens early to account for clock skew.
 var DefaultEarlyExpiry = 60 * time.Second
 
-// wrappedSignerV4 extracts the expiration time of the credentials that were used to sign each request.
-// If they will expire prior to the target time.Time, it replaces that value with the credential expiration.
+// clusterIDHeader is the signed header containing the cluster name (ID) a token is valid for.
+const clusterIDHeader = "X-K8s-Aws-Id"
+
+// ClampToCredentialExpiry is the default ExpiryClamp, it returns the expiry of the credentials if they expire
+// before the token would.
+func ClampToCredentialExpiry(expiry time.Time, credentials aws.Credentials) time.Time {
+	if credentials.CanExpire && !credentials.Expires.IsZero() && credentials.Expires.Before(expiry) {
+		return credentials.Expires
+	}
+	return expiry
+}
+
+// wrappedSignerV4 passes the expiration time of the credentials that were used to sign each request with the
+// target time.Time to clamp (ClampToCredentialExpiry if nil), replacing the target with the result.
+// If provenance is non-nil, it is populated with the details of the signing request.
+// If signingTime is non-zero, it replaces the signing time of the request.
 type wrappedSignerV4 struct {
-	target *time.Time
-	signer sts.HTTPPresignerV4
+	target      *time.Time
+	signer      sts.HTTPPresignerV4
+	clamp       func(expiry time.Time, credentials aws.Credentials) time.Time
+	provenance  *Provenance
+	signingTime time.Time
+	debug       *SigningDebug
 }
 
 // PresignHTTP implements the sts.HTTPPresignerV4 interface.
@@ -32,63 +62,429 @@ func (w *wrappedSignerV4) PresignHTTP(
 	payloadHash string, service string, region string, signingTime time.Time,
 	optFns ...func(*v4.SignerOptions),
 ) (signedURI string, signedHeaders http.Header, err error) {
-	if credentials.CanExpire && !credentials.Expires.IsZero() {
-		if credentials.Expires.Before(*w.target) {
-			*w.target = credentials.Expires
-		}
+	clamp := w.clamp
+	if clamp == nil {
+		clamp = ClampToCredentialExpiry
+	}
+	*w.target = clamp(*w.target, credentials)
+	if !w.signingTime.IsZero() {
+		signingTime = w.signingTime
+	}
+	if w.provenance != nil {
+		w.provenance.setCredentials(credentials.Source, credentials.AccessKeyID, credentials.Expires)
+		w.provenance.Region = region
+		w.provenance.Host = r.URL.Host
+		w.provenance.SigningTime = signingTime
+	}
+	if w.debug != nil {
+		optFns = append(optFns, withSigningDebug(w.debug))
 	}
 	return w.signer.PresignHTTP(ctx, credentials, r, payloadHash, service, region, signingTime, optFns...)
 }
 
+// PresignGetCallerIdentityAPIClient presigns sts:GetCallerIdentity requests, it is implemented by *sts.PresignClient.
+// Mocks, fakes and decorators (ie logging or latency injection) can be used in its place, they receive the presign
+// options of the TokenSource (cluster ID header, region, signer) and should forward them to a wrapped client.
+type PresignGetCallerIdentityAPIClient interface {
+	PresignGetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+var _ PresignGetCallerIdentityAPIClient = (*sts.PresignClient)(nil)
+
 // TokenSource is an oauth2.TokenSource that generates AWS EKS tokens from a sts.PresignClient.
+// It is safe for concurrent use but generates (presigns) a new token on every call, sharing only the credentials
+// cache of the client; the New* functions wrap it in a ReuseTokenSource which deduplicates concurrent refreshes.
+// The fields must not be modified after the first call to Token, the presign options derived from them are built once.
 // NOTE: Generally this should not be used directly, instead use the New* functions...
 type TokenSource struct {
+	// ClusterName is the name (or ARN) of the EKS cluster.
 	ClusterName string
-	Client      *sts.PresignClient
+	// Client presigns the requests, usually a *sts.PresignClient.
+	Client PresignGetCallerIdentityAPIClient
+	// Region overrides the region of Client if non-empty, it defaults to the region of a cluster ARN.
+	Region string
+	// ClusterID overrides the signed cluster ID (the cluster name by default), ie for aws-iam-authenticator clusters.
+	ClusterID string
+	// ClusterIDHeader overrides the name of the signed cluster ID header, "X-K8s-Aws-Id" by default.
+	ClusterIDHeader string
+	// Headers are additional headers signed into the presigned request, ie a tenant ID header required by a
+	// forked authenticator. The verifier must send the same headers when executing the presigned request.
+	Headers http.Header
+	// Query are additional query parameters signed into the presigned URL.
+	Query url.Values
+	// SSOProfile and SSOStartURL (optional) are reported by the *SSOSessionError returned when the SSO session
+	// of the credentials is expired.
+	SSOProfile  string
+	SSOStartURL string
+	// ClientOptions are applied to the sts.Options of Client when presigning.
+	ClientOptions []func(*sts.Options)
+	// Endpoint overrides the STS endpoint if non-empty, ie a private STS VPC endpoint (https://vpce-....sts.us-west-2.vpce.amazonaws.com).
+	Endpoint string
+	// Expiration overrides DefaultExpiration if non-zero.
+	Expiration time.Duration
+	// Format is the TokenFormat of generated tokens, V1Format if nil.
+	Format TokenFormat
+	// Context is the base context used by Token, context.Background() if nil.
+	Context context.Context
+	// TracerProvider creates a span for each generated token if non-nil.
+	TracerProvider trace.TracerProvider
+	// Logger logs generated tokens (debug) and errors (info) if non-nil.
+	Logger *slog.Logger
+	// Observers are notified of every generated token and error.
+	Observers []RefreshObserver
+	// Presigner replaces the SigV4 presigner of Client if non-nil, ie a signer using an HSM-backed key.
+	Presigner sts.HTTPPresignerV4
+	// ExpiryClamp adjusts the token expiry for the signing credentials, ClampToCredentialExpiry if nil.
+	// The expiry is always clamped to the validity of the signature (MaxExpiration after the X-Amz-Date).
+	ExpiryClamp func(expiry time.Time, credentials aws.Credentials) time.Time
+	// PresignExpires overrides DefaultPresignExpires if non-zero, it must be between 1 second and MaxExpiration.
+	PresignExpires time.Duration
+	// Now returns the current time used to compute the token expiry, time.Now if nil.
+	Now func() time.Time
+	// SigningTime fixes the SigV4 signing time (X-Amz-Date) if non-zero, producing identical tokens for identical
+	// credentials, ie for golden files. It is also the current time used to compute the expiry if Now is nil.
+	SigningTime time.Time
+	// Debug attaches the *SigningDebug (canonical request and string to sign) of every token, see TokenSigningDebug.
+	// A custom Presigner must honor v4.SignerOptions.LogSigning for it to be captured.
+	Debug bool
+
+	once          sync.Once
+	clientOptions []func(*sts.Options)
+	lastExpiry    atomic.Pointer[time.Time]
 }
 
-// Token implements the oauth2.TokenSource interface.
+// Token implements the oauth2.TokenSource interface using the base Context.
 func (ts *TokenSource) Token() (*oauth2.Token, error) {
-	expiry := time.Now().Add(DefaultExpiration)
+	return ts.TokenWithContext(baseContext(ts.Context))
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+func (ts *TokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	expiration := ts.Expiration
+	if expiration == 0 {
+		expiration = DefaultExpiration
+	}
+	presignExpires := ts.PresignExpires
+	if presignExpires == 0 {
+		presignExpires = DefaultPresignExpires
+	}
+	if presignExpires < time.Second || presignExpires > MaxExpiration {
+		return nil, &Error{Op: "PresignGetCallerIdentity", ClusterName: ts.ClusterName, Err: fmt.Errorf("presign expires %s must be between 1s and %s", presignExpires, MaxExpiration)}
+	}
+	// Custom cluster IDs (aws-iam-authenticator) are arbitrary, otherwise the name must be a valid EKS cluster name.
+	if ts.ClusterID == "" {
+		if err := ValidateClusterName(ts.ClusterName); err != nil {
+			return nil, &Error{Op: "PresignGetCallerIdentity", ClusterName: ts.ClusterName, Err: err}
+		}
+	}
+	clusterName, region := splitClusterName(ts.ClusterName)
+	if ts.Region != "" {
+		region = ts.Region
+	}
+	clusterID, header := clusterName, clusterIDHeader
+	if ts.ClusterID != "" {
+		clusterID = ts.ClusterID
+	}
+	if ts.ClusterIDHeader != "" {
+		header = ts.ClusterIDHeader
+	}
+	if clusterID == "" {
+		return nil, &Error{Op: "PresignGetCallerIdentity", ClusterName: ts.ClusterName, Err: ErrInvalidClusterName}
+	}
+	now := time.Now
+	if ts.Now != nil {
+		now = ts.Now
+	} else if !ts.SigningTime.IsZero() {
+		now = func() time.Time { return ts.SigningTime }
+	}
+	expiry := now().Add(expiration)
+	requested := expiry
+	provenance := &Provenance{ClusterName: clusterName}
+	var debug *SigningDebug
+	if ts.Debug {
+		debug = &SigningDebug{}
+	}
+	ctx, span := startSpan(ctx, ts.TracerProvider, clusterName)
 	req, err := ts.Client.PresignGetCallerIdentity(
-		context.TODO(),
+		ctx,
 		&sts.GetCallerIdentityInput{},
 		func(opts *sts.PresignOptions) {
-			opts.ClientOptions = []func(*sts.Options){
-				sts.WithAPIOptions(
-					smithyhttp.AddHeaderValue("X-K8s-Aws-Id", ts.ClusterName),
-					smithyhttp.AddHeaderValue("X-Amz-Expires", "60"),
-				),
+			opts.ClientOptions = append(opts.ClientOptions, ts.presignClientOptions(clusterID, header, region, presignExpires)...)
+			signer := opts.Presigner
+			if ts.Presigner != nil {
+				signer = ts.Presigner
 			}
 			opts.Presigner = &wrappedSignerV4{
-				target: &expiry,
-				signer: opts.Presigner,
+				target:      &expiry,
+				signer:      signer,
+				clamp:       ts.ExpiryClamp,
+				provenance:  provenance,
+				signingTime: ts.SigningTime,
+				debug:       debug,
 			}
 		},
 	)
 	if err != nil {
-		return nil, err
+		return nil, ts.fail(ctx, span, provenance, &Error{Op: "PresignGetCallerIdentity", ClusterName: clusterName, Err: wrapThrottled("PresignGetCallerIdentity", err)})
+	}
+	if ts.Endpoint != "" {
+		if err := checkEndpointHost(ts.Endpoint, req.URL); err != nil {
+			return nil, ts.fail(ctx, span, provenance, &Error{Op: "PresignGetCallerIdentity", ClusterName: clusterName, Err: err})
+		}
 	}
-	return &oauth2.Token{
-		AccessToken: "k8s-aws-v1." + base64.RawURLEncoding.EncodeToString([]byte(req.URL)),
+	endSpan(span, provenance, nil)
+	// The signature is valid for MaxExpiration after the X-Amz-Date of the URL, which may differ from our clock.
+	// The default presigner uses the signing time it is given, a custom one must be parsed from the URL.
+	if ts.Presigner == nil && !provenance.SigningTime.IsZero() {
+		if urlExpiry := provenance.SigningTime.Truncate(time.Second).Add(MaxExpiration); urlExpiry.Before(expiry) {
+			expiry = urlExpiry
+		}
+	} else if parsed, err := parsePresignedURL(req.URL); err == nil {
+		if urlExpiry := parsed.Expiry(); urlExpiry.Before(expiry) {
+			expiry = urlExpiry
+		}
+	}
+	format := ts.Format
+	if format == nil {
+		format = V1Format
+	}
+	provenance.Expiry = expiry
+	presigned := newPresignedRequest(req.URL, req.Method, req.SignedHeader, expiry)
+	var accessToken string
+	if enc, ok := format.(Encoder); ok {
+		if accessToken, err = enc.EncodeRequest(presigned); err != nil {
+			return nil, ts.fail(ctx, span, provenance, &Error{Op: "EncodeToken", ClusterName: clusterName, Err: err})
+		}
+	} else {
+		accessToken = format.Encode(req.URL)
+	}
+	logToken(ctx, ts.Logger, provenance, requested)
+	token := &oauth2.Token{
+		AccessToken: accessToken,
 		Expiry:      expiry,
-	}, nil
+	}
+	extra := map[string]interface{}{
+		ProvenanceExtraKey:       provenance,
+		PresignedRequestExtraKey: presigned,
+	}
+	if debug != nil {
+		debug.URL = req.URL
+		extra[SigningDebugExtraKey] = debug
+	}
+	token = token.WithExtra(extra)
+	ts.lastExpiry.Store(&expiry)
+	for _, o := range ts.Observers {
+		o.OnRefresh(token, expiry)
+	}
+	return token, nil
 }
 
-// NewFromPresignClient creates a new oauth2.TokenSource from a sts.PresignClient and an EKS cluster name
-func NewFromPresignClient(client *sts.PresignClient, clusterName string) oauth2.TokenSource {
-	return oauth2.ReuseTokenSourceWithExpiry(nil, &TokenSource{
-		ClusterName: clusterName,
-		Client:      client,
-	}, DefaultEarlyExpiry)
+// presignClientOptions returns the sts.Options functions applied to every presign, they only depend on the
+// fields of the TokenSource and are built on the first call.
+func (ts *TokenSource) presignClientOptions(clusterID, header, region string, presignExpires time.Duration) []func(*sts.Options) {
+	ts.once.Do(func() {
+		optFns := append([]func(*sts.Options){}, ts.ClientOptions...)
+		optFns = append(optFns, func(o *sts.Options) {
+			if o.Credentials != nil {
+				o.Credentials = credentialErrorProvider{o.Credentials, ts.SSOProfile, ts.SSOStartURL}
+			}
+		})
+		apiOptions := []func(*middleware.Stack) error{
+			smithyhttp.AddHeaderValue(header, clusterID),
+			smithyhttp.AddHeaderValue("X-Amz-Expires", strconv.Itoa(int(presignExpires/time.Second))),
+		}
+		for name, values := range ts.Headers {
+			for _, value := range values {
+				apiOptions = append(apiOptions, smithyhttp.AddHeaderValue(name, value))
+			}
+		}
+		if len(ts.Query) > 0 {
+			apiOptions = append(apiOptions, addQuery(ts.Query))
+		}
+		optFns = append(optFns, sts.WithAPIOptions(apiOptions...))
+		if region != "" {
+			optFns = append(optFns, func(o *sts.Options) {
+				o.Region = region
+			})
+		}
+		if ts.Endpoint != "" {
+			endpoint := aws.String(ts.Endpoint)
+			optFns = append(optFns, func(o *sts.Options) {
+				o.BaseEndpoint = endpoint
+			})
+		}
+		ts.clientOptions = optFns
+	})
+	return ts.clientOptions
+}
+
+// addQuery returns a middleware adding query parameters to the request before it is presigned.
+func addQuery(query url.Values) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Build.Add(middleware.BuildMiddlewareFunc("eksauthAddQuery", func(
+			ctx context.Context, in middleware.BuildInput, next middleware.BuildHandler,
+		) (middleware.BuildOutput, middleware.Metadata, error) {
+			if req, ok := in.Request.(*smithyhttp.Request); ok {
+				q := req.URL.Query()
+				for name, values := range query {
+					for _, value := range values {
+						q.Add(name, value)
+					}
+				}
+				req.URL.RawQuery = q.Encode()
+			}
+			return next.HandleBuild(ctx, in)
+		}), middleware.After)
+	}
+}
+
+// fail records a token generation error in the span, logger and observers then returns it.
+func (ts *TokenSource) fail(ctx context.Context, span trace.Span, provenance *Provenance, err error) error {
+	endSpan(span, provenance, err)
+	logError(ctx, ts.Logger, provenance.ClusterName, err)
+	for _, o := range ts.Observers {
+		o.OnError(err)
+	}
+	return err
+}
+
+// checkEndpointHost verifies the presigned URL targets the host of the custom endpoint.
+func checkEndpointHost(endpoint string, presignedURL string) error {
+	want, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid STS endpoint %q: %w", endpoint, err)
+	}
+	got, err := url.Parse(presignedURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse presigned URL: %w", err)
+	}
+	if !strings.EqualFold(got.Host, want.Host) {
+		return fmt.Errorf("presigned URL host %q does not match the STS endpoint host %q", got.Host, want.Host)
+	}
+	return nil
+}
+
+// newFromPresignClient creates the token source for the New* constructors.
+func newFromPresignClient(client PresignGetCallerIdentityAPIClient, clusterName string, env Env, o *options) oauth2.TokenSource {
+	if clusterName == "" {
+		clusterName = env.ClusterName
+	}
+	if o.preflightIdentity {
+		if err := preflightIdentityCheck(clusterName, o); err != nil {
+			return &failedTokenSource{err}
+		}
+	}
+	var ts oauth2.TokenSource = &TokenSource{
+		ClusterName:     clusterName,
+		Client:          client,
+		Expiration:      o.expirationOrDefault(),
+		Format:          o.format,
+		Context:         o.ctx,
+		TracerProvider:  o.tracerProvider,
+		Logger:          o.logger,
+		Observers:       o.observers,
+		PresignExpires:  o.presignExpires,
+		Now:             o.now,
+		SigningTime:     o.signingTime,
+		Headers:         o.headers,
+		SSOProfile:      o.ssoProfile,
+		SSOStartURL:     o.ssoStartURL,
+		Query:           o.query,
+		ClusterID:       o.clusterID,
+		Region:          o.signingRegion,
+		ClusterIDHeader: o.clusterIDHeader,
+		ClientOptions:   o.clientOptions,
+		Endpoint:        o.endpoint,
+		Presigner:       o.presigner,
+		ExpiryClamp:     o.expiryClamp,
+		Debug:           o.signingDebug,
+	}
+	if o.callerIdentity && o.stsClient != nil {
+		ts = NewIdentityTokenSource(ts, o.stsClient)
+	}
+	if o.auditSink != nil {
+		ts = &AuditTokenSource{Source: ts, Sink: o.auditSink, ClusterName: clusterName}
+	}
+	if o.rateLimiter != nil {
+		ts = &RateLimitedTokenSource{Source: ts, Limiter: o.rateLimiter, ClusterName: clusterName}
+	}
+	ts = o.wrap(clusterName, ts)
+	if env.CacheMode != CacheModeNone {
+		reuse := NewJitteredReuseTokenSource(nil, ts, o.earlyExpiryOrDefault(), o.earlyExpiryJitter)
+		if o.tokenState != nil {
+			_ = reuse.ImportState(o.tokenState)
+		}
+		if o.clockSkew != nil {
+			reuse.SetClockSkew(o.clockSkew)
+		}
+		reuse.SetContext(o.ctx)
+		ts = reuse
+	} else if _, ok := ts.(*TokenSource); !ok && o.ctx != nil {
+		// The wrappers added above use context.Background() in Token.
+		ts = &baseContextTokenSource{Source: ts, ctx: o.ctx}
+	}
+	if o.refreshOnRotation && o.credentials != nil {
+		ts = &RotationTokenSource{Source: ts, Credentials: o.credentials, Context: o.ctx}
+	}
+	if o.minValidity > 0 {
+		ts = &MinValidityTokenSource{Source: ts, MinValidity: o.minValidity, Now: o.now, Context: o.ctx}
+	}
+	return ts
+}
+
+// NewFromPresignClient creates a new oauth2.TokenSource from a sts.PresignClient (or a PresignGetCallerIdentityAPIClient) and an EKS cluster name
+// The concrete type of the returned oauth2.TokenSource is *ReuseTokenSource unless EKSAUTH_CACHE_MODE is "none"
+// or WithMinValidity or WithRefreshOnCredentialRotation is used.
+func NewFromPresignClient(client PresignGetCallerIdentityAPIClient, clusterName string, opts ...Option) oauth2.TokenSource {
+	env := loadEnv()
+	return newFromPresignClient(client, clusterName, env, newOptions(env, opts))
+}
+
+// NewFromPresignClientWithContext is NewFromPresignClient where ctx is the base context used by Token.
+// The returned oauth2.TokenSource implements ContextTokenSource for per-call contexts.
+func NewFromPresignClientWithContext(ctx context.Context, client PresignGetCallerIdentityAPIClient, clusterName string, opts ...Option) oauth2.TokenSource {
+	return NewFromPresignClient(client, clusterName, append([]Option{WithContext(ctx)}, opts...)...)
 }
 
 // NewFromClient creates a new oauth2.TokenSource from a sts.Client and an EKS cluster name
-func NewFromClient(client *sts.Client, clusterName string, optFns ...func(*sts.PresignOptions)) oauth2.TokenSource {
-	return NewFromPresignClient(sts.NewPresignClient(client, optFns...), clusterName)
+func NewFromClient(client *sts.Client, clusterName string, opts ...Option) oauth2.TokenSource {
+	env := loadEnv()
+	o := newOptions(env, opts)
+	o.stsClient = client
+	return newFromPresignClient(sts.NewPresignClient(client, o.presignOptions...), clusterName, env, o)
+}
+
+// NewFromClientWithContext is NewFromClient where ctx is the base context used by Token.
+func NewFromClientWithContext(ctx context.Context, client *sts.Client, clusterName string, opts ...Option) oauth2.TokenSource {
+	return NewFromClient(client, clusterName, append([]Option{WithContext(ctx)}, opts...)...)
 }
 
 // NewFromConfig creates a new oauth2.TokenSource from an aws.Config and an EKS cluster name
-func NewFromConfig(cfg aws.Config, clusterName string, optFns ...func(*sts.Options)) oauth2.TokenSource {
-	return NewFromClient(sts.NewFromConfig(cfg, optFns...), clusterName)
+func NewFromConfig(cfg aws.Config, clusterName string, opts ...Option) oauth2.TokenSource {
+	env := loadEnv()
+	o := newOptions(env, opts)
+	return newFromPresignClient(presignClientFromConfig(cfg, env, o), clusterName, env, o)
+}
+
+// presignClientFromConfig creates the sts.PresignClient for NewFromConfig, applying the EKSAUTH_* role and the
+// EKSAUTH_* region if cfg has none.
+func presignClientFromConfig(cfg aws.Config, env Env, o *options) *sts.PresignClient {
+	o.applyConfig(&cfg)
+	cfg.Credentials = ssoLoginCredentials(cfg, o.ssoLoginPrompt)
+	if cfg.Region == "" {
+		cfg.Region = env.Region
+	}
+	if env.RoleARN != "" {
+		cfg.Credentials = AssumeRoleChainCredentials(cfg, parseRoleChain(env.RoleARN, env.RoleDuration))
+	}
+	o.credentials = cfg.Credentials
+	o.ssoProfile, o.ssoStartURL = ssoSession(cfg)
+	client := sts.NewFromConfig(cfg, append([]func(*sts.Options){addUserAgent}, o.stsOptions...)...)
+	o.stsClient = client
+	return sts.NewPresignClient(client, o.presignOptions...)
+}
+
+// NewFromConfigWithContext is NewFromConfig where ctx is the base context used by Token.
+func NewFromConfigWithContext(ctx context.Context, cfg aws.Config, clusterName string, opts ...Option) oauth2.TokenSource {
+	return NewFromConfig(cfg, clusterName, append([]Option{WithContext(ctx)}, opts...)...)
 }
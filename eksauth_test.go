@@ -0,0 +1,52 @@
+package eksauth_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"github.com/bored-engineer/aws-eks-auth/eksauthtest"
+)
+
+// newTokenSource returns an uncached TokenSource with extra signed headers and query parameters, whose presign
+// options are built on the first call.
+func newTokenSource(client *sts.PresignClient) *eksauth.TokenSource {
+	return &eksauth.TokenSource{
+		ClusterName: "eks-cluster-name",
+		Client:      client,
+		Headers:     http.Header{"X-Tenant-Id": {"tenant"}},
+		Query:       url.Values{"X-Tenant": {"tenant"}},
+	}
+}
+
+// newBenchTokenSource returns a newTokenSource signing with the static credentials of a fake STS.
+func newBenchTokenSource(tb testing.TB) *eksauth.TokenSource {
+	s := eksauthtest.NewSTS()
+	tb.Cleanup(s.Close)
+	return newTokenSource(sts.NewPresignClient(sts.NewFromConfig(s.Config())))
+}
+
+func BenchmarkToken(b *testing.B) {
+	ts := newBenchTokenSource(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ts.Token(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTokenParallel(b *testing.B) {
+	ts := newBenchTokenSource(b)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := ts.Token(); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
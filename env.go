@@ -0,0 +1,132 @@
+package eksauth
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"golang.org/x/oauth2"
+)
+
+// Environment variables honored by the constructors in this package (and the CLI).
+// Precedence is always: explicit arguments/options (including the aws.Config), then EKSAUTH_* environment variables,
+// then package defaults.
+const (
+	// EnvClusterName is used as the cluster name when an empty cluster name is provided.
+	EnvClusterName = "EKSAUTH_CLUSTER_NAME"
+	// EnvRegion is the region used to sign tokens when neither the aws.Config nor the cluster (ARN) has one.
+	EnvRegion = "EKSAUTH_REGION"
+	// EnvRoleARN is an IAM role that is assumed with the credentials of the aws.Config before signing tokens, unless a
+	// role is given explicitly (ie NewFromRole or ClusterConfig.RoleARN).
+	// It may be a comma separated list of role ARNs which are assumed in order (role chaining).
+	EnvRoleARN = "EKSAUTH_ROLE_ARN"
+	// EnvRoleDuration is the duration of the session assumed for EnvRoleARN, ie "1h".
+	EnvRoleDuration = "EKSAUTH_ROLE_DURATION"
+	// EnvExpiration replaces DefaultExpiration unless WithExpiration is used, ie "10m".
+	EnvExpiration = "EKSAUTH_EXPIRATION"
+	// EnvCacheMode selects how tokens are cached, see the CacheMode* constants.
+	EnvCacheMode = "EKSAUTH_CACHE_MODE"
+)
+
+// Environment variables honored by NewFromEnv, they take precedence over their EKSAUTH_* equivalent.
+const (
+	// EnvEKSClusterName is the EKS cluster name.
+	EnvEKSClusterName = "EKS_CLUSTER_NAME"
+	// EnvEKSClusterARN is the EKS cluster ARN, its region is used to sign tokens. It takes precedence over EKS_CLUSTER_NAME.
+	EnvEKSClusterARN = "EKS_CLUSTER_ARN"
+	// EnvEKSRoleARN is an IAM role that is assumed before signing tokens.
+	EnvEKSRoleARN = "EKS_ROLE_ARN"
+)
+
+// Supported values for EKSAUTH_CACHE_MODE.
+const (
+	// CacheModeMemory reuses tokens in memory until they (early) expire, this is the default.
+	CacheModeMemory = "memory"
+	// CacheModeNone generates a new token on every call.
+	CacheModeNone = "none"
+)
+
+// Env is the parsed set of EKSAUTH_* environment variables, empty fields were not set.
+type Env struct {
+	ClusterName  string
+	Region       string
+	RoleARN      string
+	RoleDuration time.Duration
+	Expiration   time.Duration
+	CacheMode    string
+}
+
+// LoadEnv parses the EKSAUTH_* environment variables. Invalid variables are reported (joined) in the error and
+// left empty in the returned Env, the other fields are always set.
+func LoadEnv() (Env, error) {
+	env := Env{
+		ClusterName: os.Getenv(EnvClusterName),
+		Region:      os.Getenv(EnvRegion),
+		RoleARN:     os.Getenv(EnvRoleARN),
+		CacheMode:   os.Getenv(EnvCacheMode),
+	}
+	var errs []error
+	if s := os.Getenv(EnvExpiration); s != "" {
+		if d, err := time.ParseDuration(s); err != nil {
+			errs = append(errs, fmt.Errorf("eksauth: invalid %s: %w", EnvExpiration, err))
+		} else {
+			env.Expiration = d
+		}
+	}
+	if s := os.Getenv(EnvRoleDuration); s != "" {
+		if d, err := time.ParseDuration(s); err != nil {
+			errs = append(errs, fmt.Errorf("eksauth: invalid %s: %w", EnvRoleDuration, err))
+		} else {
+			env.RoleDuration = d
+		}
+	}
+	switch env.CacheMode {
+	case "", CacheModeMemory, CacheModeNone:
+	default:
+		errs = append(errs, fmt.Errorf("eksauth: invalid %s: %q", EnvCacheMode, env.CacheMode))
+		env.CacheMode = ""
+	}
+	return env, errors.Join(errs...)
+}
+
+// loadEnv is LoadEnv for constructors that cannot return an error, invalid values are ignored.
+func loadEnv() Env {
+	env, _ := LoadEnv()
+	return env
+}
+
+// NewFromEnv creates a new oauth2.TokenSource configured entirely from the environment: the default aws.Config is
+// loaded (honoring the standard AWS_* variables) and the cluster is EKS_CLUSTER_ARN, EKS_CLUSTER_NAME or
+// EKSAUTH_CLUSTER_NAME. If EKS_ROLE_ARN is set the role is assumed before signing tokens, see NewFromRole.
+// The ctx is only used to load the config.
+func NewFromEnv(ctx context.Context, opts ...Option) (oauth2.TokenSource, error) {
+	clusterName := os.Getenv(EnvEKSClusterARN)
+	if clusterName == "" {
+		clusterName = os.Getenv(EnvEKSClusterName)
+	}
+	if clusterName == "" && os.Getenv(EnvClusterName) == "" {
+		return nil, fmt.Errorf("%w: none of %s, %s or %s is set", ErrInvalidClusterName, EnvEKSClusterARN, EnvEKSClusterName, EnvClusterName)
+	}
+	if err := ValidateClusterName(cmp.Or(clusterName, os.Getenv(EnvClusterName))); err != nil {
+		return nil, err
+	}
+	if _, err := LoadEnv(); err != nil {
+		return nil, err
+	}
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if roleARN := os.Getenv(EnvEKSRoleARN); roleARN != "" {
+		if !arn.IsARN(roleARN) {
+			return nil, errors.New("eksauth: invalid " + EnvEKSRoleARN + ": " + roleARN)
+		}
+		return constructorError(NewFromRole(cfg, roleARN, clusterName, opts...))
+	}
+	return constructorError(NewFromConfig(cfg, clusterName, opts...))
+}
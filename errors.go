@@ -0,0 +1,140 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+)
+
+// Sentinel errors classifying token generation failures, use errors.Is to test for them:
+// ErrCredentialRetrieval and ErrCredentialsExpired usually require re-authentication (ie aws sso login),
+// ErrInvalidClusterName is a misconfiguration and ErrPresignFailed is any other failure to generate a token.
+var (
+	ErrCredentialRetrieval = errors.New("eksauth: failed to retrieve AWS credentials")
+	ErrCredentialsExpired  = errors.New("eksauth: AWS credentials are expired")
+	ErrPresignFailed       = errors.New("eksauth: failed to presign sts:GetCallerIdentity")
+	ErrInvalidClusterName  = errors.New("eksauth: invalid cluster name")
+)
+
+// Error is the error returned by the token sources in this package, it wraps the underlying (SDK) error.
+type Error struct {
+	// Op is the operation that failed, ie "PresignGetCallerIdentity".
+	Op string
+	// ClusterName is the cluster the token was being generated for, if known.
+	ClusterName string
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	msg := "eksauth: " + e.Op
+	if e.ClusterName != "" {
+		msg += " (" + e.ClusterName + ")"
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports if the error is ErrPresignFailed, which is every token generation failure that is not a
+// credential retrieval failure or an invalid cluster name.
+func (e *Error) Is(target error) bool {
+	return target == ErrPresignFailed && e.Op == "PresignGetCallerIdentity" &&
+		!errors.Is(e.Err, ErrCredentialRetrieval) && !errors.Is(e.Err, ErrInvalidClusterName)
+}
+
+// Retryable reports if the operation may succeed if retried (ie throttling or a transient network error).
+// It uses the same classification as the AWS SDK retryer (retry.DefaultRetryables).
+func (e *Error) Retryable() bool {
+	return isRetryable(e.Err)
+}
+
+// Temporary is an alias for Retryable, matching the interface used by net.Error.
+func (e *Error) Temporary() bool {
+	return e.Retryable()
+}
+
+// isRetryable classifies an error using retry.DefaultRetryables.
+func isRetryable(err error) bool {
+	for _, retryable := range retry.DefaultRetryables {
+		if v := retryable.IsErrorRetryable(err); v != aws.UnknownTernary {
+			return v.Bool()
+		}
+	}
+	return false
+}
+
+// IsRetryable reports if err (or any error it wraps) is retryable.
+// Errors implementing Retryable() bool (like *Error) are asked directly, otherwise the AWS SDK classification is used.
+func IsRetryable(err error) bool {
+	var r interface{ Retryable() bool }
+	if errors.As(err, &r) {
+		return r.Retryable()
+	}
+	return isRetryable(err)
+}
+
+// expiredErrorCodes are the API error codes returned for expired credentials.
+var expiredErrorCodes = map[string]bool{
+	"ExpiredToken":          true,
+	"ExpiredTokenException": true,
+	"RequestExpired":        true,
+}
+
+// CredentialError wraps a failure to retrieve AWS credentials, it matches ErrCredentialRetrieval
+// (and ErrCredentialsExpired if the credentials are expired) with errors.Is.
+type CredentialError struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e *CredentialError) Error() string {
+	return "failed to retrieve AWS credentials: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *CredentialError) Unwrap() error {
+	return e.Err
+}
+
+// Is implements matching ErrCredentialRetrieval and ErrCredentialsExpired.
+func (e *CredentialError) Is(target error) bool {
+	switch target {
+	case ErrCredentialRetrieval:
+		return true
+	case ErrCredentialsExpired:
+		var apiErr smithy.APIError
+		return errors.As(e.Err, &apiErr) && expiredErrorCodes[apiErr.ErrorCode()]
+	}
+	return false
+}
+
+// credentialErrorProvider wraps the errors of an aws.CredentialsProvider in a *CredentialError,
+// SSO session failures are wrapped in a *SSOSessionError reporting the profile and start URL.
+type credentialErrorProvider struct {
+	aws.CredentialsProvider
+	ssoProfile  string
+	ssoStartURL string
+}
+
+// Retrieve implements the aws.CredentialsProvider interface.
+func (p credentialErrorProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	creds, err := p.CredentialsProvider.Retrieve(ctx)
+	if err != nil {
+		if isSSOSessionError(err) {
+			err = &SSOSessionError{Profile: p.ssoProfile, StartURL: p.ssoStartURL, Err: err}
+		}
+		return creds, &CredentialError{Err: err}
+	}
+	if creds.Expired() {
+		return creds, &CredentialError{Err: ErrCredentialsExpired}
+	}
+	return creds, nil
+}
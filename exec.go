@@ -0,0 +1,122 @@
+package eksauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// DefaultExecTimeout is the default timeout of the command run by an ExecTokenSource.
+var DefaultExecTimeout = 30 * time.Second
+
+// ExecTokenSource is an oauth2.TokenSource that runs an external command which prints either a raw token
+// (in any registered TokenFormat) or an ExecCredential JSON object to stdout.
+// It is typically wrapped with NewReuseTokenSource so the command is not run on every call.
+type ExecTokenSource struct {
+	// Command is the path (or name in $PATH) of the command.
+	Command string
+	// Args are the arguments passed to the command.
+	Args []string
+	// Env are additional "KEY=VALUE" environment variables for the command.
+	Env []string
+	// IsolateEnv prevents the command from inheriting the environment of the current process.
+	IsolateEnv bool
+	// Dir is the working directory of the command, the current directory if empty.
+	Dir string
+	// Timeout overrides DefaultExecTimeout if non-zero.
+	Timeout time.Duration
+}
+
+// NewExecTokenSource creates a new oauth2.TokenSource that runs command, reusing tokens until they expire.
+func NewExecTokenSource(command string, args ...string) oauth2.TokenSource {
+	return NewReuseTokenSource(nil, &ExecTokenSource{
+		Command: command,
+		Args:    args,
+	}, DefaultEarlyExpiry)
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (ts *ExecTokenSource) Token() (*oauth2.Token, error) {
+	return ts.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the ContextTokenSource interface, cancelling ctx kills the command.
+func (ts *ExecTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	timeout := ts.Timeout
+	if timeout == 0 {
+		timeout = DefaultExecTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, ts.Command, ts.Args...)
+	cmd.Dir = ts.Dir
+	if ts.IsolateEnv {
+		cmd.Env = append([]string{}, ts.Env...)
+	} else {
+		cmd.Env = append(os.Environ(), ts.Env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("timed out after %s: %w", timeout, ctx.Err())
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, &Error{Op: "exec " + ts.Command, Err: err}
+	}
+	token, err := parseExecOutput(stdout.Bytes())
+	if err != nil {
+		return nil, &Error{Op: "exec " + ts.Command, Err: err}
+	}
+	return token, nil
+}
+
+// parseExecOutput parses the output of an ExecTokenSource command.
+func parseExecOutput(output []byte) (*oauth2.Token, error) {
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 {
+		return nil, errors.New("command produced no output")
+	}
+	var token oauth2.Token
+	if output[0] == '{' {
+		var cred ExecCredential
+		if err := json.Unmarshal(output, &cred); err != nil {
+			return nil, fmt.Errorf("invalid ExecCredential: %w", err)
+		}
+		if cred.Kind != "ExecCredential" || cred.Status == nil || cred.Status.Token == "" {
+			return nil, errors.New("invalid ExecCredential: missing kind or status.token")
+		}
+		token.AccessToken = cred.Status.Token
+		if cred.Status.ExpirationTimestamp != nil {
+			token.Expiry = *cred.Status.ExpirationTimestamp
+		}
+	} else {
+		token.AccessToken = string(output)
+	}
+	if strings.ContainsAny(token.AccessToken, " \t\r\n") {
+		return nil, errors.New("token contains whitespace")
+	}
+	if _, ok := LookupTokenFormat(token.AccessToken); !ok {
+		return nil, ErrUnknownTokenFormat
+	}
+	if token.Expiry.IsZero() {
+		expiry, err := TokenExpiry(token.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		token.Expiry = expiry
+	}
+	return &token, nil
+}
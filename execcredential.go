@@ -0,0 +1,99 @@
+package eksauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// API versions of the client.authentication.k8s.io ExecCredential.
+const (
+	ExecCredentialV1      = "client.authentication.k8s.io/v1"
+	ExecCredentialV1beta1 = "client.authentication.k8s.io/v1beta1"
+)
+
+// ExecCredential is a client.authentication.k8s.io ExecCredential (v1 or v1beta1), as printed by kubectl exec plugins.
+type ExecCredential struct {
+	Kind       string                `json:"kind"`
+	APIVersion string                `json:"apiVersion"`
+	Spec       ExecCredentialSpec    `json:"spec"`
+	Status     *ExecCredentialStatus `json:"status,omitempty"`
+}
+
+// ExecCredentialSpec is the spec of an ExecCredential, provided to plugins via KUBERNETES_EXEC_INFO.
+type ExecCredentialSpec struct {
+	Interactive bool            `json:"interactive,omitempty"`
+	Cluster     json.RawMessage `json:"cluster,omitempty"`
+}
+
+// ExecCredentialStatus is the status of an ExecCredential containing the token.
+type ExecCredentialStatus struct {
+	ExpirationTimestamp *time.Time `json:"expirationTimestamp,omitempty"`
+	Token               string     `json:"token,omitempty"`
+}
+
+// NewExecCredential creates an ExecCredential of the apiVersion (ExecCredentialV1 if empty) for a token.
+func NewExecCredential(token *oauth2.Token, apiVersion string) (*ExecCredential, error) {
+	switch apiVersion {
+	case "":
+		apiVersion = ExecCredentialV1
+	case ExecCredentialV1, ExecCredentialV1beta1:
+	default:
+		return nil, fmt.Errorf("eksauth: unsupported ExecCredential apiVersion %q", apiVersion)
+	}
+	status := &ExecCredentialStatus{Token: token.AccessToken}
+	if !token.Expiry.IsZero() {
+		// metav1.Time is serialized as RFC3339 in UTC with second precision
+		expiry := token.Expiry.UTC().Truncate(time.Second)
+		status.ExpirationTimestamp = &expiry
+	}
+	return &ExecCredential{
+		Kind:       "ExecCredential",
+		APIVersion: apiVersion,
+		Status:     status,
+	}, nil
+}
+
+// ExecCredentialJSON generates a token from ts and returns it as a JSON encoded ExecCredential of the apiVersion.
+func ExecCredentialJSON(ctx context.Context, ts oauth2.TokenSource, apiVersion string) ([]byte, error) {
+	token, err := TokenWithContext(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+	cred, err := NewExecCredential(token, apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(cred)
+}
+
+// EnvExecInfo is the environment variable kubectl (and client-go) use to pass an ExecCredential to exec plugins.
+const EnvExecInfo = "KUBERNETES_EXEC_INFO"
+
+// ParseExecInfo parses the value of KUBERNETES_EXEC_INFO, the ExecCredential (without status) of the request.
+func ParseExecInfo(value string) (*ExecCredential, error) {
+	var cred ExecCredential
+	if err := json.Unmarshal([]byte(value), &cred); err != nil {
+		return nil, fmt.Errorf("eksauth: invalid %s: %w", EnvExecInfo, err)
+	}
+	switch cred.APIVersion {
+	case ExecCredentialV1, ExecCredentialV1beta1:
+	default:
+		return nil, fmt.Errorf("eksauth: unsupported %s apiVersion %q", EnvExecInfo, cred.APIVersion)
+	}
+	return &cred, nil
+}
+
+// LoadExecInfo parses KUBERNETES_EXEC_INFO (see ParseExecInfo), it returns nil if the variable is not set,
+// ie when the plugin is not executed by kubectl.
+func LoadExecInfo() (*ExecCredential, error) {
+	value, ok := os.LookupEnv(EnvExecInfo)
+	if !ok || value == "" {
+		return nil, nil
+	}
+	return ParseExecInfo(value)
+}
@@ -0,0 +1,34 @@
+package eksauth
+
+import "time"
+
+// amzDateFormat is the format of the X-Amz-Date query parameter.
+const amzDateFormat = "20060102T150405Z"
+
+// tokenSigningTime decodes a token and returns the X-Amz-Date it was signed at.
+func tokenSigningTime(token string) (time.Time, error) {
+	parsed, err := ParseToken(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parsed.SigningTime, nil
+}
+
+// TokenExpiry returns when a raw token string expires, computed from its signing time.
+// Like aws-iam-authenticator, tokens are considered valid for MaxExpiration after the X-Amz-Date.
+func TokenExpiry(token string) (time.Time, error) {
+	signingTime, err := tokenSigningTime(token)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return signingTime.Add(MaxExpiration), nil
+}
+
+// IsExpired reports if a raw token string is expired (or will be within skew) based on its signing time.
+func IsExpired(token string, skew time.Duration) (bool, error) {
+	expiry, err := TokenExpiry(token)
+	if err != nil {
+		return false, err
+	}
+	return !time.Now().Add(skew).Before(expiry), nil
+}
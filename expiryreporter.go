@@ -0,0 +1,83 @@
+package eksauth
+
+import (
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ExpiryReporter is implemented by token sources reporting the expiry of their current token (the last generated or
+// cached one) without decoding it, ie for health endpoints and dashboards. The token sources returned by the New*
+// constructors implement it.
+type ExpiryReporter interface {
+	// ExpiresAt returns the expiry of the current token, the zero time.Time if there is none.
+	ExpiresAt() time.Time
+	// Remaining returns how long the current token is still valid, zero if there is none or it expired.
+	Remaining() time.Duration
+}
+
+var (
+	_ ExpiryReporter = (*TokenSource)(nil)
+	_ ExpiryReporter = (*ReuseTokenSource)(nil)
+	_ ExpiryReporter = (*MinValidityTokenSource)(nil)
+	_ ExpiryReporter = (*RotationTokenSource)(nil)
+)
+
+// remaining returns the duration until expiry, zero if expiry is zero or in the past.
+func remaining(expiry time.Time) time.Duration {
+	if expiry.IsZero() {
+		return 0
+	}
+	return max(time.Until(expiry), 0)
+}
+
+// expiresAt returns the ExpiresAt of ts if it implements ExpiryReporter.
+func expiresAt(ts oauth2.TokenSource) time.Time {
+	if r, ok := ts.(ExpiryReporter); ok {
+		return r.ExpiresAt()
+	}
+	return time.Time{}
+}
+
+// ExpiresAt implements the ExpiryReporter interface, it is the expiry of the last generated token.
+func (ts *TokenSource) ExpiresAt() time.Time {
+	if expiry := ts.lastExpiry.Load(); expiry != nil {
+		return *expiry
+	}
+	return time.Time{}
+}
+
+// Remaining implements the ExpiryReporter interface.
+func (ts *TokenSource) Remaining() time.Duration {
+	return remaining(ts.ExpiresAt())
+}
+
+// ExpiresAt implements the ExpiryReporter interface, it is the expiry of the cached token.
+func (s *ReuseTokenSource) ExpiresAt() time.Time {
+	return s.Expiry()
+}
+
+// Remaining implements the ExpiryReporter interface.
+func (s *ReuseTokenSource) Remaining() time.Duration {
+	return remaining(s.Expiry())
+}
+
+// ExpiresAt implements the ExpiryReporter interface using Source.
+func (s *MinValidityTokenSource) ExpiresAt() time.Time {
+	return expiresAt(s.Source)
+}
+
+// Remaining implements the ExpiryReporter interface.
+func (s *MinValidityTokenSource) Remaining() time.Duration {
+	return remaining(s.ExpiresAt())
+}
+
+// ExpiresAt implements the ExpiryReporter interface using Source.
+func (s *RotationTokenSource) ExpiresAt() time.Time {
+	return expiresAt(s.Source)
+}
+
+// Remaining implements the ExpiryReporter interface.
+func (s *RotationTokenSource) Remaining() time.Duration {
+	return remaining(s.ExpiresAt())
+}
@@ -0,0 +1,95 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrInjectedCredentialLoss is the error returned by SimulateCredentialLoss.
+var ErrInjectedCredentialLoss = errors.New("eksauth: injected fault: credentials are unavailable")
+
+// Fault describes a failure injected into a token pipeline by a FaultInjector, the zero value injects nothing.
+type Fault struct {
+	// Delay is slept before the token is retrieved, simulating slow refreshes.
+	Delay time.Duration
+	// Err is returned instead of a token, simulating failures like credential loss.
+	Err error
+	// Expired returns a copy of the token with an expiry in the past, simulating expired tokens.
+	Expired bool
+}
+
+// FaultInjector decides which Fault (if any) to inject for each call to Token.
+type FaultInjector interface {
+	Fault() Fault
+}
+
+// FaultInjectorFunc is a function implementing the FaultInjector interface.
+type FaultInjectorFunc func() Fault
+
+// Fault implements the FaultInjector interface.
+func (fn FaultInjectorFunc) Fault() Fault {
+	return fn()
+}
+
+// SimulateCredentialLoss is a FaultInjector that always fails with ErrInjectedCredentialLoss.
+var SimulateCredentialLoss = FaultInjectorFunc(func() Fault {
+	return Fault{Err: ErrInjectedCredentialLoss}
+})
+
+// RandomFaults returns a FaultInjector that injects fault with the given probability (0.0-1.0).
+func RandomFaults(probability float64, fault Fault) FaultInjector {
+	return FaultInjectorFunc(func() Fault {
+		if rand.Float64() < probability {
+			return fault
+		}
+		return Fault{}
+	})
+}
+
+// FaultTokenSource is an oauth2.TokenSource that injects faults into a wrapped oauth2.TokenSource.
+// It is intended for resilience testing of consumers and should not be used in production.
+type FaultTokenSource struct {
+	Source   oauth2.TokenSource
+	Injector FaultInjector
+}
+
+// NewFaultTokenSource creates a FaultTokenSource wrapping src.
+func NewFaultTokenSource(src oauth2.TokenSource, injector FaultInjector) *FaultTokenSource {
+	return &FaultTokenSource{Source: src, Injector: injector}
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (fts *FaultTokenSource) Token() (*oauth2.Token, error) {
+	return fts.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+func (fts *FaultTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	var fault Fault
+	if fts.Injector != nil {
+		fault = fts.Injector.Fault()
+	}
+	if fault.Delay > 0 {
+		timer := time.NewTimer(fault.Delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+	if fault.Err != nil {
+		return nil, fault.Err
+	}
+	token, err := TokenWithContext(ctx, fts.Source)
+	if err != nil || !fault.Expired {
+		return token, err
+	}
+	expired := *token
+	expired.Expiry = time.Now().Add(-time.Second)
+	return &expired, nil
+}
@@ -0,0 +1,16 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package eksauth
+
+import "os"
+
+// tryLockFile always succeeds, there is no advisory file locking on this platform so refreshes are not serialized
+// across lockers.
+func tryLockFile(f *os.File) (bool, error) {
+	return true, nil
+}
+
+// unlockFile releases the lock acquired by tryLockFile.
+func unlockFile(f *os.File) error {
+	return nil
+}
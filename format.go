@@ -0,0 +1,132 @@
+package eksauth
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownTokenFormat is returned when a token does not match any registered TokenFormat.
+var ErrUnknownTokenFormat = errors.New("eksauth: unknown token format")
+
+// TokenFormat converts a presigned sts:GetCallerIdentity URL into a bearer token and back.
+type TokenFormat interface {
+	// Prefix is the unique prefix of tokens in this format, ie "k8s-aws-v1.".
+	Prefix() string
+	// Encode converts a presigned URL into a token.
+	Encode(presignedURL string) string
+	// Decode converts a token back into the presigned URL.
+	Decode(token string) (string, error)
+}
+
+// Encoder is a TokenFormat encoding the whole presigned request (method and signed headers) instead of only its URL,
+// ie experimental formats or formats for authenticators requiring a POST. TokenSource uses EncodeRequest and
+// DecodeTokenRequest (and so Verifier) uses DecodeRequest for formats implementing it, Decode must still return the
+// presigned URL for the offline checks. Verifier only executes GET requests and only sends the signed x-amz-* headers.
+type Encoder interface {
+	TokenFormat
+	// EncodeRequest converts a presigned request into a token.
+	EncodeRequest(req *PresignedRequest) (string, error)
+	// DecodeRequest converts a token back into the presigned request.
+	DecodeRequest(token string) (*PresignedRequest, error)
+}
+
+// prefixFormat is a TokenFormat that is a prefix followed by the unpadded base64url encoded URL.
+type prefixFormat string
+
+// NewPrefixFormat creates a TokenFormat identical to V1Format except for the prefix.
+func NewPrefixFormat(prefix string) TokenFormat {
+	return prefixFormat(prefix)
+}
+
+// Prefix implements the TokenFormat interface.
+func (f prefixFormat) Prefix() string {
+	return string(f)
+}
+
+// Encode implements the TokenFormat interface.
+func (f prefixFormat) Encode(presignedURL string) string {
+	buf := make([]byte, len(f), len(f)+base64.RawURLEncoding.EncodedLen(len(presignedURL)))
+	copy(buf, f)
+	return string(base64.RawURLEncoding.AppendEncode(buf, []byte(presignedURL)))
+}
+
+// Decode implements the TokenFormat interface.
+func (f prefixFormat) Decode(token string) (string, error) {
+	encoded, ok := strings.CutPrefix(token, string(f))
+	if !ok {
+		return "", fmt.Errorf("eksauth: token is missing the %q prefix", string(f))
+	}
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("eksauth: failed to decode token: %w", err)
+	}
+	return string(b), nil
+}
+
+// V1Format is the "k8s-aws-v1." token format understood by EKS and aws-iam-authenticator.
+var V1Format = NewPrefixFormat("k8s-aws-v1.")
+
+var (
+	formatsMu sync.RWMutex
+	formats   = map[string]TokenFormat{V1Format.Prefix(): V1Format}
+)
+
+// RegisterTokenFormat registers a TokenFormat so it can be found by LookupTokenFormat and DecodeToken.
+func RegisterTokenFormat(format TokenFormat) error {
+	prefix := format.Prefix()
+	if prefix == "" {
+		return errors.New("eksauth: token format prefix cannot be empty")
+	}
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	if _, exists := formats[prefix]; exists {
+		return fmt.Errorf("eksauth: token format %q is already registered", prefix)
+	}
+	formats[prefix] = format
+	return nil
+}
+
+// LookupTokenFormat returns the registered TokenFormat matching the prefix of the token, the longest prefix wins
+// if several match (ie "k8s-aws-v1." and "k8s-aws-v1.x.").
+func LookupTokenFormat(token string) (TokenFormat, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	var match TokenFormat
+	var matchLen int
+	for prefix, format := range formats {
+		if len(prefix) > matchLen && strings.HasPrefix(token, prefix) {
+			match, matchLen = format, len(prefix)
+		}
+	}
+	return match, match != nil
+}
+
+// DecodeTokenRequest decodes a token in any registered TokenFormat into the presigned request, formats that are not
+// an Encoder only carry the URL of a GET request without signed headers.
+func DecodeTokenRequest(token string) (*PresignedRequest, error) {
+	format, ok := LookupTokenFormat(token)
+	if !ok {
+		return nil, ErrUnknownTokenFormat
+	}
+	if enc, ok := format.(Encoder); ok {
+		return enc.DecodeRequest(token)
+	}
+	presignedURL, err := format.Decode(token)
+	if err != nil {
+		return nil, err
+	}
+	return &PresignedRequest{URL: presignedURL, Method: http.MethodGet}, nil
+}
+
+// DecodeToken decodes a token in any registered TokenFormat into the presigned URL.
+func DecodeToken(token string) (string, error) {
+	format, ok := LookupTokenFormat(token)
+	if !ok {
+		return "", ErrUnknownTokenFormat
+	}
+	return format.Decode(token)
+}
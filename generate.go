@@ -0,0 +1,71 @@
+package eksauth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Token is a generated EKS token with its metadata, so audit logs and higher level tooling do not need to decode it.
+type Token struct {
+	// AccessToken is the bearer token.
+	AccessToken string
+	// Expiry is when the token expires.
+	Expiry time.Time
+	// ClusterName is the cluster the token was generated for, empty for tokens restored with WithTokenState.
+	ClusterName string
+	// Region is the signing region.
+	Region string
+	// SigningTime is the X-Amz-Date of the presigned request.
+	SigningTime time.Time
+	// PresignedURL is the presigned sts:GetCallerIdentity URL.
+	PresignedURL string
+	// AccessKeyID is the access key ID of the signing credentials.
+	AccessKeyID string
+	// CredentialSource is the source of the signing credentials (aws.Credentials.Source), base credentials first.
+	CredentialSource string
+	// Provenance is the full Provenance of the token, nil for tokens restored with WithTokenState.
+	Provenance *Provenance
+	// OAuth2 is the underlying oauth2.Token.
+	OAuth2 *oauth2.Token
+}
+
+// NewToken returns the Token of an oauth2.Token generated by this package. The metadata of tokens without a
+// Provenance (ie restored with WithTokenState) is decoded from the token.
+func NewToken(t *oauth2.Token) (*Token, error) {
+	token := &Token{AccessToken: t.AccessToken, Expiry: t.Expiry, OAuth2: t}
+	if p, ok := TokenProvenance(t); ok {
+		token.ClusterName, token.Region, token.SigningTime = p.ClusterName, p.Region, p.SigningTime
+		token.AccessKeyID, token.CredentialSource = p.AccessKeyID, strings.Join(p.CredentialSources, credentialSourceSeparator)
+		token.Provenance = p
+	}
+	req, err := TokenPresignedRequest(t)
+	if err != nil {
+		return nil, err
+	}
+	token.PresignedURL = req.URL
+	if token.Provenance == nil {
+		parsed, err := ParseToken(t.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		token.Region, token.SigningTime, token.AccessKeyID = parsed.Region, parsed.SigningTime, parsed.AccessKeyID
+	}
+	return token, nil
+}
+
+// Generate returns a token of ts with its metadata, see NewToken.
+func Generate(ctx context.Context, ts oauth2.TokenSource) (*Token, error) {
+	t, err := TokenWithContext(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+	return NewToken(t)
+}
+
+// Generate generates a new token with its metadata, see NewToken.
+func (ts *TokenSource) Generate(ctx context.Context) (*Token, error) {
+	return Generate(ctx, ts)
+}
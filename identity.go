@@ -0,0 +1,148 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"golang.org/x/oauth2"
+)
+
+// Identity is the AWS principal returned by sts:GetCallerIdentity.
+type Identity struct {
+	Account string
+	ARN     string
+	UserID  string
+}
+
+// IdentityCache caches the sts:GetCallerIdentity result for the current "epoch" of AWS credentials.
+// An epoch ends when the underlying credentials rotate (new access key or new expiry), at which point
+// the next call to Identity performs a fresh sts:GetCallerIdentity call.
+type IdentityCache struct {
+	Client *sts.Client
+
+	mu       sync.Mutex
+	epoch    string
+	identity *Identity
+}
+
+// NewIdentityCache creates a new IdentityCache from a sts.Client.
+func NewIdentityCache(client *sts.Client) *IdentityCache {
+	return &IdentityCache{Client: client}
+}
+
+// credentialEpoch returns a string that changes whenever the credentials rotate.
+func credentialEpoch(creds aws.Credentials) string {
+	if creds.CanExpire {
+		return creds.AccessKeyID + "@" + creds.Expires.UTC().Format(time.RFC3339Nano)
+	}
+	return creds.AccessKeyID
+}
+
+// Identity returns the caller identity of the current credentials, only calling sts:GetCallerIdentity
+// if the credentials have rotated since the last successful call.
+func (c *IdentityCache) Identity(ctx context.Context) (*Identity, error) {
+	opts := c.Client.Options()
+	if opts.Credentials == nil {
+		return nil, errors.New("eksauth: sts.Client has no credentials provider")
+	}
+	creds, err := opts.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	epoch := credentialEpoch(creds)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.identity != nil && c.epoch == epoch {
+		return c.identity, nil
+	}
+	out, err := c.Client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, err
+	}
+	c.epoch = epoch
+	c.identity = &Identity{
+		Account: aws.ToString(out.Account),
+		ARN:     aws.ToString(out.Arn),
+		UserID:  aws.ToString(out.UserId),
+	}
+	return c.identity, nil
+}
+
+// Invalidate discards the cached identity so the next call to Identity calls sts:GetCallerIdentity.
+func (c *IdentityCache) Invalidate() {
+	c.mu.Lock()
+	c.identity = nil
+	c.epoch = ""
+	c.mu.Unlock()
+}
+
+// IdentityTokenSource is an oauth2.TokenSource recording the caller identity of the signing credentials in the
+// Provenance of every token (see TokenIdentity), so applications can log or audit which principal accesses a cluster.
+// sts:GetCallerIdentity is executed once per credential set by the IdentityCache, failing tokens if it fails.
+// It is safe for concurrent use.
+type IdentityTokenSource struct {
+	Source oauth2.TokenSource
+	Cache  *IdentityCache
+}
+
+// NewIdentityTokenSource creates an IdentityTokenSource, client must use the credentials signing the tokens of ts.
+func NewIdentityTokenSource(ts oauth2.TokenSource, client *sts.Client) *IdentityTokenSource {
+	return &IdentityTokenSource{Source: ts, Cache: NewIdentityCache(client)}
+}
+
+// Identity returns the caller identity of the current credentials, see IdentityCache.Identity.
+func (s *IdentityTokenSource) Identity(ctx context.Context) (*Identity, error) {
+	return s.Cache.Identity(ctx)
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (s *IdentityTokenSource) Token() (*oauth2.Token, error) {
+	return s.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+// Tokens without a Provenance (not generated by this package) are returned as is, use Identity instead.
+func (s *IdentityTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	t, err := TokenWithContext(ctx, s.Source)
+	if err != nil {
+		return nil, err
+	}
+	provenance, ok := TokenProvenance(t)
+	identity, err := s.Cache.Identity(ctx)
+	if err != nil {
+		var clusterName string
+		if ok {
+			clusterName = provenance.ClusterName
+		}
+		return nil, &Error{Op: "GetCallerIdentity", ClusterName: clusterName, Err: wrapThrottled("GetCallerIdentity", err)}
+	}
+	if ok {
+		provenance.Identity = identity
+	}
+	return t, nil
+}
+
+// Invalidate implements the Invalidator interface, the cached identity is kept until the credentials rotate.
+func (s *IdentityTokenSource) Invalidate() {
+	Invalidate(s.Source)
+}
+
+// Close implements the io.Closer interface.
+func (s *IdentityTokenSource) Close() error {
+	return closeTokenSource(s.Source)
+}
+
+// TokenIdentity returns the caller identity recorded in the Provenance of a token by IdentityTokenSource (see
+// WithCallerIdentity), if present.
+func TokenIdentity(t *oauth2.Token) (*Identity, bool) {
+	p, ok := TokenProvenance(t)
+	if !ok || p.Identity == nil {
+		return nil, false
+	}
+	return p.Identity, true
+}
@@ -0,0 +1,96 @@
+package eksauth
+
+import (
+	"context"
+
+	"golang.org/x/oauth2"
+)
+
+// Invalidate discards the cached token of ts if it implements Invalidator, so the next call to Token
+// generates a new token. It reports if ts implements Invalidator.
+// Every caching token source in this package (including the wrappers returned by the New* constructors)
+// implements Invalidator, wrapper token sources forward the call to the source they wrap.
+func Invalidate(ts oauth2.TokenSource) bool {
+	invalidator, ok := ts.(Invalidator)
+	if ok {
+		invalidator.Invalidate()
+	}
+	return ok
+}
+
+// ForceRefresh discards the cached token of ts (see Invalidate) and immediately generates a new token,
+// ie after the API server rejected the cached token.
+func ForceRefresh(ctx context.Context, ts oauth2.TokenSource) (*oauth2.Token, error) {
+	Invalidate(ts)
+	return TokenWithContext(ctx, ts)
+}
+
+// Invalidate implements the Invalidator interface.
+func (s *FaultTokenSource) Invalidate() {
+	Invalidate(s.Source)
+}
+
+// Close implements the io.Closer interface.
+func (s *FaultTokenSource) Close() error {
+	return closeTokenSource(s.Source)
+}
+
+// Invalidate implements the Invalidator interface.
+func (s *RequireAssumedRoleTokenSource) Invalidate() {
+	Invalidate(s.Source)
+}
+
+// Close implements the io.Closer interface.
+func (s *RequireAssumedRoleTokenSource) Close() error {
+	return closeTokenSource(s.Source)
+}
+
+// Invalidate implements the Invalidator interface.
+func (s *PolicyTokenSource) Invalidate() {
+	Invalidate(s.Source)
+}
+
+// Close implements the io.Closer interface.
+func (s *PolicyTokenSource) Close() error {
+	return closeTokenSource(s.Source)
+}
+
+// Invalidate implements the Invalidator interface.
+func (r *ReloadingTokenSource) Invalidate() {
+	r.mu.Lock()
+	current := r.current
+	r.mu.Unlock()
+	if current != nil {
+		Invalidate(current)
+	}
+}
+
+// Close implements the io.Closer interface, it closes the current token source.
+func (r *ReloadingTokenSource) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current := r.current
+	r.current = nil
+	return closeTokenSource(current)
+}
+
+// Invalidate implements the Invalidator interface, the next call to Token refreshes synchronously.
+func (s *RefreshingTokenSource) Invalidate() {
+	s.mu.Lock()
+	s.t = nil
+	s.mu.Unlock()
+	Invalidate(s.Source)
+}
+
+// Invalidate implements the Invalidator interface, the cache entry is deleted if Cache implements TokenCacheDeleter.
+func (s *CachedTokenSource) Invalidate() {
+	if deleter, ok := s.Cache.(TokenCacheDeleter); ok {
+		_ = deleter.Delete(s.Key)
+	}
+	Invalidate(s.Source)
+}
+
+// Close implements the io.Closer interface.
+func (s *CachedTokenSource) Close() error {
+	return closeTokenSource(s.Source)
+}
@@ -0,0 +1,93 @@
+// Package keyring implements an eksauth.TokenCache backed by the OS keyring
+// (macOS Keychain, the Linux Secret Service or the Windows Credential Manager),
+// so cached tokens are not written to plaintext files.
+package keyring
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	gokeyring "github.com/zalando/go-keyring"
+	"golang.org/x/oauth2"
+)
+
+// DefaultService is the keyring service name tokens are stored under.
+const DefaultService = "eks-auth"
+
+// cachedToken is the keyring representation of a cached token.
+type cachedToken struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type,omitempty"`
+	Expiry      time.Time `json:"expiry"`
+}
+
+// Cache is an eksauth.TokenCache storing tokens in the OS keyring.
+type Cache struct {
+	// Service is the keyring service name, if empty DefaultService is used.
+	Service string
+}
+
+// New creates a new Cache using DefaultService.
+func New() *Cache {
+	return &Cache{Service: DefaultService}
+}
+
+// compile time checks that Cache implements the eksauth.TokenCache and eksauth.TokenCacheDeleter interfaces.
+var (
+	_ eksauth.TokenCache        = (*Cache)(nil)
+	_ eksauth.TokenCacheDeleter = (*Cache)(nil)
+)
+
+// service returns the keyring service name.
+func (c *Cache) service() string {
+	if c.Service == "" {
+		return DefaultService
+	}
+	return c.Service
+}
+
+// Load implements the eksauth.TokenCache interface.
+func (c *Cache) Load(key string) (*oauth2.Token, error) {
+	secret, err := gokeyring.Get(c.service(), key)
+	if errors.Is(err, gokeyring.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("keyring: failed to read cached token: %w", err)
+	}
+	var cached cachedToken
+	if err := json.Unmarshal([]byte(secret), &cached); err != nil {
+		return nil, nil
+	}
+	return &oauth2.Token{
+		AccessToken: cached.AccessToken,
+		TokenType:   cached.TokenType,
+		Expiry:      cached.Expiry,
+	}, nil
+}
+
+// Store implements the eksauth.TokenCache interface.
+func (c *Cache) Store(key string, t *oauth2.Token) error {
+	data, err := json.Marshal(cachedToken{
+		AccessToken: t.AccessToken,
+		TokenType:   t.TokenType,
+		Expiry:      t.Expiry,
+	})
+	if err != nil {
+		return err
+	}
+	if err := gokeyring.Set(c.service(), key, string(data)); err != nil {
+		return fmt.Errorf("keyring: failed to write cached token: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the cached token for key, it is not an error if there is no cached token.
+func (c *Cache) Delete(key string) error {
+	if err := gokeyring.Delete(c.service(), key); err != nil && !errors.Is(err, gokeyring.ErrNotFound) {
+		return fmt.Errorf("keyring: failed to delete cached token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Package authprovider registers an "eks" client-go auth provider, so kubeconfig driven tools can authenticate
+// to EKS clusters with a blank import:
+//
+//	import _ "github.com/bored-engineer/aws-eks-auth/kube/authprovider"
+//
+// The provider is configured by the auth-provider config of the kubeconfig user:
+//
+//	users:
+//	- name: eks
+//	  user:
+//	    auth-provider:
+//	      name: eks
+//	      config:
+//	        cluster-name: eks-cluster-name
+//	        region: us-west-2
+//	        role-arn: arn:aws:iam::123456789012:role/eks-admin
+//	        aws-profile: default
+package authprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"github.com/bored-engineer/aws-eks-auth/kube"
+	"golang.org/x/oauth2"
+	"k8s.io/client-go/rest"
+)
+
+// Name is the name the auth provider is registered as.
+const Name = "eks"
+
+// Keys of the auth-provider config.
+const (
+	ConfigClusterName = "cluster-name"
+	ConfigRegion      = "region"
+	ConfigRoleARN     = "role-arn"
+	ConfigAWSProfile  = "aws-profile"
+)
+
+func init() {
+	if err := rest.RegisterAuthProviderPlugin(Name, New); err != nil {
+		panic(err)
+	}
+}
+
+// sourceKey identifies the token source of a config.
+type sourceKey struct {
+	cluster    eksauth.ClusterConfig
+	awsProfile string
+}
+
+// sources caches the token source of each distinct config, client-go creates a provider for every client.
+var (
+	sourcesMu sync.Mutex
+	sources   = make(map[sourceKey]oauth2.TokenSource)
+)
+
+// provider is the rest.AuthProvider.
+type provider struct {
+	ts oauth2.TokenSource
+}
+
+// New is the rest.Factory of the auth provider, it is registered automatically.
+func New(_ string, cfg map[string]string, _ rest.AuthProviderConfigPersister) (rest.AuthProvider, error) {
+	cluster := eksauth.ClusterConfig{
+		Name:    cfg[ConfigClusterName],
+		Region:  cfg[ConfigRegion],
+		RoleARN: cfg[ConfigRoleARN],
+	}
+	if cluster.Name == "" {
+		return nil, errors.New("authprovider: " + ConfigClusterName + " is required")
+	}
+	key := sourceKey{cluster: cluster, awsProfile: cfg[ConfigAWSProfile]}
+
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	if ts, ok := sources[key]; ok {
+		return &provider{ts: ts}, nil
+	}
+	var opts []func(*config.LoadOptions) error
+	if key.awsProfile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(key.awsProfile))
+	}
+	awsCfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := eksauth.NewFromClusterConfig(awsCfg, cluster)
+	if err != nil {
+		return nil, err
+	}
+	sources[key] = ts
+	return &provider{ts: ts}, nil
+}
+
+// WrapTransport implements the rest.AuthProvider interface.
+func (p *provider) WrapTransport(base http.RoundTripper) http.RoundTripper {
+	return kube.WrapperFunc(p.ts)(base)
+}
+
+// Login implements the rest.AuthProvider interface, it generates a token to verify the configuration.
+func (p *provider) Login() error {
+	_, err := p.ts.Token()
+	return err
+}
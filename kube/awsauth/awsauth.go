@@ -0,0 +1,219 @@
+// Package awsauth maps verified IAM identities to Kubernetes users using the kube-system/aws-auth ConfigMap,
+// matching the semantics of aws-iam-authenticator. It is intended for servers verifying tokens (see eksauth.Verifier).
+package awsauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/yaml"
+)
+
+// Namespace and Name identify the aws-auth ConfigMap.
+const (
+	Namespace = "kube-system"
+	Name      = "aws-auth"
+)
+
+// ErrNotMapped is returned by Map when the identity is not mapped to a Kubernetes user.
+var ErrNotMapped = errors.New("awsauth: identity is not mapped")
+
+// RoleMapping is an entry of mapRoles.
+type RoleMapping struct {
+	RoleARN  string   `json:"rolearn"`
+	Username string   `json:"username"`
+	Groups   []string `json:"groups,omitempty"`
+}
+
+// UserMapping is an entry of mapUsers.
+type UserMapping struct {
+	UserARN  string   `json:"userarn"`
+	Username string   `json:"username"`
+	Groups   []string `json:"groups,omitempty"`
+}
+
+// User is the Kubernetes user an identity is mapped to.
+type User struct {
+	Username string
+	Groups   []string
+}
+
+// Mapping is the parsed content of the aws-auth ConfigMap.
+type Mapping struct {
+	Roles    []RoleMapping
+	Users    []UserMapping
+	Accounts []string
+}
+
+// Parse parses the data of the aws-auth ConfigMap (the mapRoles, mapUsers and mapAccounts keys).
+func Parse(data map[string]string) (*Mapping, error) {
+	var m Mapping
+	if err := yaml.Unmarshal([]byte(data["mapRoles"]), &m.Roles); err != nil {
+		return nil, fmt.Errorf("awsauth: invalid mapRoles: %w", err)
+	}
+	if err := yaml.Unmarshal([]byte(data["mapUsers"]), &m.Users); err != nil {
+		return nil, fmt.Errorf("awsauth: invalid mapUsers: %w", err)
+	}
+	if err := yaml.Unmarshal([]byte(data["mapAccounts"]), &m.Accounts); err != nil {
+		return nil, fmt.Errorf("awsauth: invalid mapAccounts: %w", err)
+	}
+	return &m, nil
+}
+
+// canonicalARN returns the canonical (lower case) principal ARN of a caller ARN, assumed role sessions are
+// converted into the role ARN, and the session name (if any).
+func canonicalARN(callerARN string) (principal string, sessionName string) {
+	if parsed, err := arn.Parse(callerARN); err == nil && parsed.Service == "sts" && strings.HasPrefix(parsed.Resource, "assumed-role/") {
+		sessionName = parsed.Resource[strings.LastIndex(parsed.Resource, "/")+1:]
+	}
+	return strings.ToLower(eksauth.PrincipalARN(callerARN)), sessionName
+}
+
+// stripPath removes the path of a role or user ARN, assumed role sessions do not include the path of the role.
+func stripPath(s string) string {
+	parsed, err := arn.Parse(s)
+	if err != nil {
+		return strings.ToLower(s)
+	}
+	kind, rest, ok := strings.Cut(parsed.Resource, "/")
+	if ok {
+		parsed.Resource = kind + "/" + rest[strings.LastIndex(rest, "/")+1:]
+	}
+	return strings.ToLower(parsed.String())
+}
+
+// render expands the {{AccountID}}, {{SessionName}} and {{SessionNameRaw}} templates of a username or group.
+// Like aws-iam-authenticator, {{SessionName}} replaces '@' with '-'.
+func render(s string, identity *eksauth.Identity, sessionName string) string {
+	return strings.NewReplacer(
+		"{{AccountID}}", identity.Account,
+		"{{SessionName}}", strings.ReplaceAll(sessionName, "@", "-"),
+		"{{SessionNameRaw}}", sessionName,
+	).Replace(s)
+}
+
+// user renders the User of a mapping entry, an empty username defaults to the caller ARN.
+func user(username string, groups []string, identity *eksauth.Identity, sessionName string) *User {
+	u := &User{Username: identity.ARN}
+	if username != "" {
+		u.Username = render(username, identity, sessionName)
+	}
+	for _, group := range groups {
+		u.Groups = append(u.Groups, render(group, identity, sessionName))
+	}
+	return u
+}
+
+// Map maps a verified identity (see eksauth.Verifier.Verify) to a Kubernetes user using mapRoles, then mapUsers,
+// then mapAccounts. It returns ErrNotMapped if no entry matches.
+func (m *Mapping) Map(identity *eksauth.Identity) (*User, error) {
+	principal, sessionName := canonicalARN(identity.ARN)
+	for _, role := range m.Roles {
+		if strings.ToLower(role.RoleARN) == principal || stripPath(role.RoleARN) == principal {
+			return user(role.Username, role.Groups, identity, sessionName), nil
+		}
+	}
+	for _, u := range m.Users {
+		if strings.ToLower(u.UserARN) == principal || stripPath(u.UserARN) == principal {
+			return user(u.Username, u.Groups, identity, sessionName), nil
+		}
+	}
+	for _, account := range m.Accounts {
+		if account == identity.Account {
+			return user("", nil, identity, sessionName), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotMapped, identity.ARN)
+}
+
+// Watcher keeps a Mapping up to date by watching the aws-auth ConfigMap.
+// It is safe for concurrent use.
+type Watcher struct {
+	Client kubernetes.Interface
+	// OnError is called when the ConfigMap cannot be parsed, the previous Mapping remains in use. It may be nil.
+	OnError func(err error)
+
+	mapping atomic.Pointer[Mapping]
+}
+
+// NewWatcher creates a Watcher for the aws-auth ConfigMap of the cluster of client, call Run to start watching.
+func NewWatcher(client kubernetes.Interface) *Watcher {
+	return &Watcher{Client: client}
+}
+
+// Load fetches the aws-auth ConfigMap once, ie for callers that do not Run the Watcher.
+func (w *Watcher) Load(ctx context.Context) error {
+	cm, err := w.Client.CoreV1().ConfigMaps(Namespace).Get(ctx, Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	m, err := Parse(cm.Data)
+	if err != nil {
+		return err
+	}
+	w.mapping.Store(m)
+	return nil
+}
+
+// update parses and stores the mapping of a ConfigMap received by the informer.
+func (w *Watcher) update(obj any) {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return
+	}
+	m, err := Parse(cm.Data)
+	if err != nil {
+		if w.OnError != nil {
+			w.OnError(err)
+		}
+		return
+	}
+	w.mapping.Store(m)
+}
+
+// Run watches the aws-auth ConfigMap until ctx is done, it returns once the initial state has been synced.
+func (w *Watcher) Run(ctx context.Context) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(w.Client, 0,
+		informers.WithNamespace(Namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", Name).String()
+		}),
+	)
+	informer := factory.Core().V1().ConfigMaps().Informer()
+	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    w.update,
+		UpdateFunc: func(_, obj any) { w.update(obj) },
+		DeleteFunc: func(any) { w.mapping.Store(&Mapping{}) },
+	}); err != nil {
+		return err
+	}
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return errors.New("awsauth: failed to sync the aws-auth ConfigMap")
+	}
+	return nil
+}
+
+// Mapping returns the current Mapping, it is empty until the ConfigMap has been loaded.
+func (w *Watcher) Mapping() *Mapping {
+	if m := w.mapping.Load(); m != nil {
+		return m
+	}
+	return &Mapping{}
+}
+
+// Map maps identity using the current Mapping, see Mapping.Map.
+func (w *Watcher) Map(identity *eksauth.Identity) (*User, error) {
+	return w.Mapping().Map(identity)
+}
@@ -0,0 +1,143 @@
+package awsauth_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"github.com/bored-engineer/aws-eks-auth/kube/awsauth"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// configMapData is an aws-auth ConfigMap exercising every kind of mapping.
+var configMapData = map[string]string{
+	"mapRoles": `
+- rolearn: arn:aws:iam::111122223333:role/admin
+  username: admin:{{SessionName}}
+  groups:
+  - system:masters
+- rolearn: arn:aws:iam::111122223333:role/teams/dev/developer
+  username: dev:{{AccountID}}:{{SessionNameRaw}}
+  groups:
+  - developers
+  - account:{{AccountID}}
+- rolearn: arn:aws:iam::111122223333:role/Nodes
+  username: system:node:{{SessionName}}
+  groups:
+  - system:nodes
+`,
+	"mapUsers": `
+- userarn: arn:aws:iam::111122223333:user/alice
+  username: alice
+  groups:
+  - viewers
+- userarn: arn:aws:iam::111122223333:user/ops/bob
+`,
+	"mapAccounts": `
+- "444455556666"
+`,
+}
+
+func TestMap(t *testing.T) {
+	m, err := awsauth.Parse(configMapData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		arn  string
+		want *awsauth.User
+	}{
+		{
+			name: "assumed role",
+			arn:  "arn:aws:sts::111122223333:assumed-role/admin/jane@example.com",
+			want: &awsauth.User{Username: "admin:jane-example.com", Groups: []string{"system:masters"}},
+		},
+		{
+			name: "role",
+			arn:  "arn:aws:iam::111122223333:role/admin",
+			want: &awsauth.User{Username: "admin:", Groups: []string{"system:masters"}},
+		},
+		{
+			// Assumed role sessions do not include the path of the role.
+			name: "assumed role with a path",
+			arn:  "arn:aws:sts::111122223333:assumed-role/developer/jane@example.com",
+			want: &awsauth.User{Username: "dev:111122223333:jane@example.com", Groups: []string{"developers", "account:111122223333"}},
+		},
+		{
+			name: "case insensitive",
+			arn:  "arn:aws:sts::111122223333:assumed-role/nodes/i-0123456789abcdef0",
+			want: &awsauth.User{Username: "system:node:i-0123456789abcdef0", Groups: []string{"system:nodes"}},
+		},
+		{
+			name: "user",
+			arn:  "arn:aws:iam::111122223333:user/alice",
+			want: &awsauth.User{Username: "alice", Groups: []string{"viewers"}},
+		},
+		{
+			name: "user with a path",
+			arn:  "arn:aws:iam::111122223333:user/bob",
+			want: &awsauth.User{Username: "arn:aws:iam::111122223333:user/bob"},
+		},
+		{
+			name: "account",
+			arn:  "arn:aws:sts::444455556666:assumed-role/anything/session",
+			want: &awsauth.User{Username: "arn:aws:sts::444455556666:assumed-role/anything/session"},
+		},
+		{name: "unmapped role", arn: "arn:aws:sts::111122223333:assumed-role/other/session"},
+		{name: "unmapped account", arn: "arn:aws:sts::999999999999:assumed-role/admin/session"},
+		{name: "role name as session", arn: "arn:aws:sts::111122223333:assumed-role/other/admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := arn.Parse(tt.arn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := m.Map(&eksauth.Identity{Account: parsed.AccountID, ARN: tt.arn, UserID: "AROA:session"})
+			if tt.want == nil {
+				if !errors.Is(err, awsauth.ErrNotMapped) {
+					t.Errorf("got %+v, %v, want %v", got, err, awsauth.ErrNotMapped)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, key := range []string{"mapRoles", "mapUsers", "mapAccounts"} {
+		if _, err := awsauth.Parse(map[string]string{key: "{"}); err == nil {
+			t.Errorf("%s: got nil error", key)
+		}
+	}
+}
+
+func TestWatcherLoad(t *testing.T) {
+	client := fake.NewClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: awsauth.Namespace, Name: awsauth.Name},
+		Data:       configMapData,
+	})
+	w := awsauth.NewWatcher(client)
+	identity := &eksauth.Identity{Account: "111122223333", ARN: "arn:aws:iam::111122223333:user/alice"}
+	if _, err := w.Map(identity); !errors.Is(err, awsauth.ErrNotMapped) {
+		t.Errorf("before Load: got %v, want %v", err, awsauth.ErrNotMapped)
+	}
+	if err := w.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if u, err := w.Map(identity); err != nil || u.Username != "alice" {
+		t.Errorf("got %+v, %v", u, err)
+	}
+}
@@ -0,0 +1,77 @@
+package kube
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// ClusterInfoRESTConfig returns an unauthenticated rest.Config for the API server endpoint and CA of the cluster,
+// see WrapConfig to authenticate it.
+func ClusterInfoRESTConfig(info *eksauth.ClusterInfo) *rest.Config {
+	return &rest.Config{
+		Host: info.Endpoint,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: info.CertificateAuthorityData,
+		},
+	}
+}
+
+// RESTConfig discovers the API server endpoint and CA of the EKS cluster using eks:DescribeCluster and returns
+// a rest.Config authenticating with a token source created from cfg (see eksauth.NewFromConfig).
+// The clusterName may be a cluster ARN, in which case its region is used for both DescribeCluster and tokens.
+// Clusters registered with EKS Connector fail with eksauth.ErrConnectorCluster, no token can authenticate them.
+// Use NewDiscoveryRESTConfig to share (and cache) discovery between calls.
+func RESTConfig(ctx context.Context, cfg aws.Config, clusterName string, opts ...eksauth.Option) (*rest.Config, error) {
+	return NewDiscoveryRESTConfig(ctx, eksauth.NewDiscoveryFromConfig(cfg), cfg, clusterName, opts...)
+}
+
+// NewDiscoveryRESTConfig is like RESTConfig but discovers the cluster using d.
+func NewDiscoveryRESTConfig(ctx context.Context, d *eksauth.Discovery, cfg aws.Config, clusterName string, opts ...eksauth.Option) (*rest.Config, error) {
+	info, err := d.Describe(ctx, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	restCfg := ClusterInfoRESTConfig(info)
+	WrapRestConfig(cfg, restCfg, clusterName, opts...)
+	return restCfg, nil
+}
+
+// NewClientset creates a kubernetes.Clientset for the EKS cluster, see RESTConfig.
+func NewClientset(ctx context.Context, cfg aws.Config, clusterName string, opts ...eksauth.Option) (*kubernetes.Clientset, error) {
+	restCfg, err := RESTConfig(ctx, cfg, clusterName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(restCfg)
+}
+
+// NewDynamicClient creates a dynamic.DynamicClient for the EKS cluster, see RESTConfig.
+func NewDynamicClient(ctx context.Context, cfg aws.Config, clusterName string, opts ...eksauth.Option) (*dynamic.DynamicClient, error) {
+	restCfg, err := RESTConfig(ctx, cfg, clusterName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(restCfg)
+}
+
+// RESTConfigs returns a rest.Config (see RESTConfig) for each cluster keyed by cluster name, ie for multi-cluster
+// controllers (ctrl.NewManager or client.New per cluster). Discovery is shared between the clusters.
+// The returned configs may be further wrapped (restCfg.Wrap) or copied (rest.CopyConfig), the EKS token is always
+// applied closest to the TLS transport.
+func RESTConfigs(ctx context.Context, cfg aws.Config, clusterNames []string, opts ...eksauth.Option) (map[string]*rest.Config, error) {
+	d := eksauth.NewDiscoveryFromConfig(cfg)
+	configs := make(map[string]*rest.Config, len(clusterNames))
+	for _, clusterName := range clusterNames {
+		restCfg, err := NewDiscoveryRESTConfig(ctx, d, cfg, clusterName, opts...)
+		if err != nil {
+			return nil, err
+		}
+		configs[clusterName] = restCfg
+	}
+	return configs, nil
+}
@@ -0,0 +1,188 @@
+package kube
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"golang.org/x/oauth2"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ClusterDetails are the EKS cluster details extracted from a kubeconfig context.
+type ClusterDetails struct {
+	// Context is the name of the kubeconfig context the details were extracted from.
+	Context string
+	// Name is the EKS cluster name.
+	Name string
+	// ARN is the EKS cluster ARN, if known.
+	ARN string
+	// Region is the AWS region of the cluster, if known.
+	Region string
+	// RoleARN is the IAM role assumed by the exec plugin, if any.
+	RoleARN string
+	// AWSProfile is the AWS_PROFILE set in the exec plugin environment, if any.
+	AWSProfile string
+	// Server is the Kubernetes API server URL.
+	Server string
+	// CertificateAuthorityData is the PEM encoded CA bundle of the API server.
+	CertificateAuthorityData []byte
+}
+
+// CurrentContext extracts the EKS cluster details of the current context of the default kubeconfig (ie $KUBECONFIG).
+func CurrentContext() (*ClusterDetails, error) {
+	cfg, err := clientcmd.NewDefaultClientConfigLoadingRules().Load()
+	if err != nil {
+		return nil, err
+	}
+	return ClusterDetailsFromConfig(cfg, "")
+}
+
+// ClusterDetailsFromConfig extracts the EKS cluster details from the named context of a kubeconfig.
+// If contextName is empty the current context of the kubeconfig is used.
+func ClusterDetailsFromConfig(cfg *clientcmdapi.Config, contextName string) (*ClusterDetails, error) {
+	if contextName == "" {
+		contextName = cfg.CurrentContext
+	}
+	kubeCtx, ok := cfg.Contexts[contextName]
+	if !ok || kubeCtx == nil {
+		return nil, fmt.Errorf("kube: context %q not found in kubeconfig", contextName)
+	}
+	details := &ClusterDetails{Context: contextName}
+
+	// aws eks update-kubeconfig names the cluster (and context) after the cluster ARN
+	for _, candidate := range []string{kubeCtx.Cluster, contextName} {
+		if name, region, ok := parseClusterARN(candidate); ok {
+			details.ARN, details.Name, details.Region = candidate, name, region
+			break
+		}
+	}
+
+	if cluster, ok := cfg.Clusters[kubeCtx.Cluster]; ok && cluster != nil {
+		details.Server = cluster.Server
+		details.CertificateAuthorityData = cluster.CertificateAuthorityData
+		if details.Region == "" {
+			details.Region = regionFromServer(cluster.Server)
+		}
+	}
+
+	if authInfo, ok := cfg.AuthInfos[kubeCtx.AuthInfo]; ok && authInfo != nil && authInfo.Exec != nil {
+		applyExecConfig(details, authInfo.Exec)
+	}
+
+	if details.Name == "" {
+		return nil, errors.New("kube: unable to determine the EKS cluster name from kubeconfig context " + contextName)
+	}
+	return details, nil
+}
+
+// parseClusterARN parses an EKS cluster ARN (arn:aws:eks:us-west-2:123456789012:cluster/foo).
+func parseClusterARN(s string) (name string, region string, ok bool) {
+	a, ok := eksauth.ParseClusterARN(s)
+	return a.Name, a.Region, ok
+}
+
+// regionFromServer extracts the region from an EKS API server URL (https://ABCDEF.gr7.us-west-2.eks.amazonaws.com).
+func regionFromServer(server string) string {
+	u, err := url.Parse(server)
+	if err != nil {
+		return ""
+	}
+	labels := strings.Split(u.Hostname(), ".")
+	for idx, label := range labels {
+		if label == "eks" && idx > 0 {
+			return labels[idx-1]
+		}
+	}
+	return ""
+}
+
+// applyExecConfig extracts the cluster details from an `aws eks get-token` or `aws-iam-authenticator token` exec stanza.
+func applyExecConfig(details *ClusterDetails, exec *clientcmdapi.ExecConfig) {
+	for _, env := range exec.Env {
+		switch env.Name {
+		case "AWS_PROFILE":
+			details.AWSProfile = env.Value
+		case "AWS_REGION", "AWS_DEFAULT_REGION":
+			if details.Region == "" {
+				details.Region = env.Value
+			}
+		}
+	}
+	nameFlags, roleFlags := []string{"--cluster-name"}, []string{"--role-arn"}
+	if strings.TrimSuffix(filepath.Base(exec.Command), ".exe") == "aws-iam-authenticator" {
+		nameFlags, roleFlags = []string{"-i", "--cluster-id"}, []string{"-r", "--role"}
+	}
+	for idx := 0; idx < len(exec.Args); idx++ {
+		flag, value, hasValue := strings.Cut(exec.Args[idx], "=")
+		if !hasValue {
+			if idx+1 >= len(exec.Args) {
+				break
+			}
+			value = exec.Args[idx+1]
+		}
+		switch {
+		case contains(nameFlags, flag):
+			if name, region, ok := parseClusterARN(value); ok {
+				details.ARN, details.Name, details.Region = value, name, region
+			} else {
+				details.Name = value
+			}
+		case contains(roleFlags, flag):
+			details.RoleARN = value
+		case flag == "--region":
+			details.Region = value
+		case flag == "--profile":
+			details.AWSProfile = value
+		default:
+			continue
+		}
+		if !hasValue {
+			idx++
+		}
+	}
+}
+
+// contains reports if s is in list.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// NewFromClusterDetails creates a new oauth2.TokenSource for the cluster details using the provided aws.Config.
+// If the details contain a region or role ARN they override the region/credentials of the aws.Config.
+// NOTE: The AWSProfile is not loaded, callers should load the aws.Config from that profile themselves.
+func NewFromClusterDetails(cfg aws.Config, details *ClusterDetails, opts ...eksauth.Option) (oauth2.TokenSource, error) {
+	return eksauth.NewFromClusterConfig(cfg, details.ClusterConfig(), opts...)
+}
+
+// NewFromCurrentContext creates a new oauth2.TokenSource for the cluster of the current kubeconfig context.
+func NewFromCurrentContext(cfg aws.Config, opts ...eksauth.Option) (oauth2.TokenSource, *ClusterDetails, error) {
+	details, err := CurrentContext()
+	if err != nil {
+		return nil, nil, err
+	}
+	ts, err := NewFromClusterDetails(cfg, details, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ts, details, nil
+}
+
+// ClusterConfig converts the details into an eksauth.ClusterConfig.
+func (details *ClusterDetails) ClusterConfig() eksauth.ClusterConfig {
+	return eksauth.ClusterConfig{
+		Name:    details.Name,
+		Region:  details.Region,
+		RoleARN: details.RoleARN,
+	}
+}
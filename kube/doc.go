@@ -0,0 +1,2 @@
+// Package kube integrates eksauth token sources with k8s.io/client-go.
+package kube
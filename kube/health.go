@@ -0,0 +1,121 @@
+package kube
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// HealthStatus classifies the result of a HealthCheck.
+type HealthStatus string
+
+// Statuses reported by HealthCheck.
+const (
+	// HealthOK is a successful authenticated call.
+	HealthOK HealthStatus = "ok"
+	// HealthTokenFailed is a failure to generate a token, ie missing or expired AWS credentials.
+	HealthTokenFailed HealthStatus = "token-failed"
+	// HealthNetwork is a failure to reach the API server (DNS, connection refused, timeout...).
+	HealthNetwork HealthStatus = "network"
+	// HealthTLS is a TLS handshake or certificate verification failure, ie a wrong certificate authority.
+	HealthTLS HealthStatus = "tls"
+	// HealthTokenRejected is a 401 Unauthorized, the cluster does not accept the token (ie no access entry).
+	HealthTokenRejected HealthStatus = "token-rejected"
+	// HealthForbidden is a 403 Forbidden, the token is accepted but RBAC denies the request.
+	HealthForbidden HealthStatus = "forbidden"
+	// HealthError is any other failure.
+	HealthError HealthStatus = "error"
+)
+
+// HealthResult is the result of a HealthCheck.
+type HealthResult struct {
+	Status HealthStatus
+	// Version is the version of the API server if the call succeeded.
+	Version *version.Info
+	// Latency is the duration of the authenticated call, including generating the token.
+	Latency time.Duration
+	// Err is the error of the call, nil if Status is HealthOK.
+	Err error
+}
+
+// Healthy reports if the check succeeded.
+func (r *HealthResult) Healthy() bool {
+	return r.Status == HealthOK
+}
+
+// HealthCheck generates a token and performs a cheap authenticated call (GET /version) against the API server of
+// restCfg (see RESTConfig), classifying failures so readiness probes can tell network, TLS, token and RBAC failures apart.
+func HealthCheck(ctx context.Context, restCfg *rest.Config) *HealthResult {
+	client, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return &HealthResult{Status: HealthError, Err: err}
+	}
+	return HealthCheckClient(ctx, client)
+}
+
+// HealthCheckCluster is HealthCheck for an EKS cluster discovered with eks:DescribeCluster, see RESTConfig.
+func HealthCheckCluster(ctx context.Context, cfg aws.Config, clusterName string, opts ...eksauth.Option) *HealthResult {
+	restCfg, err := RESTConfig(ctx, cfg, clusterName, opts...)
+	if err != nil {
+		return &HealthResult{Status: HealthError, Err: err}
+	}
+	return HealthCheck(ctx, restCfg)
+}
+
+// HealthCheckClient is HealthCheck using an existing client.
+func HealthCheckClient(ctx context.Context, client kubernetes.Interface) *HealthResult {
+	start := time.Now()
+	var info version.Info
+	body, err := client.Discovery().RESTClient().Get().AbsPath("/version").DoRaw(ctx)
+	if err == nil {
+		err = json.Unmarshal(body, &info)
+	}
+	result := &HealthResult{Latency: time.Since(start), Status: classifyHealth(err)}
+	if err != nil {
+		result.Err = err
+	} else {
+		result.Version = &info
+	}
+	return result
+}
+
+// classifyHealth returns the HealthStatus of the error of an authenticated call.
+func classifyHealth(err error) HealthStatus {
+	if err == nil {
+		return HealthOK
+	}
+	var (
+		eksErr          *eksauth.Error
+		unknownAuth     x509.UnknownAuthorityError
+		certInvalid     x509.CertificateInvalidError
+		hostnameErr     x509.HostnameError
+		verificationErr *tls.CertificateVerificationError
+		recordHeaderErr tls.RecordHeaderError
+		netErr          net.Error
+	)
+	switch {
+	case errors.As(err, &eksErr), errors.Is(err, eksauth.ErrCredentialRetrieval):
+		return HealthTokenFailed
+	case apierrors.IsUnauthorized(err):
+		return HealthTokenRejected
+	case apierrors.IsForbidden(err):
+		return HealthForbidden
+	case errors.As(err, &unknownAuth), errors.As(err, &certInvalid), errors.As(err, &hostnameErr),
+		errors.As(err, &verificationErr), errors.As(err, &recordHeaderErr):
+		return HealthTLS
+	case errors.As(err, &netErr):
+		return HealthNetwork
+	}
+	return HealthError
+}
@@ -0,0 +1,150 @@
+package kube
+
+import (
+	"errors"
+	"io/fs"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// DefaultExecCommand is the command of the exec plugin stanza written by NewKubeconfig.
+const DefaultExecCommand = "eks-auth"
+
+// KubeconfigOptions configure the kubeconfig entry generated by NewKubeconfig.
+type KubeconfigOptions struct {
+	// Alias is the name of the cluster and context, the cluster ARN (or name if unknown) by default.
+	Alias string
+	// UserAlias is the name of the user, Alias by default.
+	UserAlias string
+	// Command is the exec plugin command, DefaultExecCommand by default.
+	Command string
+	// Region is passed to the exec plugin as --region.
+	Region string
+	// RoleARN is passed to the exec plugin as --role-arn.
+	RoleARN string
+	// ExternalID is passed to the exec plugin as --external-id.
+	ExternalID string
+	// MFASerial is passed to the exec plugin as --mfa-serial.
+	MFASerial string
+	// SSOLogin passes --sso-login to the exec plugin.
+	SSOLogin bool
+	// Profile is passed to the exec plugin as --profile (an eks-auth profile).
+	Profile string
+	// AWSProfile is set as AWS_PROFILE in the exec plugin environment.
+	AWSProfile string
+	// Token embeds a static token in the user instead of an exec plugin stanza, it expires after at most 15 minutes.
+	Token string
+}
+
+// NewKubeconfig generates a kubeconfig containing a single cluster, user and (current) context for the cluster.
+// By default the user runs `eks-auth get-token` as an exec plugin, which ClusterDetailsFromConfig understands.
+func NewKubeconfig(info *eksauth.ClusterInfo, opts KubeconfigOptions) *clientcmdapi.Config {
+	clusterName := info.ARN
+	if clusterName == "" {
+		clusterName = info.Name
+	}
+	alias := opts.Alias
+	if alias == "" {
+		alias = clusterName
+	}
+	userAlias := opts.UserAlias
+	if userAlias == "" {
+		userAlias = alias
+	}
+
+	authInfo := &clientcmdapi.AuthInfo{Token: opts.Token}
+	if opts.Token == "" {
+		authInfo.Exec = newExecConfig(clusterName, opts)
+	}
+
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters[alias] = &clientcmdapi.Cluster{
+		Server:                   info.Endpoint,
+		CertificateAuthorityData: info.CertificateAuthorityData,
+	}
+	cfg.AuthInfos[userAlias] = authInfo
+	cfg.Contexts[alias] = &clientcmdapi.Context{
+		Cluster:  alias,
+		AuthInfo: userAlias,
+	}
+	cfg.CurrentContext = alias
+	return cfg
+}
+
+// newExecConfig returns the `eks-auth get-token` exec plugin stanza for the cluster, opts.Token is ignored.
+func newExecConfig(clusterName string, opts KubeconfigOptions) *clientcmdapi.ExecConfig {
+	command := opts.Command
+	if command == "" {
+		command = DefaultExecCommand
+	}
+	exec := &clientcmdapi.ExecConfig{
+		APIVersion:      eksauth.ExecCredentialV1beta1,
+		Command:         command,
+		Args:            []string{"get-token", "--cluster-name", clusterName},
+		InteractiveMode: clientcmdapi.IfAvailableExecInteractiveMode,
+	}
+	if opts.Region != "" {
+		exec.Args = append(exec.Args, "--region", opts.Region)
+	}
+	if opts.RoleARN != "" {
+		exec.Args = append(exec.Args, "--role-arn", opts.RoleARN)
+	}
+	if opts.ExternalID != "" {
+		exec.Args = append(exec.Args, "--external-id", opts.ExternalID)
+	}
+	if opts.MFASerial != "" {
+		exec.Args = append(exec.Args, "--mfa-serial", opts.MFASerial)
+	}
+	if opts.SSOLogin {
+		exec.Args = append(exec.Args, "--sso-login")
+	}
+	if opts.Profile != "" {
+		exec.Args = append(exec.Args, "--profile", opts.Profile)
+	}
+	if opts.AWSProfile != "" {
+		exec.Env = append(exec.Env, clientcmdapi.ExecEnvVar{Name: "AWS_PROFILE", Value: opts.AWSProfile})
+	}
+	return exec
+}
+
+// MergeKubeconfig merges the clusters, users and contexts of src into dst, replacing entries with the same name
+// and leaving every other entry untouched, like `aws eks update-kubeconfig`. The current context of dst is set
+// to the current context of src if src has one.
+func MergeKubeconfig(dst, src *clientcmdapi.Config) {
+	for name, cluster := range src.Clusters {
+		dst.Clusters[name] = cluster
+	}
+	for name, authInfo := range src.AuthInfos {
+		dst.AuthInfos[name] = authInfo
+	}
+	for name, kubeCtx := range src.Contexts {
+		dst.Contexts[name] = kubeCtx
+	}
+	if src.CurrentContext != "" {
+		dst.CurrentContext = src.CurrentContext
+	}
+}
+
+// DefaultKubeconfigPath returns the kubeconfig file updated by UpdateKubeconfig when no path is given,
+// the first file of $KUBECONFIG or ~/.kube/config.
+func DefaultKubeconfigPath() string {
+	return clientcmd.NewDefaultPathOptions().GetDefaultFilename()
+}
+
+// UpdateKubeconfig merges entry into the kubeconfig file at path (see MergeKubeconfig), creating it if it does not
+// exist. If path is empty DefaultKubeconfigPath is used. The file is written with mode 0600.
+func UpdateKubeconfig(path string, entry *clientcmdapi.Config) error {
+	if path == "" {
+		path = DefaultKubeconfigPath()
+	}
+	cfg, err := clientcmd.LoadFromFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		cfg = clientcmdapi.NewConfig()
+	} else if err != nil {
+		return err
+	}
+	MergeKubeconfig(cfg, entry)
+	return clientcmd.WriteToFile(*cfg, path)
+}
@@ -0,0 +1,84 @@
+package kube
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// MigrateExecConfig returns the `eks-auth get-token` exec plugin stanza (running command, DefaultExecCommand if
+// empty) equivalent to an `aws eks get-token` or `aws-iam-authenticator token` exec stanza, for the same cluster,
+// region, role and AWS profile. The environment of the stanza is kept. It returns false if exec is neither or
+// the cluster name cannot be determined, see ClusterDetailsFromConfig.
+func MigrateExecConfig(exec *clientcmdapi.ExecConfig, command string) (*clientcmdapi.ExecConfig, bool) {
+	if exec == nil || !isLegacyExecConfig(exec) {
+		return nil, false
+	}
+	var details ClusterDetails
+	applyExecConfig(&details, exec)
+	if details.Name == "" {
+		return nil, false
+	}
+	clusterName, region := details.ARN, ""
+	if clusterName == "" {
+		clusterName, region = details.Name, details.Region
+	}
+	migrated := newExecConfig(clusterName, KubeconfigOptions{Command: command, Region: region, RoleARN: details.RoleARN})
+	if exec.APIVersion == eksauth.ExecCredentialV1 {
+		migrated.APIVersion = exec.APIVersion
+	}
+	if exec.InteractiveMode != "" {
+		migrated.InteractiveMode = exec.InteractiveMode
+	}
+	migrated.Env = append([]clientcmdapi.ExecEnvVar(nil), exec.Env...)
+	if details.AWSProfile != "" && !hasExecEnv(exec, "AWS_PROFILE") {
+		migrated.Env = append(migrated.Env, clientcmdapi.ExecEnvVar{Name: "AWS_PROFILE", Value: details.AWSProfile})
+	}
+	return migrated, true
+}
+
+// MigrateKubeconfig replaces (in place) every `aws eks get-token` and `aws-iam-authenticator token` exec stanza of
+// the users of cfg with the `eks-auth get-token` stanza returned by MigrateExecConfig. It returns the sorted names
+// of the migrated users.
+func MigrateKubeconfig(cfg *clientcmdapi.Config, command string) []string {
+	var migrated []string
+	for name, authInfo := range cfg.AuthInfos {
+		if authInfo == nil {
+			continue
+		}
+		if exec, ok := MigrateExecConfig(authInfo.Exec, command); ok {
+			authInfo.Exec = exec
+			migrated = append(migrated, name)
+		}
+	}
+	sort.Strings(migrated)
+	return migrated
+}
+
+// isLegacyExecConfig reports if exec runs `aws eks get-token` or `aws-iam-authenticator token`.
+func isLegacyExecConfig(exec *clientcmdapi.ExecConfig) bool {
+	switch strings.TrimSuffix(filepath.Base(exec.Command), ".exe") {
+	case "aws":
+		for idx := 0; idx+1 < len(exec.Args); idx++ {
+			if exec.Args[idx] == "eks" && exec.Args[idx+1] == "get-token" {
+				return true
+			}
+		}
+	case "aws-iam-authenticator":
+		return contains(exec.Args, "token")
+	}
+	return false
+}
+
+// hasExecEnv reports if the environment variable is set by the exec stanza.
+func hasExecEnv(exec *clientcmdapi.ExecConfig, name string) bool {
+	for _, env := range exec.Env {
+		if env.Name == name {
+			return true
+		}
+	}
+	return false
+}
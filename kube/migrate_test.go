@@ -0,0 +1,54 @@
+package kube_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bored-engineer/aws-eks-auth/kube"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestMigrateKubeconfig(t *testing.T) {
+	cfg := clientcmdapi.NewConfig()
+	cfg.AuthInfos["aws"] = &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{
+		APIVersion: "client.authentication.k8s.io/v1beta1",
+		Command:    "aws",
+		Args:       []string{"--region", "us-west-2", "eks", "get-token", "--cluster-name", "eks-cluster-name", "--role-arn", "arn:aws:iam::123456789012:role/admin"},
+		Env:        []clientcmdapi.ExecEnvVar{{Name: "AWS_STS_REGIONAL_ENDPOINTS", Value: "regional"}},
+	}}
+	cfg.AuthInfos["authenticator"] = &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{
+		APIVersion: "client.authentication.k8s.io/v1alpha1",
+		Command:    "/usr/local/bin/aws-iam-authenticator",
+		Args:       []string{"token", "-i", "arn:aws:eks:eu-west-1:123456789012:cluster/other", "--profile", "dev"},
+	}}
+	cfg.AuthInfos["static"] = &clientcmdapi.AuthInfo{Token: "token"}
+	cfg.AuthInfos["other"] = &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: "gke-gcloud-auth-plugin"}}
+
+	if got, want := kube.MigrateKubeconfig(cfg, ""), []string{"authenticator", "aws"}; !slices.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	exec := cfg.AuthInfos["aws"].Exec
+	if want := []string{"get-token", "--cluster-name", "eks-cluster-name", "--region", "us-west-2", "--role-arn", "arn:aws:iam::123456789012:role/admin"}; exec.Command != kube.DefaultExecCommand || !slices.Equal(exec.Args, want) {
+		t.Errorf("got %s %q, want %s %q", exec.Command, exec.Args, kube.DefaultExecCommand, want)
+	}
+	if len(exec.Env) != 1 || exec.Env[0].Name != "AWS_STS_REGIONAL_ENDPOINTS" {
+		t.Errorf("environment not kept: %v", exec.Env)
+	}
+	exec = cfg.AuthInfos["authenticator"].Exec
+	if want := []string{"get-token", "--cluster-name", "arn:aws:eks:eu-west-1:123456789012:cluster/other"}; !slices.Equal(exec.Args, want) {
+		t.Errorf("got %q, want %q", exec.Args, want)
+	}
+	if exec.APIVersion != "client.authentication.k8s.io/v1beta1" {
+		t.Errorf("got apiVersion %s", exec.APIVersion)
+	}
+	if len(exec.Env) != 1 || exec.Env[0] != (clientcmdapi.ExecEnvVar{Name: "AWS_PROFILE", Value: "dev"}) {
+		t.Errorf("got environment %v, want AWS_PROFILE=dev", exec.Env)
+	}
+	if cfg.AuthInfos["other"].Exec.Command != "gke-gcloud-auth-plugin" {
+		t.Error("unrelated exec plugin was migrated")
+	}
+	// Migrated stanzas are not migrated again.
+	if got := kube.MigrateKubeconfig(cfg, ""); len(got) != 0 {
+		t.Errorf("got %q on the second migration", got)
+	}
+}
@@ -0,0 +1,60 @@
+package kube
+
+import (
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"golang.org/x/oauth2"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+)
+
+// WrapperFunc returns a transport.WrapperFunc that sets the Authorization header of every request using ts.
+// Requests rejected with 401 Unauthorized are retried once with a fresh token, see eksauth.Transport.
+func WrapperFunc(ts oauth2.TokenSource) transport.WrapperFunc {
+	return func(base http.RoundTripper) http.RoundTripper {
+		return eksauth.NewTransport(ts, base)
+	}
+}
+
+// WrapConfig configures restCfg to authenticate using tokens from ts.
+// client-go applies WrapTransport closest to the TLS transport, so the EKS token replaces any Authorization
+// header set by the other layers; to avoid confusion the other credentials of restCfg are cleared.
+func WrapConfig(restCfg *rest.Config, ts oauth2.TokenSource) {
+	clearCredentials(restCfg)
+	restCfg.Wrap(WrapperFunc(ts))
+}
+
+// clearCredentials clears the credentials of restCfg replaced by an EKS token.
+func clearCredentials(restCfg *rest.Config) {
+	restCfg.BearerToken = ""
+	restCfg.BearerTokenFile = ""
+	restCfg.Username = ""
+	restCfg.Password = ""
+	restCfg.ExecProvider = nil
+	restCfg.AuthProvider = nil
+}
+
+// impersonationKey is the eksauth.WithValue key of WithImpersonation.
+type impersonationKey struct{}
+
+// WithImpersonation returns an eksauth.Option making the rest.Config helpers of this package (WrapRestConfig,
+// RESTConfig, NewClientset...) impersonate a Kubernetes user and groups on top of the EKS token, so automation
+// authenticated as an IAM principal can act as a reduced-privilege identity. The IAM principal needs the RBAC
+// permission to impersonate it. Other constructors ignore it.
+func WithImpersonation(impersonate rest.ImpersonationConfig) eksauth.Option {
+	return eksauth.WithValue(impersonationKey{}, impersonate)
+}
+
+// WrapRestConfig creates a new oauth2.TokenSource from an aws.Config and an EKS cluster name and configures
+// restCfg to authenticate using it, see WrapConfig. The token source is returned so it can be shared.
+// The Impersonate field of restCfg is set if WithImpersonation is one of opts.
+func WrapRestConfig(cfg aws.Config, restCfg *rest.Config, clusterName string, opts ...eksauth.Option) oauth2.TokenSource {
+	ts := eksauth.NewFromConfig(cfg, clusterName, opts...)
+	WrapConfig(restCfg, ts)
+	if impersonate, ok := eksauth.OptionValue(opts, impersonationKey{}); ok {
+		restCfg.Impersonate = impersonate.(rest.ImpersonationConfig)
+	}
+	return ts
+}
@@ -0,0 +1,47 @@
+package kube_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bored-engineer/aws-eks-auth/eksauthtest"
+	"github.com/bored-engineer/aws-eks-auth/kube"
+	"k8s.io/client-go/rest"
+)
+
+func TestWrapRestConfigImpersonation(t *testing.T) {
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	defer srv.Close()
+	fake := eksauthtest.NewSTS()
+	defer fake.Close()
+
+	restCfg := &rest.Config{Host: srv.URL, BearerToken: "replaced"}
+	kube.WrapRestConfig(fake.Config(), restCfg, "eks-cluster-name", kube.WithImpersonation(rest.ImpersonationConfig{
+		UserName: "jane",
+		Groups:   []string{"viewers"},
+	}))
+	client, err := rest.HTTPClientFor(restCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(srv.URL + "/version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got := header.Get("Authorization"); !strings.HasPrefix(got, "Bearer k8s-aws-v1.") {
+		t.Errorf("Authorization: got %q, want an EKS token", got)
+	}
+	if got := header.Get("Impersonate-User"); got != "jane" {
+		t.Errorf("Impersonate-User: got %q, want %q", got, "jane")
+	}
+	if got := header.Values("Impersonate-Group"); len(got) != 1 || got[0] != "viewers" {
+		t.Errorf("Impersonate-Group: got %q, want [viewers]", got)
+	}
+}
@@ -0,0 +1,122 @@
+package kube
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// RESTClientGetter implements the genericclioptions.RESTClientGetter interface (k8s.io/cli-runtime) for a
+// rest.Config, so the Helm SDK (action.Configuration.Init) can target an EKS cluster without a kubeconfig file.
+type RESTClientGetter struct {
+	// Config is the (authenticated) rest.Config of the cluster, see RESTConfig.
+	Config *rest.Config
+	// Namespace is the default namespace, "default" if empty.
+	Namespace string
+
+	mu        sync.Mutex
+	discovery discovery.CachedDiscoveryInterface
+	mapper    meta.RESTMapper
+}
+
+// NewRESTClientGetter creates a RESTClientGetter for restCfg.
+func NewRESTClientGetter(restCfg *rest.Config, namespace string) *RESTClientGetter {
+	return &RESTClientGetter{Config: restCfg, Namespace: namespace}
+}
+
+// NewRESTClientGetterForCluster creates a RESTClientGetter for the EKS cluster, see RESTConfig.
+func NewRESTClientGetterForCluster(ctx context.Context, cfg aws.Config, clusterName, namespace string, opts ...eksauth.Option) (*RESTClientGetter, error) {
+	restCfg, err := RESTConfig(ctx, cfg, clusterName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewRESTClientGetter(restCfg, namespace), nil
+}
+
+// ToRESTConfig returns a copy of Config.
+func (g *RESTClientGetter) ToRESTConfig() (*rest.Config, error) {
+	return rest.CopyConfig(g.Config), nil
+}
+
+// ToDiscoveryClient returns a discovery client for the cluster, cached in memory.
+func (g *RESTClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.discovery != nil {
+		return g.discovery, nil
+	}
+	restCfg := rest.CopyConfig(g.Config)
+	// Discovery of clusters with many CRDs makes a burst of requests, like kubectl raise the limit.
+	restCfg.Burst = 300
+	client, err := discovery.NewDiscoveryClientForConfig(restCfg)
+	if err != nil {
+		return nil, err
+	}
+	g.discovery = memory.NewMemCacheClient(client)
+	return g.discovery, nil
+}
+
+// ToRESTMapper returns a deferred discovery RESTMapper for the cluster which expands resource shortcuts.
+func (g *RESTClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	client, err := g.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.mapper == nil {
+		g.mapper = restmapper.NewShortcutExpander(restmapper.NewDeferredDiscoveryRESTMapper(client), client, nil)
+	}
+	return g.mapper, nil
+}
+
+// ToRawKubeConfigLoader returns a clientcmd.ClientConfig for Config and Namespace.
+func (g *RESTClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return clientConfig{g}
+}
+
+// clientConfig implements clientcmd.ClientConfig for a RESTClientGetter.
+type clientConfig struct {
+	g *RESTClientGetter
+}
+
+// RawConfig returns a kubeconfig for the cluster and namespace, it does not contain the credentials.
+func (c clientConfig) RawConfig() (clientcmdapi.Config, error) {
+	namespace, _, _ := c.Namespace()
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters["eks"] = &clientcmdapi.Cluster{
+		Server:                   c.g.Config.Host,
+		CertificateAuthorityData: c.g.Config.CAData,
+	}
+	cfg.AuthInfos["eks"] = clientcmdapi.NewAuthInfo()
+	cfg.Contexts["eks"] = &clientcmdapi.Context{Cluster: "eks", AuthInfo: "eks", Namespace: namespace}
+	cfg.CurrentContext = "eks"
+	return *cfg, nil
+}
+
+// ClientConfig returns a copy of the rest.Config.
+func (c clientConfig) ClientConfig() (*rest.Config, error) {
+	return c.g.ToRESTConfig()
+}
+
+// Namespace returns the namespace, it is overridden if the Namespace of the RESTClientGetter is set.
+func (c clientConfig) Namespace() (string, bool, error) {
+	if c.g.Namespace != "" {
+		return c.g.Namespace, true, nil
+	}
+	return "default", false, nil
+}
+
+// ConfigAccess returns the default kubeconfig loading rules, the generated config is never persisted.
+func (c clientConfig) ConfigAccess() clientcmd.ConfigAccess {
+	return clientcmd.NewDefaultPathOptions()
+}
@@ -0,0 +1,200 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"golang.org/x/oauth2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Keys of the Secret data written by a SecretPublisher.
+const (
+	SecretTokenKey      = "token"
+	SecretExpiryKey     = "expiry"
+	SecretKubeconfigKey = "kubeconfig"
+)
+
+// SecretPublisher keeps a fresh token in a Kubernetes Secret, ie for GitOps agents or cluster registration
+// controllers publishing credentials of an EKS cluster to workloads of another cluster. The Secret is created if
+// missing and updated EarlyExpiry before the token expires. The client needs the "get", "create" and "update"
+// permissions on the Secret. When several replicas run, either set IsLeader or use RunWithLeaderElection so only
+// the leader writes the Secret.
+type SecretPublisher struct {
+	Source    oauth2.TokenSource
+	Client    kubernetes.Interface
+	Namespace string
+	Name      string
+	// Data returns the Secret data for a token, TokenSecretData if nil (see KubeconfigSecretData).
+	Data func(t *oauth2.Token) (map[string][]byte, error)
+	// Labels and Annotations are set on the Secret.
+	Labels      map[string]string
+	Annotations map[string]string
+	// EarlyExpiry is how long before expiry the Secret is updated, eksauth.DefaultEarlyExpiry if zero.
+	EarlyExpiry time.Duration
+	// RetryInterval is the delay before retrying a failed update (or checking IsLeader again),
+	// eksauth.DefaultRefreshRetryInterval if zero.
+	RetryInterval time.Duration
+	// IsLeader reports if this replica may write the Secret, every replica writes it if nil.
+	IsLeader func() bool
+	// OnError is called when a refresh or update fails, it may be nil.
+	OnError func(err error)
+}
+
+// TokenSecretData returns the access token (SecretTokenKey) and its RFC 3339 expiry (SecretExpiryKey).
+func TokenSecretData(t *oauth2.Token) (map[string][]byte, error) {
+	return map[string][]byte{
+		SecretTokenKey:  []byte(t.AccessToken),
+		SecretExpiryKey: []byte(t.Expiry.UTC().Format(time.RFC3339)),
+	}, nil
+}
+
+// KubeconfigSecretData returns a SecretPublisher.Data function storing a kubeconfig for the cluster embedding the
+// token (SecretKubeconfigKey), see NewKubeconfig. The token and expiry keys of TokenSecretData are also set.
+func KubeconfigSecretData(info *eksauth.ClusterInfo, opts KubeconfigOptions) func(t *oauth2.Token) (map[string][]byte, error) {
+	return func(t *oauth2.Token) (map[string][]byte, error) {
+		opts := opts
+		opts.Token = t.AccessToken
+		kubeconfig, err := clientcmd.Write(*NewKubeconfig(info, opts))
+		if err != nil {
+			return nil, err
+		}
+		data, _ := TokenSecretData(t)
+		data[SecretKubeconfigKey] = kubeconfig
+		return data, nil
+	}
+}
+
+// Publish fetches a token from Source and writes it to the Secret once, regardless of IsLeader.
+func (p *SecretPublisher) Publish(ctx context.Context) (*oauth2.Token, error) {
+	t, err := eksauth.TokenWithContext(ctx, p.Source)
+	if err != nil {
+		return nil, err
+	}
+	encode := p.Data
+	if encode == nil {
+		encode = TokenSecretData
+	}
+	data, err := encode(t)
+	if err != nil {
+		return nil, err
+	}
+	secrets := p.Client.CoreV1().Secrets(p.Namespace)
+	secret, err := secrets.Get(ctx, p.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: p.Name, Namespace: p.Namespace},
+			Type:       corev1.SecretTypeOpaque,
+		}
+		p.apply(secret, data)
+		if _, err := secrets.Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+			return nil, fmt.Errorf("kube: failed to create secret %s/%s: %w", p.Namespace, p.Name, err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("kube: failed to get secret %s/%s: %w", p.Namespace, p.Name, err)
+	default:
+		p.apply(secret, data)
+		if _, err := secrets.Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+			return nil, fmt.Errorf("kube: failed to update secret %s/%s: %w", p.Namespace, p.Name, err)
+		}
+	}
+	return t, nil
+}
+
+// apply sets the data, labels and annotations of secret.
+func (p *SecretPublisher) apply(secret *corev1.Secret, data map[string][]byte) {
+	secret.Data = data
+	if len(p.Labels) > 0 && secret.Labels == nil {
+		secret.Labels = make(map[string]string, len(p.Labels))
+	}
+	for k, v := range p.Labels {
+		secret.Labels[k] = v
+	}
+	if len(p.Annotations) > 0 && secret.Annotations == nil {
+		secret.Annotations = make(map[string]string, len(p.Annotations))
+	}
+	for k, v := range p.Annotations {
+		secret.Annotations[k] = v
+	}
+}
+
+// Run publishes the token then keeps the Secret fresh until ctx is done, it returns nil once ctx is done.
+// Failures are reported to OnError and retried after RetryInterval. Nothing is written while IsLeader is false.
+func (p *SecretPublisher) Run(ctx context.Context) error {
+	if p.Client == nil || p.Name == "" {
+		return errors.New("kube: SecretPublisher needs a Client and a Name")
+	}
+	earlyExpiry := p.EarlyExpiry
+	if earlyExpiry == 0 {
+		earlyExpiry = eksauth.DefaultEarlyExpiry
+	}
+	retryInterval := p.RetryInterval
+	if retryInterval == 0 {
+		retryInterval = eksauth.DefaultRefreshRetryInterval
+	}
+	for {
+		wait := retryInterval
+		if p.IsLeader == nil || p.IsLeader() {
+			t, err := p.Publish(ctx)
+			switch {
+			case ctx.Err() != nil:
+				return nil
+			case err != nil:
+				if p.OnError != nil {
+					p.OnError(err)
+				}
+			case !t.Expiry.IsZero():
+				wait = max(time.Until(t.Expiry)-earlyExpiry, time.Second)
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// RunWithLeaderElection runs the publisher only while this replica holds the Lease leaseName (in Namespace),
+// identity must be unique per replica (ie the pod name). It returns once ctx is done and the Lease is released.
+// The client also needs the "get", "create" and "update" permissions on the Lease (coordination.k8s.io).
+func (p *SecretPublisher) RunWithLeaderElection(ctx context.Context, leaseName, identity string) error {
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Name: leaseName, Namespace: p.Namespace},
+			Client:     p.Client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		LeaseDuration:   15 * time.Second,
+		RenewDeadline:   10 * time.Second,
+		RetryPeriod:     2 * time.Second,
+		ReleaseOnCancel: true,
+		Name:            leaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				_ = p.Run(ctx)
+			},
+			OnStoppedLeading: func() {},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("kube: leader election: %w", err)
+	}
+	// Run returns when the lease is lost, campaign again until ctx is done.
+	for ctx.Err() == nil {
+		elector.Run(ctx)
+	}
+	return nil
+}
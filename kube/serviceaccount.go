@@ -0,0 +1,54 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultServiceAccountAudience is the audience of the tokens requested by ServiceAccountTokenRetriever.
+const DefaultServiceAccountAudience = "sts.amazonaws.com"
+
+// ServiceAccountTokenRetriever is a stscreds.IdentityTokenRetriever requesting a short-lived token for a Kubernetes
+// service account with the TokenRequest API, ie a controller assuming IAM roles on behalf of the service accounts
+// of a cluster, see eksauth.WebIdentityCredentials. The client needs the "create" permission on the
+// serviceaccounts/token subresource.
+type ServiceAccountTokenRetriever struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Name      string
+	// Audience of the token, DefaultServiceAccountAudience if empty.
+	Audience string
+	// ExpirationSeconds is the requested validity of the token, the API server default (1 hour) if zero.
+	ExpirationSeconds int64
+	// Context is used for the TokenRequest, context.Background() if nil.
+	Context context.Context
+}
+
+// GetIdentityToken implements the stscreds.IdentityTokenRetriever interface.
+func (r *ServiceAccountTokenRetriever) GetIdentityToken() ([]byte, error) {
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	audience := r.Audience
+	if audience == "" {
+		audience = DefaultServiceAccountAudience
+	}
+	req := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences: []string{audience},
+		},
+	}
+	if r.ExpirationSeconds != 0 {
+		req.Spec.ExpirationSeconds = &r.ExpirationSeconds
+	}
+	resp, err := r.Client.CoreV1().ServiceAccounts(r.Namespace).CreateToken(ctx, r.Name, req, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("kube: failed to request a token for service account %s/%s: %w", r.Namespace, r.Name, err)
+	}
+	return []byte(resp.Status.Token), nil
+}
@@ -0,0 +1,87 @@
+package kube
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"golang.org/x/oauth2"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+)
+
+// ResettableTokenSource adapts an oauth2.TokenSource to transport.ResettableTokenSource, the contract of the
+// client-go bearer token round tripper: when a request is rejected with 401 Unauthorized client-go calls
+// ResetTokenOlderThan with the start of the request, which invalidates (see eksauth.Invalidate) the token if it
+// was minted before.
+type ResettableTokenSource struct {
+	Source oauth2.TokenSource
+
+	mu     sync.Mutex
+	last   string
+	issued time.Time
+}
+
+var _ transport.ResettableTokenSource = (*ResettableTokenSource)(nil)
+
+// NewResettableTokenSource wraps ts in a ResettableTokenSource.
+func NewResettableTokenSource(ts oauth2.TokenSource) *ResettableTokenSource {
+	return &ResettableTokenSource{Source: ts}
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (s *ResettableTokenSource) Token() (*oauth2.Token, error) {
+	return s.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the eksauth.ContextTokenSource interface.
+func (s *ResettableTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	t, err := eksauth.TokenWithContext(ctx, s.Source)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.AccessToken != s.last {
+		s.last, s.issued = t.AccessToken, time.Now()
+		// Cached tokens were minted when they were signed, not when first seen.
+		if p, ok := eksauth.TokenProvenance(t); ok && !p.SigningTime.IsZero() {
+			s.issued = p.SigningTime
+		}
+	}
+	return t, nil
+}
+
+// ResetTokenOlderThan implements the transport.ResettableTokenSource interface.
+func (s *ResettableTokenSource) ResetTokenOlderThan(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last != "" && s.issued.Before(t) {
+		s.last, s.issued = "", time.Time{}
+		eksauth.Invalidate(s.Source)
+	}
+}
+
+// Invalidate implements the eksauth.Invalidator interface.
+func (s *ResettableTokenSource) Invalidate() {
+	s.mu.Lock()
+	s.last, s.issued = "", time.Time{}
+	s.mu.Unlock()
+	eksauth.Invalidate(s.Source)
+}
+
+// TransportWrapperFunc is WrapperFunc using the client-go bearer token round tripper
+// (transport.ResettableTokenSourceWrapTransport) instead of eksauth.Transport: requests already carrying an
+// Authorization header pass through untouched and a 401 Unauthorized resets the token without retrying the
+// request, like the credentials client-go configures itself. It composes with the impersonation, user-agent and
+// debug round trippers client-go stacks on the transport.
+func TransportWrapperFunc(ts oauth2.TokenSource) transport.WrapperFunc {
+	return transport.ResettableTokenSourceWrapTransport(NewResettableTokenSource(ts))
+}
+
+// WrapConfigTransport is WrapConfig using TransportWrapperFunc.
+func WrapConfigTransport(restCfg *rest.Config, ts oauth2.TokenSource) {
+	clearCredentials(restCfg)
+	restCfg.Wrap(TransportWrapperFunc(ts))
+}
@@ -0,0 +1,141 @@
+// Package webhook implements the Kubernetes TokenReview API (a webhook token authenticator) backed by
+// eksauth.Verifier and the awsauth mapping, so self-hosted (ie kubeadm) clusters can authenticate IAM principals
+// with the tokens generated by eksauth, aws eks get-token or aws-iam-authenticator.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"github.com/bored-engineer/aws-eks-auth/kube/awsauth"
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+// maxRequestSize bounds the size of a TokenReview request body.
+const maxRequestSize = 1 << 20
+
+// Verifier verifies a token, *eksauth.Verifier and *eksauth.CachingVerifier implement it.
+type Verifier interface {
+	Verify(ctx context.Context, token string) (*eksauth.Identity, error)
+}
+
+// Mapper maps a verified identity to a Kubernetes user, *awsauth.Mapping and *awsauth.Watcher implement it.
+type Mapper interface {
+	Map(identity *eksauth.Identity) (*awsauth.User, error)
+}
+
+// Handler is an http.Handler serving TokenReview requests (authentication.k8s.io/v1 and v1beta1), configure the
+// API server with --authentication-token-webhook-config-file pointing at it. Rejected tokens are answered with an
+// unauthenticated status, malformed requests with 400 Bad Request and STS failures (eksauth.ErrSTSUnavailable) with
+// 503 Service Unavailable, so the API server does not cache them as authentication failures.
+type Handler struct {
+	Verifier Verifier
+	Mapper   Mapper
+	// Logger logs rejected tokens (info) if non-nil.
+	Logger *slog.Logger
+}
+
+// NewHandler creates a Handler verifying tokens with verifier and mapping identities with mapper.
+func NewHandler(verifier Verifier, mapper Mapper) *Handler {
+	return &Handler{Verifier: verifier, Mapper: mapper}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	var review authenticationv1.TokenReview
+	if err := json.Unmarshal(body, &review); err != nil {
+		http.Error(w, "invalid TokenReview: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if review.APIVersion == "" {
+		review.APIVersion = authenticationv1.SchemeGroupVersion.String()
+	}
+	if review.Kind == "" {
+		review.Kind = "TokenReview"
+	}
+	status, err := h.review(r.Context(), review.Spec.Token)
+	if errors.Is(err, eksauth.ErrSTSUnavailable) {
+		http.Error(w, status.Error, http.StatusServiceUnavailable)
+		return
+	}
+	review.Status = status
+	// The API server ignores the spec of the response, do not echo the token back
+	review.Spec = authenticationv1.TokenReviewSpec{}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(&review)
+}
+
+// Review verifies and maps a token, returning the status of the TokenReview.
+func (h *Handler) Review(ctx context.Context, token string) authenticationv1.TokenReviewStatus {
+	status, _ := h.review(ctx, token)
+	return status
+}
+
+// review implements Review, also returning the error of a rejected token.
+func (h *Handler) review(ctx context.Context, token string) (authenticationv1.TokenReviewStatus, error) {
+	identity, err := h.Verifier.Verify(ctx, token)
+	if err != nil {
+		return h.reject(ctx, err), err
+	}
+	user, err := h.Mapper.Map(identity)
+	if err != nil {
+		return h.reject(ctx, err), err
+	}
+	return authenticationv1.TokenReviewStatus{
+		Authenticated: true,
+		User: authenticationv1.UserInfo{
+			Username: user.Username,
+			UID:      UID(identity),
+			Groups:   user.Groups,
+			Extra:    Extra(token, identity),
+		},
+	}, nil
+}
+
+// reject logs the error and returns an unauthenticated status.
+func (h *Handler) reject(ctx context.Context, err error) authenticationv1.TokenReviewStatus {
+	if h.Logger != nil {
+		h.Logger.InfoContext(ctx, "rejected token", "error", err)
+	}
+	return authenticationv1.TokenReviewStatus{Error: err.Error()}
+}
+
+// UID returns the UID of a Kubernetes user mapped from identity, matching aws-iam-authenticator
+// ("aws-iam-authenticator:<account>:<user ID>") so audit logs and RBAC bindings stay compatible.
+func UID(identity *eksauth.Identity) string {
+	return fmt.Sprintf("aws-iam-authenticator:%s:%s", identity.Account, identity.UserID)
+}
+
+// Extra returns the extra fields of a Kubernetes user mapped from identity, using the keys of aws-iam-authenticator.
+func Extra(token string, identity *eksauth.Identity) map[string]authenticationv1.ExtraValue {
+	extra := map[string]authenticationv1.ExtraValue{
+		"arn":          {identity.ARN},
+		"canonicalArn": {eksauth.PrincipalARN(identity.ARN)},
+	}
+	if principalID, sessionName, ok := strings.Cut(identity.UserID, ":"); ok {
+		extra["principalId"] = authenticationv1.ExtraValue{principalID}
+		extra["sessionName"] = authenticationv1.ExtraValue{sessionName}
+	} else {
+		extra["principalId"] = authenticationv1.ExtraValue{identity.UserID}
+	}
+	if parsed, err := eksauth.ParseToken(token); err == nil && parsed.AccessKeyID != "" {
+		extra["accessKeyId"] = authenticationv1.ExtraValue{parsed.AccessKeyID}
+	}
+	return extra
+}
@@ -0,0 +1,141 @@
+package webhook_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"github.com/bored-engineer/aws-eks-auth/kube/awsauth"
+	"github.com/bored-engineer/aws-eks-auth/kube/webhook"
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+// verifierFunc implements webhook.Verifier.
+type verifierFunc func(ctx context.Context, token string) (*eksauth.Identity, error)
+
+func (f verifierFunc) Verify(ctx context.Context, token string) (*eksauth.Identity, error) {
+	return f(ctx, token)
+}
+
+// identities are the identities of the tokens accepted by testVerifier.
+var identities = map[string]*eksauth.Identity{
+	"admin": {
+		Account: "111122223333",
+		ARN:     "arn:aws:sts::111122223333:assumed-role/admin/jane",
+		UserID:  "AROAEXAMPLE:jane",
+	},
+	"unmapped": {
+		Account: "111122223333",
+		ARN:     "arn:aws:sts::111122223333:assumed-role/other/jane",
+		UserID:  "AROAOTHER:jane",
+	},
+}
+
+var testVerifier = verifierFunc(func(ctx context.Context, token string) (*eksauth.Identity, error) {
+	if token == "unavailable" {
+		return nil, fmt.Errorf("%w: returned 503 Service Unavailable", eksauth.ErrSTSUnavailable)
+	}
+	if identity, ok := identities[token]; ok {
+		return identity, nil
+	}
+	return nil, fmt.Errorf("%w: sts:GetCallerIdentity returned SignatureDoesNotMatch", eksauth.ErrInvalidToken)
+})
+
+var testMapping = &awsauth.Mapping{Roles: []awsauth.RoleMapping{{
+	RoleARN:  "arn:aws:iam::111122223333:role/admin",
+	Username: "admin:{{SessionName}}",
+	Groups:   []string{"system:masters"},
+}}}
+
+// review posts a TokenReview for the token to h.
+func review(t *testing.T, h http.Handler, token string) (*httptest.ResponseRecorder, *authenticationv1.TokenReview) {
+	t.Helper()
+	body, err := json.Marshal(&authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/authenticate", bytes.NewReader(body)))
+	var out authenticationv1.TokenReview
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w, &out
+}
+
+func TestHandlerAuthenticated(t *testing.T) {
+	w, out := review(t, webhook.NewHandler(testVerifier, testMapping), "admin")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusOK)
+	}
+	if out.APIVersion != "authentication.k8s.io/v1" || out.Kind != "TokenReview" {
+		t.Errorf("got %s %s", out.APIVersion, out.Kind)
+	}
+	if out.Spec.Token != "" {
+		t.Error("the token was echoed back")
+	}
+	want := authenticationv1.TokenReviewStatus{
+		Authenticated: true,
+		User: authenticationv1.UserInfo{
+			Username: "admin:jane",
+			UID:      "aws-iam-authenticator:111122223333:AROAEXAMPLE:jane",
+			Groups:   []string{"system:masters"},
+			Extra: map[string]authenticationv1.ExtraValue{
+				"arn":          {"arn:aws:sts::111122223333:assumed-role/admin/jane"},
+				"canonicalArn": {"arn:aws:iam::111122223333:role/admin"},
+				"principalId":  {"AROAEXAMPLE"},
+				"sessionName":  {"jane"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(out.Status, want) {
+		t.Errorf("got %+v, want %+v", out.Status, want)
+	}
+}
+
+func TestHandlerUnauthenticated(t *testing.T) {
+	for _, token := range []string{"invalid", "unmapped"} {
+		t.Run(token, func(t *testing.T) {
+			w, out := review(t, webhook.NewHandler(testVerifier, testMapping), token)
+			if w.Code != http.StatusOK {
+				t.Fatalf("got status %d, want %d", w.Code, http.StatusOK)
+			}
+			if out.Status.Authenticated || out.Status.User.Username != "" {
+				t.Errorf("got authenticated status %+v", out.Status)
+			}
+			if out.Status.Error == "" {
+				t.Error("got an empty error")
+			}
+		})
+	}
+}
+
+func TestHandlerSTSUnavailable(t *testing.T) {
+	w, _ := review(t, webhook.NewHandler(testVerifier, testMapping), "unavailable")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestHandlerInvalidRequests(t *testing.T) {
+	h := webhook.NewHandler(testVerifier, testMapping)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/authenticate", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: got status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/authenticate", strings.NewReader("{")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid JSON: got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
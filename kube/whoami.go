@@ -0,0 +1,30 @@
+package kube
+
+import (
+	"context"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// WhoAmI returns the Kubernetes user (username, UID, groups and extra) the cluster maps the credentials of client to,
+// using the SelfSubjectReview API (Kubernetes 1.28+). This confirms how an IAM principal is mapped (by the aws-auth
+// ConfigMap or access entries) before debugging RBAC.
+func WhoAmI(ctx context.Context, client kubernetes.Interface) (*authenticationv1.UserInfo, error) {
+	review, err := client.AuthenticationV1().SelfSubjectReviews().Create(ctx, &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &review.Status.UserInfo, nil
+}
+
+// WhoAmIForConfig is like WhoAmI but creates the client from restCfg, see RESTConfig.
+func WhoAmIForConfig(ctx context.Context, restCfg *rest.Config) (*authenticationv1.UserInfo, error) {
+	client, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, err
+	}
+	return WhoAmI(ctx, client)
+}
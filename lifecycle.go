@@ -0,0 +1,92 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// Lifecycle is implemented by long-lived components that must be started and cleanly shut down.
+type Lifecycle interface {
+	// Start starts any background work, it must not block once started.
+	Start(ctx context.Context) error
+	// Close stops all background work and releases resources, waiting for goroutines to exit.
+	Close() error
+}
+
+// LifecycleManager starts a set of components in order and closes them in reverse order.
+type LifecycleManager struct {
+	mu         sync.Mutex
+	components []Lifecycle
+	started    int
+}
+
+// NewLifecycleManager creates a LifecycleManager for the components, which are started in the order provided.
+func NewLifecycleManager(components ...Lifecycle) *LifecycleManager {
+	return &LifecycleManager{components: components}
+}
+
+// Add adds a component, it is started by the next call to Start.
+func (m *LifecycleManager) Add(component Lifecycle) {
+	m.mu.Lock()
+	m.components = append(m.components, component)
+	m.mu.Unlock()
+}
+
+// Start starts every component that has not been started yet. If a component fails to start,
+// the components started by this call are closed (in reverse order) and the error is returned.
+func (m *LifecycleManager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for idx := m.started; idx < len(m.components); idx++ {
+		if err := m.components[idx].Start(ctx); err != nil {
+			return errors.Join(err, closeAll(m.components[m.started:idx]))
+		}
+	}
+	m.started = len(m.components)
+	return nil
+}
+
+// Close closes every started component in reverse order, returning all errors joined together.
+func (m *LifecycleManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err := closeAll(m.components[:m.started])
+	m.started = 0
+	return err
+}
+
+// closeAll closes the components in reverse order.
+func closeAll(components []Lifecycle) error {
+	var errs []error
+	for idx := len(components) - 1; idx >= 0; idx-- {
+		if err := components[idx].Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CloseableTokenSource is a token source holding resources (ie a background refresh goroutine) that must be
+// released with Close once it is no longer used.
+type CloseableTokenSource interface {
+	oauth2.TokenSource
+	io.Closer
+}
+
+// Close closes ts if it implements io.Closer, it is a no-op otherwise. Wrapper token sources in this package
+// forward Close to the source they wrap, so closing the outermost wrapper releases everything it holds.
+func Close(ts oauth2.TokenSource) error {
+	return closeTokenSource(ts)
+}
+
+// closeTokenSource closes the token source if it implements io.Closer.
+func closeTokenSource(ts any) error {
+	if closer, ok := ts.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
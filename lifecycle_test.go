@@ -0,0 +1,164 @@
+package eksauth_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"github.com/bored-engineer/aws-eks-auth/eksauthtest"
+	"golang.org/x/oauth2"
+)
+
+// checkGoroutines fails the test if goroutines started during the test are still running once it completes.
+func checkGoroutines(t *testing.T) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		deadline := time.Now().Add(2 * time.Second)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				buf := make([]byte, 1<<20)
+				t.Errorf("%d goroutines leaked:\n%s", runtime.NumGoroutine()-before, buf[:runtime.Stack(buf, true)])
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
+
+// component is a Lifecycle recording its calls in log.
+type component struct {
+	name     string
+	log      *[]string
+	startErr error
+}
+
+func (c *component) Start(ctx context.Context) error {
+	*c.log = append(*c.log, "start "+c.name)
+	return c.startErr
+}
+
+func (c *component) Close() error {
+	*c.log = append(*c.log, "close "+c.name)
+	return nil
+}
+
+func TestLifecycleManagerOrder(t *testing.T) {
+	var log []string
+	m := eksauth.NewLifecycleManager(&component{name: "a", log: &log}, &component{name: "b", log: &log})
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"start a", "start b", "close b", "close a"}; !slices.Equal(log, want) {
+		t.Errorf("got %q, want %q", log, want)
+	}
+}
+
+func TestLifecycleManagerStartFailure(t *testing.T) {
+	var log []string
+	errStart := errors.New("start failed")
+	m := eksauth.NewLifecycleManager(
+		&component{name: "a", log: &log},
+		&component{name: "b", log: &log},
+		&component{name: "c", log: &log, startErr: errStart},
+	)
+	if err := m.Start(context.Background()); !errors.Is(err, errStart) {
+		t.Fatalf("got %v, want %v", err, errStart)
+	}
+	if want := []string{"start a", "start b", "start c", "close b", "close a"}; !slices.Equal(log, want) {
+		t.Errorf("got %q, want %q", log, want)
+	}
+}
+
+func TestRefreshingTokenSourceCloseReleasesGoroutine(t *testing.T) {
+	checkGoroutines(t)
+	ts := eksauth.NewRefreshingTokenSource(eksauthtest.NewStatic("token", time.Now().Add(time.Hour)))
+	m := eksauth.NewLifecycleManager(ts)
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// The last token remains cached after Close.
+	if tok, err := ts.Token(); err != nil || tok.AccessToken != "token" {
+		t.Errorf("got %v, %v", tok, err)
+	}
+}
+
+func TestRefreshingTokenSourceContextReleasesGoroutine(t *testing.T) {
+	checkGoroutines(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	ts := eksauth.NewRefreshingTokenSource(eksauthtest.NewStatic("token", time.Now().Add(time.Hour)))
+	if err := ts.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	ts.Stop()
+}
+
+func TestRegistryCloseReleasesGoroutines(t *testing.T) {
+	checkGoroutines(t)
+	ctx := context.Background()
+	r := eksauth.NewRegistry(func(name string) (oauth2.TokenSource, error) {
+		ts := eksauth.NewRefreshingTokenSource(eksauthtest.NewStatic(name, time.Now().Add(time.Hour)))
+		return ts, ts.Start(ctx)
+	})
+	for _, name := range []string{"a", "b", "c"} {
+		if _, err := r.Get(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := eksauth.NewLifecycleManager(r)
+	if err := m.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTokenServerReleasesGoroutines(t *testing.T) {
+	checkGoroutines(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	path := filepath.Join(t.TempDir(), "eks-auth.sock")
+	srv := eksauth.NewTokenServer(eksauthtest.NewStatic("token", time.Now().Add(time.Hour)), "eks-cluster-name")
+	done := make(chan error, 1)
+	go func() { done <- srv.ListenAndServe(ctx, path) }()
+	var conn net.Conn
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		var err error
+		if conn, err = net.Dial("unix", path); err == nil {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("{}\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	// The open connection must not keep the server running.
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("ListenAndServe did not return after the context was canceled")
+	}
+}
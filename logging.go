@@ -0,0 +1,40 @@
+package eksauth
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// logToken logs a generated token, requested is the expiry before it was clamped to the credential or signature expiry.
+func logToken(ctx context.Context, logger *slog.Logger, provenance *Provenance, requested time.Time) {
+	if logger == nil {
+		return
+	}
+	if provenance.Expiry.Before(requested) {
+		logger.InfoContext(ctx, "eksauth: token expiry clamped to credential or signature expiry",
+			slog.String("cluster", provenance.ClusterName),
+			slog.Time("requested_expiry", requested),
+			slog.Time("credentials_expire", provenance.CredentialsExpire),
+		)
+	}
+	logger.DebugContext(ctx, "eksauth: generated token",
+		slog.String("cluster", provenance.ClusterName),
+		slog.String("region", provenance.Region),
+		slog.String("credential_source", strings.Join(provenance.CredentialSources, credentialSourceSeparator)),
+		slog.Time("expiry", provenance.Expiry),
+		slog.Duration("lifetime", time.Until(provenance.Expiry)),
+	)
+}
+
+// logError logs a failure to generate a token.
+func logError(ctx context.Context, logger *slog.Logger, clusterName string, err error) {
+	if logger == nil {
+		return
+	}
+	logger.InfoContext(ctx, "eksauth: failed to generate token",
+		slog.String("cluster", clusterName),
+		slog.Any("error", err),
+	)
+}
@@ -0,0 +1,78 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"golang.org/x/oauth2"
+)
+
+// Manager hands out (and caches) per-cluster token sources that share a single sts.PresignClient,
+// so the credentials of the client are only resolved (and cached) once regardless of the number of clusters.
+// The embedded Registry is keyed by cluster name and evicts the least recently used clusters beyond MaxEntries.
+type Manager struct {
+	*Registry[string]
+	// Client is the sts.PresignClient shared by every cluster.
+	Client *sts.PresignClient
+}
+
+// NewManager creates a Manager from a sts.PresignClient, opts are applied to every cluster's token source.
+// maxEntries is the maximum number of cached clusters, zero is unlimited.
+func NewManager(client *sts.PresignClient, maxEntries int, opts ...Option) *Manager {
+	env := loadEnv()
+	return newManager(client, maxEntries, env, newOptions(env, opts))
+}
+
+// newManager creates a Manager from parsed options, which may have been filled in by presignClientFromConfig.
+func newManager(client *sts.PresignClient, maxEntries int, env Env, o *options) *Manager {
+	m := &Manager{Client: client}
+	m.Registry = NewRegistry(func(clusterName string) (oauth2.TokenSource, error) {
+		return newFromPresignClient(m.Client, clusterName, env, o), nil
+	})
+	m.MaxEntries = maxEntries
+	return m
+}
+
+// NewManagerFromConfig creates a Manager from an aws.Config like NewFromConfig.
+// The credentials of cfg are wrapped in an aws.CredentialsCache (if they are not already) so they are shared by every cluster.
+func NewManagerFromConfig(cfg aws.Config, maxEntries int, opts ...Option) *Manager {
+	if cfg.Credentials != nil && !aws.IsCredentialsProvider(cfg.Credentials, (*aws.CredentialsCache)(nil)) {
+		cfg.Credentials = aws.NewCredentialsCache(cfg.Credentials)
+	}
+	env := loadEnv()
+	o := newOptions(env, opts)
+	return newManager(presignClientFromConfig(cfg, env, o), maxEntries, env, o)
+}
+
+// TokenSource returns the (cached) token source of the named cluster.
+func (m *Manager) TokenSource(clusterName string) oauth2.TokenSource {
+	// The constructor never fails so neither does Get.
+	ts, _ := m.Get(clusterName)
+	return ts
+}
+
+// TokensFor returns a token for each of the named clusters in one pass, ie for fleet controllers refreshing many
+// clusters at once. The credentials and signer of Client are resolved once and shared by every token, cached
+// tokens that are still valid are reused. Tokens of the clusters that succeeded are returned along with the
+// errors (joined) of those that failed.
+func (m *Manager) TokensFor(ctx context.Context, clusterNames []string) (map[string]*oauth2.Token, error) {
+	tokens := make(map[string]*oauth2.Token, len(clusterNames))
+	var errs []error
+	for _, clusterName := range clusterNames {
+		if _, ok := tokens[clusterName]; ok {
+			continue
+		}
+		t, err := TokenWithContext(ctx, m.TokenSource(clusterName))
+		if err != nil {
+			if ctx.Err() != nil {
+				return tokens, ctx.Err()
+			}
+			errs = append(errs, err)
+			continue
+		}
+		tokens[clusterName] = t
+	}
+	return tokens, errors.Join(errs...)
+}
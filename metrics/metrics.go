@@ -0,0 +1,141 @@
+// Package metrics instruments eksauth token sources with Prometheus metrics.
+//
+//	m, err := metrics.New(prometheus.DefaultRegisterer)
+//	ts := eksauth.NewFromConfig(cfg, "eks-cluster-name", m.Option())
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/oauth2"
+)
+
+// Namespace is the namespace (prefix) of every metric.
+const Namespace = "eksauth"
+
+// Error types of the eksauth_token_errors_total metric.
+const (
+	ErrorTypeThrottled = "throttled"
+	ErrorTypeTimeout   = "timeout"
+	ErrorTypeRetryable = "retryable"
+	ErrorTypeOther     = "other"
+)
+
+// Metrics are the Prometheus collectors for token issuance.
+type Metrics struct {
+	// TokensIssued counts successfully generated tokens by cluster.
+	TokensIssued *prometheus.CounterVec
+	// Errors counts token generation errors by cluster and error type.
+	Errors *prometheus.CounterVec
+	// PresignDuration observes how long generating (presigning) a token took, including credential retrieval.
+	PresignDuration *prometheus.HistogramVec
+	// RemainingLifetime observes the remaining lifetime of the previous token when it was refreshed.
+	RemainingLifetime *prometheus.HistogramVec
+}
+
+// newMetrics creates the collectors without registering them.
+func newMetrics() *Metrics {
+	return &Metrics{
+		TokensIssued: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "tokens_issued_total",
+			Help:      "Number of EKS tokens generated.",
+		}, []string{"cluster"}),
+		Errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "token_errors_total",
+			Help:      "Number of failed EKS token generations by error type.",
+		}, []string{"cluster", "type"}),
+		PresignDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "presign_duration_seconds",
+			Help:      "Time taken to generate an EKS token, including credential retrieval.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 9),
+		}, []string{"cluster"}),
+		RemainingLifetime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "token_remaining_lifetime_seconds",
+			Help:      "Remaining lifetime of the previous EKS token when it was refreshed.",
+			Buckets:   []float64{0, 15, 30, 60, 120, 300, 600, 900},
+		}, []string{"cluster"}),
+	}
+}
+
+// New creates the Metrics and registers them with reg.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	m := newMetrics()
+	for _, c := range []prometheus.Collector{m.TokensIssued, m.Errors, m.PresignDuration, m.RemainingLifetime} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// ErrorType classifies a token generation error for the eksauth_token_errors_total metric.
+func ErrorType(err error) string {
+	switch {
+	case errors.Is(err, eksauth.ErrThrottled):
+		return ErrorTypeThrottled
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return ErrorTypeTimeout
+	case eksauth.IsRetryable(err):
+		return ErrorTypeRetryable
+	default:
+		return ErrorTypeOther
+	}
+}
+
+// TokenSource is an oauth2.TokenSource that records metrics for every token generated by Source.
+// It should wrap an uncached source (see Option), otherwise cache hits are counted as issued tokens.
+type TokenSource struct {
+	Source      oauth2.TokenSource
+	Metrics     *Metrics
+	ClusterName string
+
+	mu     sync.Mutex
+	expiry time.Time
+}
+
+// Wrap creates a TokenSource recording metrics for src labelled with clusterName.
+func (m *Metrics) Wrap(clusterName string, src oauth2.TokenSource) *TokenSource {
+	return &TokenSource{Source: src, Metrics: m, ClusterName: clusterName}
+}
+
+// Option returns an eksauth.Option that instruments the token source created by the eksauth New* constructors.
+// The cluster label is the cluster name given to the constructor.
+func (m *Metrics) Option() eksauth.Option {
+	return eksauth.WithClusterMiddleware(func(clusterName string, src oauth2.TokenSource) oauth2.TokenSource {
+		return m.Wrap(clusterName, src)
+	})
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (s *TokenSource) Token() (*oauth2.Token, error) {
+	return s.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the eksauth.ContextTokenSource interface.
+func (s *TokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	start := time.Now()
+	t, err := eksauth.TokenWithContext(ctx, s.Source)
+	s.Metrics.PresignDuration.WithLabelValues(s.ClusterName).Observe(time.Since(start).Seconds())
+	if err != nil {
+		s.Metrics.Errors.WithLabelValues(s.ClusterName, ErrorType(err)).Inc()
+		return nil, err
+	}
+	s.Metrics.TokensIssued.WithLabelValues(s.ClusterName).Inc()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.expiry.IsZero() {
+		s.Metrics.RemainingLifetime.WithLabelValues(s.ClusterName).Observe(max(time.Until(s.expiry), 0).Seconds())
+	}
+	s.expiry = t.Expiry
+	return t, nil
+}
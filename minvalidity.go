@@ -0,0 +1,74 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrInsufficientValidity is returned by MinValidityTokenSource when even a refreshed token expires too soon.
+var ErrInsufficientValidity = errors.New("eksauth: token does not have the minimum remaining validity")
+
+// MinValidityTokenSource is an oauth2.TokenSource that never returns a token with less than MinValidity of
+// remaining life: the wrapped source is invalidated and asked for a new token instead. It guards long-lived
+// connections (ie watches and exec sessions) from being established with a token that is about to lapse,
+// independently of the early expiry of any cache it wraps.
+type MinValidityTokenSource struct {
+	Source      oauth2.TokenSource
+	MinValidity time.Duration
+	// Now overrides time.Now.
+	Now func() time.Time
+	// Context is the base context used by Token, context.Background() if nil.
+	Context context.Context
+}
+
+// NewMinValidityTokenSource wraps src in a MinValidityTokenSource.
+func NewMinValidityTokenSource(src oauth2.TokenSource, minValidity time.Duration) *MinValidityTokenSource {
+	return &MinValidityTokenSource{Source: src, MinValidity: minValidity}
+}
+
+// sufficient reports if the token has at least MinValidity of remaining life, tokens without expiry always do.
+func (s *MinValidityTokenSource) sufficient(t *oauth2.Token) bool {
+	if t.Expiry.IsZero() {
+		return true
+	}
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	return t.Expiry.Sub(now()) >= s.MinValidity
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (s *MinValidityTokenSource) Token() (*oauth2.Token, error) {
+	return s.TokenWithContext(baseContext(s.Context))
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+func (s *MinValidityTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	t, err := TokenWithContext(ctx, s.Source)
+	if err != nil || s.sufficient(t) {
+		return t, err
+	}
+	t, err = ForceRefresh(ctx, s.Source)
+	if err != nil {
+		return nil, err
+	}
+	if !s.sufficient(t) {
+		return nil, fmt.Errorf("%w: expires at %s, %s required", ErrInsufficientValidity, t.Expiry.Format(time.RFC3339), s.MinValidity)
+	}
+	return t, nil
+}
+
+// Invalidate implements the Invalidator interface.
+func (s *MinValidityTokenSource) Invalidate() {
+	Invalidate(s.Source)
+}
+
+// Close implements the io.Closer interface.
+func (s *MinValidityTokenSource) Close() error {
+	return closeTokenSource(s.Source)
+}
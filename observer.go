@@ -0,0 +1,34 @@
+package eksauth
+
+import (
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// RefreshObserver is notified whenever a TokenSource mints a new token or fails to.
+// Methods are called synchronously from Token so they should not block.
+type RefreshObserver interface {
+	OnRefresh(t *oauth2.Token, expiry time.Time)
+	OnError(err error)
+}
+
+// RefreshObserverFuncs implements the RefreshObserver interface using (optional) functions.
+type RefreshObserverFuncs struct {
+	Refresh func(t *oauth2.Token, expiry time.Time)
+	Error   func(err error)
+}
+
+// OnRefresh implements the RefreshObserver interface.
+func (f RefreshObserverFuncs) OnRefresh(t *oauth2.Token, expiry time.Time) {
+	if f.Refresh != nil {
+		f.Refresh(t, expiry)
+	}
+}
+
+// OnError implements the RefreshObserver interface.
+func (f RefreshObserverFuncs) OnError(err error) {
+	if f.Error != nil {
+		f.Error(err)
+	}
+}
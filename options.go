@@ -0,0 +1,526 @@
+package eksauth
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/oauth2"
+)
+
+// Option configures a token source created by the New* constructors.
+// Options take precedence over the EKSAUTH_* environment variables, which take precedence over the package defaults.
+type Option func(*options)
+
+// options are the settings collected from a list of Option values.
+type options struct {
+	ctx                context.Context
+	expiration         time.Duration
+	earlyExpiry        *time.Duration
+	format             TokenFormat
+	stsOptions         []func(*sts.Options)
+	presignOptions     []func(*sts.PresignOptions)
+	middleware         []func(clusterName string, ts oauth2.TokenSource) oauth2.TokenSource
+	tracerProvider     trace.TracerProvider
+	logger             *slog.Logger
+	observers          []RefreshObserver
+	assumeRoleOptions  []func(*stscreds.AssumeRoleOptions)
+	webIdentityOptions []func(*stscreds.WebIdentityRoleOptions)
+	presignExpires     time.Duration
+	now                func() time.Time
+	clusterID          string
+	signingRegion      string
+	clusterIDHeader    string
+	clientOptions      []func(*sts.Options)
+	preflightIdentity  bool
+	signingDebug       bool
+	rateLimiter        *RateLimiter
+	auditSink          AuditSink
+	endpoint           string
+	earlyExpiryJitter  time.Duration
+	retry              *RetryPolicy
+	httpClient         aws.HTTPClient
+	appID              string
+	presigner          sts.HTTPPresignerV4
+	expiryClamp        func(time.Time, aws.Credentials) time.Time
+	tokenState         []byte
+	minValidity        time.Duration
+	signingTime        time.Time
+	headers            http.Header
+	query              url.Values
+	refreshOnRotation  bool
+	mfaTokenProvider   func() (string, error)
+	ssoLoginPrompt     SSOLoginPrompt
+	callerIdentity     bool
+	clockSkew          *ClockSkew
+	// credentials are the credentials signing tokens, set by the constructors building the sts.Client.
+	credentials aws.CredentialsProvider
+	// stsClient is the sts.Client presigning tokens, if known.
+	stsClient *sts.Client
+	// ssoProfile and ssoStartURL describe the SSO session of credentials, if any.
+	ssoProfile  string
+	ssoStartURL string
+	// values are set by WithValue.
+	values map[any]any
+}
+
+// newOptions applies the Option values on top of the EKSAUTH_* environment variables.
+func newOptions(env Env, opts []Option) *options {
+	o := &options{
+		expiration: env.Expiration,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// expirationOrDefault returns the configured expiration or DefaultExpiration.
+func (o *options) expirationOrDefault() time.Duration {
+	if o.expiration != 0 {
+		return o.expiration
+	}
+	return DefaultExpiration
+}
+
+// earlyExpiryOrDefault returns the configured early expiry or DefaultEarlyExpiry.
+func (o *options) earlyExpiryOrDefault() time.Duration {
+	if o.earlyExpiry != nil {
+		return *o.earlyExpiry
+	}
+	return DefaultEarlyExpiry
+}
+
+// WithContext sets the base context used by Token, see ContextTokenSource for per-call contexts.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithExpiration sets the expiration of generated tokens, overriding DefaultExpiration.
+func WithExpiration(d time.Duration) Option {
+	return func(o *options) {
+		o.expiration = d
+	}
+}
+
+// WithEarlyExpiryJitter refreshes each cached token a random duration in [0, jitter) earlier than the early expiry,
+// so thousands of processes started at the same time (ie after a deploy) do not all refresh at the same instant.
+func WithEarlyExpiryJitter(jitter time.Duration) Option {
+	return func(o *options) {
+		o.earlyExpiryJitter = jitter
+	}
+}
+
+// WithMinValidity refuses to return a token with less than d of remaining life, a new token is generated instead,
+// see MinValidityTokenSource. The returned token source is then a *MinValidityTokenSource wrapping the cache.
+func WithMinValidity(d time.Duration) Option {
+	return func(o *options) {
+		o.minValidity = d
+	}
+}
+
+// WithRefreshOnCredentialRotation generates a new token (even if the cached one has not expired) when the
+// credentials provider produces new credentials, see RotationTokenSource. It has no effect on the constructors
+// receiving an sts.Client or sts.PresignClient, whose credentials are unknown.
+func WithRefreshOnCredentialRotation() Option {
+	return func(o *options) {
+		o.refreshOnRotation = true
+	}
+}
+
+// WithMFATokenProvider sets the function returning the MFA code when assuming the role of a ClusterConfig with an
+// mfa_serial, see NewFromClusterConfig. Without it such roles cannot be assumed.
+func WithMFATokenProvider(fn func() (string, error)) Option {
+	return func(o *options) {
+		o.mfaTokenProvider = fn
+	}
+}
+
+// WithSSOLogin enables the automatic SSO login: when the IAM Identity Center (AWS SSO) session of the aws.Config
+// credentials is expired or missing, SSOLogin runs with prompt and the token is generated once the user approved the
+// device authorization. It is meant for interactive processes, ie a kubectl exec plugin, see SSOLogin.
+// A nil prompt disables it.
+func WithSSOLogin(prompt SSOLoginPrompt) Option {
+	return func(o *options) {
+		o.ssoLoginPrompt = prompt
+	}
+}
+
+// WithCallerIdentity records the caller identity of the signing credentials in the Provenance of tokens (see
+// TokenIdentity), calling sts:GetCallerIdentity once per credential set, see IdentityTokenSource.
+// It is ignored by NewFromPresignClient since the sts.Client is unknown.
+func WithCallerIdentity() Option {
+	return func(o *options) {
+		o.callerIdentity = true
+	}
+}
+
+// WithPreflightIdentityCheck calls sts:GetCallerIdentity (a real call, not presigned) when the token source is
+// created, so unusable credentials (revoked keys, an expired session or a denied role) fail at startup.
+// Constructors returning an error return the failure, the others return a token source failing every call with it.
+// It requires a sts.Client, it always fails for NewFromPresignClient.
+func WithPreflightIdentityCheck() Option {
+	return func(o *options) {
+		o.preflightIdentity = true
+	}
+}
+
+// WithSigningDebug attaches the SigV4 canonical request, string to sign, signed headers and presigned URL to every
+// token (never the secret key), see TokenSigningDebug. It helps diagnosing signature mismatches.
+func WithSigningDebug() Option {
+	return func(o *options) {
+		o.signingDebug = true
+	}
+}
+
+// WithRateLimit limits how often new tokens are generated for each cluster using the token buckets of l, which may
+// be shared by many token sources. Cached tokens are not limited, failures are a *RateLimitedError (ErrRateLimited).
+func WithRateLimit(l *RateLimiter) Option {
+	return func(o *options) {
+		o.rateLimiter = l
+	}
+}
+
+// WithAudit records every token issuance (and failure) to sink, see AuditEvent, ie SlogAuditSink, FileAuditSink or
+// HTTPAuditSink. Tokens returned from the cache are not recorded. The caller ARN is only known with WithCallerIdentity.
+func WithAudit(sink AuditSink) Option {
+	return func(o *options) {
+		o.auditSink = sink
+	}
+}
+
+// WithTokenState seeds the token cache with a token serialized by ExportTokenState, so no credentials are needed
+// until it (early) expires. Invalid or expired states are ignored, the state must be for the same cluster.
+func WithTokenState(data []byte) Option {
+	return func(o *options) {
+		o.tokenState = data
+	}
+}
+
+// WithPresignExpires sets the X-Amz-Expires of the presigned URL, overriding DefaultPresignExpires.
+// It must be between 1 second and MaxExpiration (the STS maximum), otherwise generating tokens fails.
+func WithPresignExpires(d time.Duration) Option {
+	return func(o *options) {
+		o.presignExpires = d
+	}
+}
+
+// WithEarlyExpiry sets how long before expiry cached tokens are refreshed, overriding DefaultEarlyExpiry.
+func WithEarlyExpiry(d time.Duration) Option {
+	return func(o *options) {
+		o.earlyExpiry = &d
+	}
+}
+
+// WithTokenFormat sets the TokenFormat of generated tokens, overriding V1Format.
+func WithTokenFormat(format TokenFormat) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithSTSOptions adds functions that configure the sts.Client built by NewFromConfig.
+func WithSTSOptions(optFns ...func(*sts.Options)) Option {
+	return func(o *options) {
+		o.stsOptions = append(o.stsOptions, optFns...)
+	}
+}
+
+// WithPresignOptions adds functions that configure the sts.PresignClient built by NewFromClient and NewFromConfig.
+func WithPresignOptions(optFns ...func(*sts.PresignOptions)) Option {
+	return func(o *options) {
+		o.presignOptions = append(o.presignOptions, optFns...)
+	}
+}
+
+// WithTracerProvider records an OpenTelemetry span for each generated token using tp.
+// Spans include the cluster name, region and credential source and record any error.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithLogger logs generated tokens and their expiry at debug level, and errors and expiry clamping at info level.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithRefreshObserver adds observers notified whenever a new token is generated or generation fails.
+func WithRefreshObserver(observers ...RefreshObserver) Option {
+	return func(o *options) {
+		o.observers = append(o.observers, observers...)
+	}
+}
+
+// WithAssumeRoleOptions adds functions that configure the stscreds.AssumeRoleProvider built by NewFromRole,
+// see WithRoleSessionName, WithRoleDuration, WithRolePolicy, WithRoleTags and WithSourceIdentity.
+func WithAssumeRoleOptions(optFns ...func(*stscreds.AssumeRoleOptions)) Option {
+	return func(o *options) {
+		o.assumeRoleOptions = append(o.assumeRoleOptions, optFns...)
+	}
+}
+
+// WithWebIdentityOptions adds functions that configure the stscreds.WebIdentityRoleProvider built by NewFromWebIdentity.
+func WithWebIdentityOptions(optFns ...func(*stscreds.WebIdentityRoleOptions)) Option {
+	return func(o *options) {
+		o.webIdentityOptions = append(o.webIdentityOptions, optFns...)
+	}
+}
+
+// WithSigningTime fixes the SigV4 signing time (X-Amz-Date) of generated tokens and, unless WithClock is used,
+// the time their expiry is computed from. Combined with static credentials it produces stable tokens for
+// golden-file tests and fuzzing, it should not be used in production.
+func WithSigningTime(t time.Time) Option {
+	return func(o *options) {
+		o.signingTime = t
+	}
+}
+
+// WithClock sets the function returning the current time used to compute token expiry, time.Now by default.
+// It allows tests to deterministically control the expiry (and clamping to the credential expiry) of tokens.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// WithClusterID overrides the signed cluster ID (the cluster name by default), this allows minting tokens for
+// self-hosted clusters running aws-iam-authenticator which supports arbitrary cluster IDs.
+func WithClusterID(clusterID string) Option {
+	return func(o *options) {
+		o.clusterID = clusterID
+	}
+}
+
+// WithSigningRegion presigns tokens for the regional STS endpoint of region (both the signed host and the SigV4
+// region), independently of the region of the aws.Config or sts.Client, ie credentials resolved in us-east-1 for a
+// cluster in eu-west-1. It takes precedence over the region of a cluster ARN.
+func WithSigningRegion(region string) Option {
+	return func(o *options) {
+		o.signingRegion = region
+	}
+}
+
+// WithClusterIDHeader overrides the name of the signed cluster ID header, "X-K8s-Aws-Id" by default.
+func WithClusterIDHeader(header string) Option {
+	return func(o *options) {
+		o.clusterIDHeader = header
+	}
+}
+
+// WithSignedHeader adds a header signed into the presigned request (like the cluster ID header), ie for forked
+// authenticators requiring extra context such as a tenant ID. It may be repeated.
+func WithSignedHeader(name, value string) Option {
+	return func(o *options) {
+		if o.headers == nil {
+			o.headers = make(http.Header)
+		}
+		o.headers.Add(name, value)
+	}
+}
+
+// WithQueryParameter adds a query parameter signed into the presigned URL. It may be repeated.
+// NOTE: Verifier (and the EKS authenticator) reject tokens with unexpected query parameters.
+func WithQueryParameter(name, value string) Option {
+	return func(o *options) {
+		if o.query == nil {
+			o.query = make(url.Values)
+		}
+		o.query.Add(name, value)
+	}
+}
+
+// withClientOptions adds functions that configure the sts.Options used when presigning, for every constructor.
+func withClientOptions(optFns ...func(*sts.Options)) Option {
+	return func(o *options) {
+		o.clientOptions = append(o.clientOptions, optFns...)
+	}
+}
+
+// WithFIPS signs tokens against the FIPS STS endpoint of the region, ie for GovCloud.
+func WithFIPS() Option {
+	return withClientOptions(func(o *sts.Options) {
+		o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+	})
+}
+
+// WithDualStack signs tokens against the dual-stack (IPv4 and IPv6) STS endpoint of the region.
+func WithDualStack() Option {
+	return withClientOptions(func(o *sts.Options) {
+		o.EndpointOptions.UseDualStackEndpoint = aws.DualStackEndpointStateEnabled
+	})
+}
+
+// WithSTSEndpoint signs tokens against a custom STS endpoint, ie a private STS VPC endpoint
+// (https://vpce-0123456789abcdef-abcdefgh.sts.us-west-2.vpce.amazonaws.com) in VPCs without internet egress.
+// Token generation fails if the host of the presigned URL does not match the endpoint.
+func WithSTSEndpoint(endpoint string) Option {
+	return func(o *options) {
+		o.endpoint = endpoint
+	}
+}
+
+// WithEndpointResolver sets the sts.EndpointResolverV2 resolving the STS endpoint signed into tokens, ie to route
+// through a service mesh or an egress proxy with an SNI allowlist. The resolver receives the sts.EndpointParameters
+// of the request (region, FIPS, dual-stack and the BaseEndpoint of WithSTSEndpoint).
+func WithEndpointResolver(resolver sts.EndpointResolverV2) Option {
+	return withClientOptions(func(o *sts.Options) {
+		o.EndpointResolverV2 = resolver
+	})
+}
+
+// WithAPIOptions adds smithy middleware to the presigned GetCallerIdentity operation, ie request instrumentation,
+// test hooks or extra signing steps. Middleware added to the Build step (before signing) changes the signed request.
+func WithAPIOptions(optFns ...func(*middleware.Stack) error) Option {
+	return withClientOptions(sts.WithAPIOptions(optFns...))
+}
+
+// WithHTTPClient sets the HTTP client of the STS clients built from an aws.Config (aws.Config.HTTPClient),
+// ie for proxies, custom CAs of TLS intercepting networks or connection pool tuning.
+// It is used to assume roles, credentials already present in the aws.Config keep their own HTTP client.
+func WithHTTPClient(client aws.HTTPClient) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithValue attaches a value to the options that is ignored by this package, so packages wrapping the constructors
+// (ie kube.WithImpersonation) can define their own Option values. The key should be an unexported type, see OptionValue.
+func WithValue(key, value any) Option {
+	return func(o *options) {
+		if o.values == nil {
+			o.values = make(map[any]any)
+		}
+		o.values[key] = value
+	}
+}
+
+// OptionValue returns the value attached to opts with WithValue for key, the last one wins.
+func OptionValue(opts []Option, key any) (any, bool) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	value, ok := o.values[key]
+	return value, ok
+}
+
+// WithTransport is WithHTTPClient using an http.Client with the http.RoundTripper.
+func WithTransport(rt http.RoundTripper) Option {
+	return WithHTTPClient(&http.Client{Transport: rt})
+}
+
+// applyConfig applies the options that configure an aws.Config.
+func (o *options) applyConfig(cfg *aws.Config) {
+	if o.httpClient != nil {
+		cfg.HTTPClient = o.httpClient
+	}
+	if o.appID != "" {
+		cfg.AppID = o.appID
+	}
+	if o.clockSkew != nil {
+		cfg.HTTPClient = o.clockSkew.HTTPClient(cfg.HTTPClient)
+	}
+}
+
+// WithClockSkew adapts the early expiry of the token cache to the clock skew tracked by c (see ClockSkew), instead
+// of relying on the fixed early expiry on badly skewed hosts. The AWS responses of clients built from an
+// aws.Config (ie when assuming roles) are observed by c, call ClockSkew.Measure when all credentials are static.
+func WithClockSkew(c *ClockSkew) Option {
+	return func(o *options) {
+		o.clockSkew = c
+	}
+}
+
+// WithUserAgentAppID appends an application identifier (aws.Config.AppID) to the User-Agent of the AWS API calls
+// made by clients built from an aws.Config (ie assuming roles), next to the "eks-auth/<version>" marker,
+// so credential traffic can be attributed in CloudTrail and VPC endpoint logs. See WithAppID for an sts.Client.
+func WithUserAgentAppID(appID string) Option {
+	return func(o *options) {
+		o.appID = appID
+	}
+}
+
+// WithPresigner replaces the SigV4 presigner used to sign tokens, ie with a signer using an HSM-backed key.
+func WithPresigner(presigner sts.HTTPPresignerV4) Option {
+	return func(o *options) {
+		o.presigner = presigner
+	}
+}
+
+// WithSigV4a presigns tokens with SigV4a (see SigV4aPresigner) valid in every region of regionSet ("*" if empty),
+// ie for multi-region STS access points. It replaces WithPresigner.
+func WithSigV4a(regionSet ...string) Option {
+	return WithPresigner(NewSigV4aPresigner(regionSet...))
+}
+
+// WithExpiryClamp replaces ClampToCredentialExpiry, fn returns the token expiry given the requested expiry and the
+// credentials that signed the token. The expiry is always clamped to the validity of the signature.
+func WithExpiryClamp(fn func(expiry time.Time, credentials aws.Credentials) time.Time) Option {
+	return func(o *options) {
+		o.expiryClamp = fn
+	}
+}
+
+// WithoutExpiryClamp disables clamping the token expiry to the expiry of the signing credentials.
+// NOTE: EKS rejects tokens signed by expired credentials, so tokens may be rejected before their expiry.
+func WithoutExpiryClamp() Option {
+	return WithExpiryClamp(func(expiry time.Time, _ aws.Credentials) time.Time {
+		return expiry
+	})
+}
+
+// WithRetry retries transient token generation failures (ie IMDS timeouts or throttling) within a single call to
+// Token according to policy, or DefaultRetryPolicy if no policy is provided.
+func WithRetry(policy ...RetryPolicy) Option {
+	p := DefaultRetryPolicy
+	if len(policy) > 0 {
+		p = policy[0]
+	}
+	return func(o *options) {
+		o.retry = &p
+	}
+}
+
+// WithMiddleware wraps the (uncached) token source with fns, the first function is the innermost wrapper.
+// Middleware is invoked for each token generated, ie on refreshes of the cached token.
+func WithMiddleware(fns ...func(oauth2.TokenSource) oauth2.TokenSource) Option {
+	return func(o *options) {
+		for _, fn := range fns {
+			o.middleware = append(o.middleware, func(_ string, ts oauth2.TokenSource) oauth2.TokenSource {
+				return fn(ts)
+			})
+		}
+	}
+}
+
+// WithClusterMiddleware is WithMiddleware where fns are also given the name of the cluster, ie to label metrics
+// regardless of the wrappers added by other options.
+func WithClusterMiddleware(fns ...func(clusterName string, ts oauth2.TokenSource) oauth2.TokenSource) Option {
+	return func(o *options) {
+		o.middleware = append(o.middleware, fns...)
+	}
+}
+
+// wrap applies the middleware and retry policy to ts, middleware observes every attempt.
+func (o *options) wrap(clusterName string, ts oauth2.TokenSource) oauth2.TokenSource {
+	for _, fn := range o.middleware {
+		ts = fn(clusterName, ts)
+	}
+	if o.retry != nil {
+		ts = NewRetryTokenSource(ts, *o.retry)
+	}
+	return ts
+}
@@ -0,0 +1,94 @@
+package eksauth
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParsedToken is the unverified contents of a token, see ParseToken.
+type ParsedToken struct {
+	// URL is the presigned sts:GetCallerIdentity URL.
+	URL *url.URL
+	// Prefix is the prefix of the TokenFormat the token was encoded with.
+	Prefix string
+	// SignedHeaders are the (lowercase) signed headers of the presigned request.
+	SignedHeaders []string
+	// ClusterIDSigned reports if the x-k8s-aws-id header is signed.
+	// NOTE: the cluster ID itself is not part of the token, it is provided by the verifier when calling STS.
+	ClusterIDSigned bool
+	// SigningTime is the X-Amz-Date of the presigned request.
+	SigningTime time.Time
+	// Expires is the X-Amz-Expires of the presigned request.
+	Expires time.Duration
+	// AccessKeyID is the access key ID from the X-Amz-Credential scope.
+	AccessKeyID string
+	// Region is the signing region from the X-Amz-Credential scope, the X-Amz-Region-Set of SigV4a tokens.
+	Region string
+	// Service is the signing service from the X-Amz-Credential scope, this should always be "sts".
+	Service string
+}
+
+// Expiry returns when the token expires, MaxExpiration after SigningTime (like aws-iam-authenticator).
+func (p *ParsedToken) Expiry() time.Time {
+	return p.SigningTime.Add(MaxExpiration)
+}
+
+// ParseToken decodes a token in any registered TokenFormat and returns its contents WITHOUT verifying it.
+// It is intended for debugging and logging, use a Verifier to actually authenticate a token.
+func ParseToken(token string) (*ParsedToken, error) {
+	format, ok := LookupTokenFormat(token)
+	if !ok {
+		return nil, ErrUnknownTokenFormat
+	}
+	presignedURL, err := format.Decode(token)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := parsePresignedURL(presignedURL)
+	if err != nil {
+		return nil, err
+	}
+	parsed.Prefix = format.Prefix()
+	return parsed, nil
+}
+
+// parsePresignedURL parses a presigned sts:GetCallerIdentity URL into a ParsedToken (without a Prefix).
+func parsePresignedURL(presignedURL string) (*ParsedToken, error) {
+	u, err := url.Parse(presignedURL)
+	if err != nil {
+		return nil, fmt.Errorf("eksauth: failed to parse presigned URL: %w", err)
+	}
+	query := u.Query()
+	parsed := &ParsedToken{URL: u}
+
+	date := query.Get("X-Amz-Date")
+	if date == "" {
+		return nil, fmt.Errorf("eksauth: presigned URL is missing X-Amz-Date")
+	}
+	if parsed.SigningTime, err = time.Parse(amzDateFormat, date); err != nil {
+		return nil, fmt.Errorf("eksauth: invalid X-Amz-Date: %w", err)
+	}
+	if s := query.Get("X-Amz-Expires"); s != "" {
+		seconds, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("eksauth: invalid X-Amz-Expires: %w", err)
+		}
+		parsed.Expires = time.Duration(seconds) * time.Second
+	}
+	if s := query.Get("X-Amz-SignedHeaders"); s != "" {
+		parsed.SignedHeaders = strings.Split(strings.ToLower(s), ";")
+		parsed.ClusterIDSigned = slices.Contains(parsed.SignedHeaders, strings.ToLower(clusterIDHeader))
+	}
+	// X-Amz-Credential is <access key>/<date>/<region>/<service>/aws4_request, the region of SigV4a is the region set
+	switch scope := strings.Split(query.Get("X-Amz-Credential"), "/"); len(scope) {
+	case 5:
+		parsed.AccessKeyID, parsed.Region, parsed.Service = scope[0], scope[2], scope[3]
+	case 4:
+		parsed.AccessKeyID, parsed.Region, parsed.Service = scope[0], query.Get("X-Amz-Region-Set"), scope[2]
+	}
+	return parsed, nil
+}
@@ -0,0 +1,60 @@
+package eksauth
+
+import "strings"
+
+// AWS partitions understood by PartitionForRegion and PartitionForHost.
+const (
+	PartitionAWS      = "aws"
+	PartitionChina    = "aws-cn"
+	PartitionGovCloud = "aws-us-gov"
+	PartitionISO      = "aws-iso"
+	PartitionISOB     = "aws-iso-b"
+)
+
+// PartitionForRegion returns the AWS partition of a region, ie "aws-cn" for "cn-north-1".
+func PartitionForRegion(region string) string {
+	switch {
+	case region == "":
+		return ""
+	case strings.HasPrefix(region, "cn-"):
+		return PartitionChina
+	case strings.HasPrefix(region, "us-gov-"):
+		return PartitionGovCloud
+	case strings.HasPrefix(region, "us-isob-"):
+		return PartitionISOB
+	case strings.HasPrefix(region, "us-iso-"):
+		return PartitionISO
+	default:
+		return PartitionAWS
+	}
+}
+
+// PartitionForHost returns the AWS partition of an STS hostname.
+func PartitionForHost(host string) string {
+	switch {
+	case host == "":
+		return ""
+	case strings.HasSuffix(host, ".amazonaws.com.cn"), strings.HasSuffix(host, ".amazonwebservices.com.cn"):
+		return PartitionChina
+	case strings.Contains(host, "us-gov-"):
+		return PartitionGovCloud
+	case strings.HasSuffix(host, ".c2s.ic.gov"):
+		return PartitionISO
+	case strings.HasSuffix(host, ".sc2s.sgov.gov"):
+		return PartitionISOB
+	default:
+		return PartitionAWS
+	}
+}
+
+// regionForHost returns the region of a regional STS hostname (sts.us-west-2.amazonaws.com), empty if global.
+func regionForHost(host string) string {
+	labels := strings.Split(host, ".")
+	if len(labels) < 3 || (labels[0] != "sts" && labels[0] != "sts-fips") {
+		return ""
+	}
+	if labels[1] == "amazonaws" || labels[1] == "api" {
+		return ""
+	}
+	return labels[1]
+}
@@ -0,0 +1,55 @@
+package eksauth
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// appDirName is the name of the directory created under the user config/cache directories.
+const appDirName = "eks-auth"
+
+// ConfigDir returns the directory used for configuration files.
+// It is $EKSAUTH_CONFIG_DIR if set, otherwise "eks-auth" under os.UserConfigDir
+// ($XDG_CONFIG_HOME or ~/.config on Unix, ~/Library/Application Support on macOS, %AppData% on Windows).
+func ConfigDir() (string, error) {
+	if dir := os.Getenv("EKSAUTH_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appDirName), nil
+}
+
+// CacheDir returns the directory used for cached data such as tokens.
+// It is $EKSAUTH_CACHE_DIR if set, otherwise "eks-auth" under os.UserCacheDir
+// ($XDG_CACHE_HOME or ~/.cache on Unix, ~/Library/Caches on macOS, %LocalAppData% on Windows).
+func CacheDir() (string, error) {
+	if dir := os.Getenv("EKSAUTH_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appDirName), nil
+}
+
+// DefaultProfilesPath returns the default location of the profiles file, "profiles.json" in ConfigDir.
+func DefaultProfilesPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "profiles.json"), nil
+}
+
+// LoadDefaultProfiles loads the profiles file from DefaultProfilesPath.
+func LoadDefaultProfiles() (*Profiles, error) {
+	path, err := DefaultProfilesPath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadProfiles(path)
+}
@@ -0,0 +1,272 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"golang.org/x/oauth2"
+)
+
+// ErrPolicyViolation is matched (using errors.Is) by every *PolicyError.
+var ErrPolicyViolation = errors.New("eksauth: policy violation")
+
+// PolicyError is returned when a token would violate a policy enforced by this package.
+type PolicyError struct {
+	// Policy is the name of the violated policy, ie "RequireAssumedRole".
+	Policy string
+	// Reason describes the violation.
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *PolicyError) Error() string {
+	return "eksauth: policy " + e.Policy + " violated: " + e.Reason
+}
+
+// Is allows errors.Is(err, ErrPolicyViolation).
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrPolicyViolation
+}
+
+// CheckAssumedRole returns a *PolicyError unless the identity is an assumed IAM role session.
+func CheckAssumedRole(identity *Identity) error {
+	parsed, err := arn.Parse(identity.ARN)
+	if err != nil {
+		return &PolicyError{Policy: "RequireAssumedRole", Reason: "unable to parse caller ARN: " + err.Error()}
+	}
+	if parsed.Service != "sts" || !strings.HasPrefix(parsed.Resource, "assumed-role/") {
+		return &PolicyError{Policy: "RequireAssumedRole", Reason: identity.ARN + " is not an assumed role"}
+	}
+	return nil
+}
+
+// RequireAssumedRoleTokenSource is an oauth2.TokenSource that refuses to return tokens signed by
+// long-term IAM user or root credentials.
+type RequireAssumedRoleTokenSource struct {
+	Source oauth2.TokenSource
+	// Identity (optional) is used to verify the caller is an assumed role using sts:GetCallerIdentity.
+	// It must use the same credentials as Source. If nil, only long-term (AKIA) access keys are rejected,
+	// which does not detect temporary credentials obtained via sts:GetSessionToken for an IAM user.
+	Identity *IdentityCache
+}
+
+// NewRequireAssumedRole wraps src in a RequireAssumedRoleTokenSource, identity may be nil.
+func NewRequireAssumedRole(src oauth2.TokenSource, identity *IdentityCache) *RequireAssumedRoleTokenSource {
+	return &RequireAssumedRoleTokenSource{Source: src, Identity: identity}
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (ts *RequireAssumedRoleTokenSource) Token() (*oauth2.Token, error) {
+	return ts.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+func (ts *RequireAssumedRoleTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	token, err := TokenWithContext(ctx, ts.Source)
+	if err != nil {
+		return nil, err
+	}
+	if provenance, ok := TokenProvenance(token); ok && strings.HasPrefix(provenance.AccessKeyID, "AKIA") {
+		return nil, &PolicyError{Policy: "RequireAssumedRole", Reason: "token was signed by long-term credentials " + provenance.AccessKeyID}
+	}
+	if ts.Identity != nil {
+		identity, err := ts.Identity.Identity(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := CheckAssumedRole(identity); err != nil {
+			return nil, err
+		}
+	}
+	return token, nil
+}
+
+// TokenPolicy are organizational rules enforced on every token by a PolicyTokenSource, zero values are not enforced.
+type TokenPolicy struct {
+	// MinLifetime is the minimum remaining lifetime of a token when it is returned.
+	MinLifetime time.Duration
+	// MaxLifetime is the maximum remaining lifetime of a token when it is returned.
+	MaxLifetime time.Duration
+	// AllowedRoleARNs are glob patterns ('*' matches any characters) the signing role must match.
+	// The role is the last role assumed by this package, or the role of the caller identity if available.
+	// Role paths are ignored on both sides since assumed role ARNs do not include them, ie the pattern
+	// "arn:aws:iam::*:role/eks/deployer" matches the role "arn:aws:iam::123456789012:role/deployer".
+	AllowedRoleARNs []string
+	// RequiredSessionTags are session tags the signing session must have, mapped to a glob pattern their value must
+	// match ("*" for any value). The tags are those of the roles assumed by this package (see Provenance.SessionTags),
+	// tokens signed by other credentials violate the policy.
+	RequiredSessionTags map[string]string
+	// DeniedPartitions are AWS partitions (ie "aws-cn") tokens may not be signed in.
+	DeniedPartitions []string
+}
+
+// globMatch reports if s matches the glob pattern where '*' matches any (possibly empty) sequence of characters.
+func globMatch(pattern string, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(s, part)
+		if idx < 0 {
+			return false
+		}
+		s = s[idx+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
+
+// withoutRolePath removes the path of an IAM role ARN (or ARN pattern), others are returned unchanged.
+func withoutRolePath(roleARN string) string {
+	prefix, resource, ok := strings.Cut(roleARN, ":role/")
+	if !ok {
+		return roleARN
+	}
+	if idx := strings.LastIndex(resource, "/"); idx >= 0 {
+		resource = resource[idx+1:]
+	}
+	return prefix + ":role/" + resource
+}
+
+// roleFromAssumedRole converts an assumed role session ARN into the ARN of the role (without its path).
+func roleFromAssumedRole(callerARN string) (string, bool) {
+	parsed, err := arn.Parse(callerARN)
+	if err != nil || parsed.Service != "sts" {
+		return "", false
+	}
+	resource, ok := strings.CutPrefix(parsed.Resource, "assumed-role/")
+	if !ok {
+		return "", false
+	}
+	roleName, _, _ := strings.Cut(resource, "/")
+	return arn.ARN{
+		Partition: parsed.Partition,
+		Service:   "iam",
+		AccountID: parsed.AccountID,
+		Resource:  "role/" + roleName,
+	}.String(), true
+}
+
+// Check returns all violations of the policy for the token (and optional caller identity) joined together.
+func (p *TokenPolicy) Check(token *oauth2.Token, identity *Identity) error {
+	var errs []error
+	if !token.Expiry.IsZero() {
+		lifetime := time.Until(token.Expiry)
+		if p.MinLifetime > 0 && lifetime < p.MinLifetime {
+			errs = append(errs, &PolicyError{Policy: "MinLifetime", Reason: fmt.Sprintf("token lifetime %s is less than %s", lifetime.Round(time.Second), p.MinLifetime)})
+		}
+		if p.MaxLifetime > 0 && lifetime > p.MaxLifetime {
+			errs = append(errs, &PolicyError{Policy: "MaxLifetime", Reason: fmt.Sprintf("token lifetime %s exceeds %s", lifetime.Round(time.Second), p.MaxLifetime)})
+		}
+	}
+
+	provenance, _ := TokenProvenance(token)
+	var roleARN, partition string
+	if provenance != nil {
+		if len(provenance.RoleChain) > 0 {
+			roleARN = withoutRolePath(provenance.RoleChain[len(provenance.RoleChain)-1])
+		}
+		partition = PartitionForHost(provenance.Host)
+	}
+	if identity != nil {
+		if role, ok := roleFromAssumedRole(identity.ARN); ok && roleARN == "" {
+			roleARN = role
+		}
+		if parsed, err := arn.Parse(identity.ARN); err == nil {
+			partition = parsed.Partition
+		}
+	}
+
+	if len(p.AllowedRoleARNs) > 0 {
+		allowed := false
+		for _, pattern := range p.AllowedRoleARNs {
+			if roleARN != "" && globMatch(withoutRolePath(pattern), roleARN) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			reason := "token was not signed by an assumed role"
+			if roleARN != "" {
+				reason = roleARN + " does not match any allowed role ARN pattern"
+			}
+			errs = append(errs, &PolicyError{Policy: "AllowedRoleARNs", Reason: reason})
+		}
+	}
+	if len(p.RequiredSessionTags) > 0 {
+		var tags map[string]string
+		if provenance != nil {
+			tags = provenance.SessionTags
+		}
+		keys := make([]string, 0, len(p.RequiredSessionTags))
+		for key := range p.RequiredSessionTags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, ok := tags[key]
+			switch {
+			case tags == nil:
+				errs = append(errs, &PolicyError{Policy: "RequiredSessionTags", Reason: "the session tags of the signing credentials are unknown, no role was assumed by this package"})
+			case !ok:
+				errs = append(errs, &PolicyError{Policy: "RequiredSessionTags", Reason: "session tag " + key + " is missing"})
+			case !globMatch(p.RequiredSessionTags[key], value):
+				errs = append(errs, &PolicyError{Policy: "RequiredSessionTags", Reason: fmt.Sprintf("session tag %s=%q does not match %q", key, value, p.RequiredSessionTags[key])})
+			}
+			if tags == nil {
+				break
+			}
+		}
+	}
+	for _, denied := range p.DeniedPartitions {
+		if partition == denied {
+			errs = append(errs, &PolicyError{Policy: "DeniedPartitions", Reason: "tokens may not be signed in partition " + partition})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PolicyTokenSource is an oauth2.TokenSource that enforces a TokenPolicy on every token of the wrapped source.
+type PolicyTokenSource struct {
+	Source oauth2.TokenSource
+	Policy TokenPolicy
+	// Identity (optional) provides the caller identity, it must use the same credentials as Source.
+	Identity *IdentityCache
+}
+
+// NewPolicyTokenSource wraps src in a PolicyTokenSource, identity may be nil.
+func NewPolicyTokenSource(src oauth2.TokenSource, policy TokenPolicy, identity *IdentityCache) *PolicyTokenSource {
+	return &PolicyTokenSource{Source: src, Policy: policy, Identity: identity}
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (ts *PolicyTokenSource) Token() (*oauth2.Token, error) {
+	return ts.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+func (ts *PolicyTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	token, err := TokenWithContext(ctx, ts.Source)
+	if err != nil {
+		return nil, err
+	}
+	var identity *Identity
+	if ts.Identity != nil {
+		if identity, err = ts.Identity.Identity(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if err := ts.Policy.Check(token, identity); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
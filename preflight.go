@@ -0,0 +1,50 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/oauth2"
+)
+
+// errPreflightNoClient is returned by the identity preflight check of constructors without a sts.Client.
+var errPreflightNoClient = errors.New("requires a sts.Client, use NewFromClient or NewFromConfig")
+
+// preflightIdentityCheck calls sts:GetCallerIdentity (not presigned) with the credentials of o.stsClient.
+func preflightIdentityCheck(clusterName string, o *options) error {
+	if o.stsClient == nil {
+		return &Error{Op: "PreflightIdentityCheck", ClusterName: clusterName, Err: errPreflightNoClient}
+	}
+	ctx := o.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, err := NewIdentityCache(o.stsClient).Identity(ctx); err != nil {
+		return &Error{Op: "PreflightIdentityCheck", ClusterName: clusterName, Err: err}
+	}
+	return nil
+}
+
+// failedTokenSource is returned by the constructors without an error result when the preflight check fails,
+// every call returns the error of the check.
+type failedTokenSource struct {
+	err error
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (ts *failedTokenSource) Token() (*oauth2.Token, error) {
+	return nil, ts.err
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+func (ts *failedTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	return nil, ts.err
+}
+
+// constructorError returns the preflight error of a token source created by a New* constructor, if any.
+func constructorError(ts oauth2.TokenSource) (oauth2.TokenSource, error) {
+	if failed, ok := ts.(*failedTokenSource); ok {
+		return nil, failed.err
+	}
+	return ts, nil
+}
@@ -0,0 +1,90 @@
+package eksauth
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// PresignedRequestExtraKey is the oauth2.Token.Extra key containing the *PresignedRequest of a generated token.
+const PresignedRequestExtraKey = "eksauth.presigned_request"
+
+// PresignedRequest is the presigned sts:GetCallerIdentity request a token encodes, ie for debugging with curl,
+// custom authenticators or re-encoding the token in another format.
+type PresignedRequest struct {
+	// URL is the presigned URL.
+	URL string
+	// Method is the HTTP method of the request, GET unless an Encoder produced the token.
+	Method string
+	// SignedHeader are the signed headers (ie x-k8s-aws-id) that must be sent along with the URL, except Host.
+	// It is nil if the request was decoded from a token that is not in an Encoder format, see TokenPresignedRequest.
+	SignedHeader http.Header
+	// Expiry is the expiry of the token.
+	Expiry time.Time
+}
+
+// TokenPresignedRequest returns the PresignedRequest of a token. Tokens generated by this package carry the signed
+// headers, other tokens (or tokens restored with WithTokenState) are decoded with DecodeTokenRequest.
+func TokenPresignedRequest(t *oauth2.Token) (*PresignedRequest, error) {
+	if req, ok := t.Extra(PresignedRequestExtraKey).(*PresignedRequest); ok {
+		return req, nil
+	}
+	req, err := DecodeTokenRequest(t.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	req.Expiry = t.Expiry
+	return req, nil
+}
+
+// newPresignedRequest creates the PresignedRequest of a presign result.
+func newPresignedRequest(presignedURL, method string, signedHeader http.Header, expiry time.Time) *PresignedRequest {
+	header := signedHeader.Clone()
+	header.Del("Host")
+	return &PresignedRequest{URL: presignedURL, Method: method, SignedHeader: header, Expiry: expiry}
+}
+
+// Request returns an *http.Request executing the presigned request.
+func (r *PresignedRequest) Request() (*http.Request, error) {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return nil, err
+	}
+	method := r.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	return &http.Request{
+		Method: method,
+		URL:    u,
+		Host:   u.Host,
+		Header: r.SignedHeader.Clone(),
+	}, nil
+}
+
+// Curl returns a curl command executing the presigned request, ie for debugging.
+func (r *PresignedRequest) Curl() string {
+	var b strings.Builder
+	b.WriteString("curl")
+	names := make([]string, 0, len(r.SignedHeader))
+	for name := range r.SignedHeader {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range r.SignedHeader[name] {
+			b.WriteString(" -H " + shellQuote(name+": "+value))
+		}
+	}
+	b.WriteString(" " + shellQuote(r.URL))
+	return b.String()
+}
+
+// shellQuote single quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
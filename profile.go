@@ -0,0 +1,288 @@
+package eksauth
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration that is (un)marshalled as a string like "15m" in configuration files.
+type Duration time.Duration
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return &json.UnmarshalTypeError{Value: string(b), Type: reflect.TypeOf(*d)}
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Profile is a named environment (ie dev/stage/prod) mapping to a set of clusters and the defaults used to access them.
+type Profile struct {
+	// AWSProfile is the shared config profile (~/.aws/config) used to load credentials.
+	AWSProfile string `json:"aws_profile,omitempty"`
+	// Region is the default AWS region of the clusters.
+	Region string `json:"region,omitempty"`
+	// RoleARN is the default IAM role assumed before generating tokens.
+	RoleARN string `json:"role_arn,omitempty"`
+	// RoleDuration is the duration of the assumed role session, see WithRoleDuration.
+	RoleDuration Duration `json:"role_duration,omitempty"`
+	// Expiration overrides DefaultExpiration for generated tokens.
+	Expiration Duration `json:"expiration,omitempty"`
+	// DefaultCluster is the cluster used when no cluster name is provided.
+	DefaultCluster string `json:"default_cluster,omitempty"`
+	// Clusters maps EKS cluster names to their (optional) overrides.
+	Clusters map[string]ClusterConfig `json:"clusters,omitempty"`
+}
+
+// ClusterNames returns the sorted names of the clusters in the profile.
+func (p *Profile) ClusterNames() []string {
+	names := make([]string, 0, len(p.Clusters))
+	for name := range p.Clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Cluster returns the ClusterConfig of the named cluster with the profile defaults applied.
+// If name is empty, the DefaultCluster of the profile is used.
+func (p *Profile) Cluster(name string) (ClusterConfig, error) {
+	if name == "" {
+		name = p.DefaultCluster
+	}
+	if name == "" {
+		return ClusterConfig{}, fmt.Errorf("eksauth: no cluster name provided and profile has no default_cluster")
+	}
+	cluster, ok := p.Clusters[name]
+	if !ok {
+		return ClusterConfig{}, fmt.Errorf("eksauth: cluster %q not found in profile", name)
+	}
+	if cluster.Name == "" {
+		cluster.Name = name
+	}
+	if cluster.Region == "" {
+		cluster.Region = p.Region
+	}
+	if cluster.RoleARN == "" {
+		cluster.RoleARN = p.RoleARN
+	}
+	if cluster.RoleDuration == 0 {
+		cluster.RoleDuration = p.RoleDuration
+	}
+	if cluster.Expiration == 0 {
+		cluster.Expiration = p.Expiration
+	}
+	return cluster, nil
+}
+
+// Validate validates every cluster in the profile with ClusterConfig.Validate.
+func (p *Profile) Validate() error {
+	var errs []error
+	for _, name := range p.ClusterNames() {
+		cluster, err := p.Cluster(name)
+		if err == nil {
+			err = cluster.Validate()
+		}
+		if err != nil {
+			errs = append(errs, &FieldError{Field: "clusters." + name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Profiles is the contents of a named configuration profiles file.
+type Profiles struct {
+	// Default is the name of the profile used when no profile is selected.
+	Default string `json:"default,omitempty"`
+	// Profiles maps profile names to their configuration.
+	Profiles map[string]*Profile `json:"profiles"`
+}
+
+// Get returns the named profile, if name is empty the Default profile is returned.
+func (p *Profiles) Get(name string) (*Profile, error) {
+	if name == "" {
+		name = p.Default
+	}
+	if name == "" {
+		return nil, fmt.Errorf("eksauth: no profile selected and no default profile configured")
+	}
+	profile, ok := p.Profiles[name]
+	if !ok || profile == nil {
+		return nil, fmt.Errorf("eksauth: profile %q not found", name)
+	}
+	return profile, nil
+}
+
+// Validate validates every profile with Profile.Validate.
+func (p *Profiles) Validate() error {
+	var errs []error
+	if p.Default != "" {
+		if _, ok := p.Profiles[p.Default]; !ok {
+			errs = append(errs, &FieldError{Field: "default", Err: fmt.Errorf("profile %q not found", p.Default)})
+		}
+	}
+	names := make([]string, 0, len(p.Profiles))
+	for name := range p.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if profile := p.Profiles[name]; profile != nil {
+			if err := profile.Validate(); err != nil {
+				errs = append(errs, &FieldError{Field: "profiles." + name, Err: err})
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ParseProfiles parses the JSON encoded contents of a profiles file.
+func ParseProfiles(data []byte) (*Profiles, error) {
+	var p Profiles
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("eksauth: failed to parse profiles: %w", err)
+	}
+	return &p, nil
+}
+
+// LoadProfiles reads and parses a profiles file from disk.
+func LoadProfiles(path string) (*Profiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseProfiles(data)
+}
+
+// ProfilesSchema is the JSON schema of the profiles file.
+//
+//go:embed profiles.schema.json
+var ProfilesSchema []byte
+
+// ConfigError is a configuration file error with the line and column (1-indexed) it occurred at.
+type ConfigError struct {
+	Path   string
+	Line   int
+	Column int
+	Err    error
+}
+
+// Error implements the error interface.
+func (e *ConfigError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "<input>"
+	}
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", path, e.Err)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", path, e.Line, e.Column, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// newConfigError creates a ConfigError for the byte offset in data, a negative offset is an unknown location.
+func newConfigError(path string, data []byte, offset int64, err error) *ConfigError {
+	if offset < 0 {
+		return &ConfigError{Path: path, Err: err}
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	line, column := 1, 1
+	for _, b := range data[:offset] {
+		if b == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return &ConfigError{Path: path, Line: line, Column: column, Err: err}
+}
+
+// unknownFieldRegexp extracts the field name from the error returned for unknown fields by json.Decoder.
+var unknownFieldRegexp = regexp.MustCompile(`^json: unknown field "(.+)"$`)
+
+// locateKey returns the offset of the first object key named key in data, or -1 if not found.
+func locateKey(data []byte, key string) int64 {
+	quoted, err := json.Marshal(key)
+	if err != nil {
+		return -1
+	}
+	loc := regexp.MustCompile(regexp.QuoteMeta(string(quoted)) + `\s*:`).FindIndex(data)
+	if loc == nil {
+		return -1
+	}
+	return int64(loc[0])
+}
+
+// parseProfilesStrict implements ParseProfilesStrict, path is only used for error messages.
+func parseProfilesStrict(path string, data []byte) (*Profiles, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var p Profiles
+	if err := dec.Decode(&p); err != nil {
+		offset := int64(-1)
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &syntaxErr) {
+			offset = syntaxErr.Offset
+		} else if errors.As(err, &typeErr) {
+			if typeErr.Offset > 0 {
+				offset = typeErr.Offset
+			} else if typeErr.Field != "" {
+				fields := strings.Split(typeErr.Field, ".")
+				offset = locateKey(data, fields[len(fields)-1])
+			}
+		} else if m := unknownFieldRegexp.FindStringSubmatch(err.Error()); m != nil {
+			offset = locateKey(data, m[1])
+		}
+		return nil, newConfigError(path, data, offset, err)
+	}
+	if dec.More() {
+		return nil, newConfigError(path, data, dec.InputOffset(), errors.New("unexpected data after the top-level object"))
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ParseProfilesStrict is ParseProfiles but rejects unknown keys, type mismatches and invalid values.
+// Decoding errors are returned as a *ConfigError containing the line and column of the problem.
+func ParseProfilesStrict(data []byte) (*Profiles, error) {
+	return parseProfilesStrict("", data)
+}
+
+// LoadProfilesStrict is LoadProfiles using ParseProfilesStrict.
+func LoadProfilesStrict(path string) (*Profiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseProfilesStrict(path, data)
+}
@@ -0,0 +1,94 @@
+package eksauth
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ProvenanceExtraKey is the oauth2.Token.Extra key containing the *Provenance of a generated token.
+const ProvenanceExtraKey = "eksauth.provenance"
+
+// credentialSourceSeparator separates the hops of a credential source chain in aws.Credentials.Source.
+const credentialSourceSeparator = " > "
+
+// Provenance records how a generated token came to exist, for audit purposes.
+type Provenance struct {
+	// ClusterName is the cluster the token was generated for.
+	ClusterName string
+	// CredentialSources is the chain of credential sources (aws.Credentials.Source), base credentials first.
+	CredentialSources []string
+	// RoleChain are the IAM roles assumed (by this package) to obtain the signing credentials, first hop first.
+	RoleChain []string
+	// SessionTags are the session tags of the last role assumed by this package, including the transitive tags of
+	// the previous hops. It is nil if no role was assumed, tags of sessions assumed outside this package are unknown.
+	SessionTags map[string]string
+	// AccessKeyID is the access key ID of the signing credentials.
+	AccessKeyID string
+	// CredentialsExpire is when the signing credentials expire, zero if they do not.
+	CredentialsExpire time.Time
+	// Region is the region the request was signed for.
+	Region string
+	// Host is the STS host the request was signed against.
+	Host string
+	// SigningTime is when the request was signed.
+	SigningTime time.Time
+	// Expiry is the expiry of the token.
+	Expiry time.Time
+	// Identity is the caller identity of the signing credentials, only set by WithCallerIdentity.
+	Identity *Identity
+}
+
+// setCredentials records the signing credentials source chain in the provenance.
+func (p *Provenance) setCredentials(source string, accessKeyID string, expires time.Time) {
+	p.AccessKeyID = accessKeyID
+	p.CredentialsExpire = expires
+	p.CredentialSources = nil
+	p.RoleChain = nil
+	p.SessionTags = nil
+	if source == "" {
+		return
+	}
+	var transitive map[string]bool
+	for _, hop := range strings.Split(source, credentialSourceSeparator) {
+		role, ok := strings.CutPrefix(hop, assumeRoleSourcePrefix)
+		if !ok {
+			p.CredentialSources = append(p.CredentialSources, hop)
+			continue
+		}
+		roleARN, encoded, _ := strings.Cut(role, "]")
+		p.CredentialSources = append(p.CredentialSources, assumeRoleSourcePrefix+roleARN+"]")
+		p.RoleChain = append(p.RoleChain, roleARN)
+		// Only the transitive tags of the previous session persist.
+		tags := make(map[string]string)
+		for key, value := range p.SessionTags {
+			if transitive[key] {
+				tags[key] = value
+			}
+		}
+		values, _ := url.ParseQuery(strings.TrimSuffix(strings.TrimPrefix(encoded, "{"), "}"))
+		for key := range values {
+			if tagKey, ok := strings.CutPrefix(key, "tag:"); ok {
+				tags[tagKey] = values.Get(key)
+			}
+		}
+		if transitive == nil {
+			transitive = make(map[string]bool)
+		}
+		for _, key := range values[transitiveTagsKey] {
+			transitive[key] = true
+		}
+		p.SessionTags = tags
+	}
+}
+
+// TokenProvenance returns the Provenance of a token generated by this package, if present.
+func TokenProvenance(t *oauth2.Token) (*Provenance, bool) {
+	if t == nil {
+		return nil, false
+	}
+	p, ok := t.Extra(ProvenanceExtraKey).(*Provenance)
+	return p, ok
+}
@@ -0,0 +1,119 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrRateLimited is matched (using errors.Is) by every *RateLimitedError.
+var ErrRateLimited = errors.New("eksauth: token generation was rate limited")
+
+// RateLimitedError is returned by a RateLimitedTokenSource when the token bucket of the cluster is empty.
+type RateLimitedError struct {
+	// ClusterName is the rate limited cluster.
+	ClusterName string
+	// RetryAfter is when the next token may be generated.
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("eksauth: token generation for %s was rate limited, retry after %s", e.ClusterName, e.RetryAfter)
+}
+
+// Is allows errors.Is(err, ErrRateLimited).
+func (e *RateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// Retryable reports that rate limited calls must not be retried immediately.
+func (e *RateLimitedError) Retryable() bool {
+	return false
+}
+
+// RateLimiter is a token bucket per cluster name limiting how often tokens are generated, ie to protect the
+// credential chain and STS from callers bypassing the token cache. It may be shared by many token sources.
+// It is safe for concurrent use.
+type RateLimiter struct {
+	// Every is the interval at which the bucket of a cluster refills by one.
+	Every time.Duration
+	// Burst is the size of the bucket of each cluster, 1 if zero.
+	Burst int
+
+	mu      sync.Mutex
+	buckets map[string]*rateBucket
+}
+
+// rateBucket is the state of the token bucket of a cluster.
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a RateLimiter allowing burst tokens per cluster, refilled one every interval.
+func NewRateLimiter(every time.Duration, burst int) *RateLimiter {
+	return &RateLimiter{Every: every, Burst: burst}
+}
+
+// Allow takes a token from the bucket of the cluster, if it is empty it returns false and the delay until
+// the next token is available.
+func (l *RateLimiter) Allow(clusterName string) (bool, time.Duration) {
+	if l.Every <= 0 {
+		return true, 0
+	}
+	burst := float64(max(l.Burst, 1))
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.buckets == nil {
+		l.buckets = make(map[string]*rateBucket)
+	}
+	b, ok := l.buckets[clusterName]
+	if !ok {
+		b = &rateBucket{tokens: burst, last: now}
+		l.buckets[clusterName] = b
+	}
+	b.tokens = min(burst, b.tokens+float64(now.Sub(b.last))/float64(l.Every))
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(l.Every))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// RateLimitedTokenSource is an oauth2.TokenSource failing with a *RateLimitedError (instead of calling Source)
+// when the bucket of ClusterName in Limiter is empty.
+type RateLimitedTokenSource struct {
+	Source      oauth2.TokenSource
+	Limiter     *RateLimiter
+	ClusterName string
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (s *RateLimitedTokenSource) Token() (*oauth2.Token, error) {
+	return s.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+func (s *RateLimitedTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	if ok, retryAfter := s.Limiter.Allow(s.ClusterName); !ok {
+		return nil, &RateLimitedError{ClusterName: s.ClusterName, RetryAfter: retryAfter}
+	}
+	return TokenWithContext(ctx, s.Source)
+}
+
+// Invalidate implements the Invalidator interface.
+func (s *RateLimitedTokenSource) Invalidate() {
+	Invalidate(s.Source)
+}
+
+// Close implements the io.Closer interface.
+func (s *RateLimitedTokenSource) Close() error {
+	return closeTokenSource(s.Source)
+}
@@ -0,0 +1,194 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// DefaultRefreshJitter is the maximum random duration a RefreshingTokenSource refreshes earlier than EarlyExpiry.
+var DefaultRefreshJitter = 30 * time.Second
+
+// DefaultRefreshRetryInterval is how long a RefreshingTokenSource waits before retrying a failed refresh.
+var DefaultRefreshRetryInterval = 5 * time.Second
+
+// RefreshingTokenSource is an oauth2.TokenSource that renews tokens in a background goroutine before they expire,
+// so that calls to Token never block on presigning or credential retrieval while it is running.
+// If the background refresh has not produced a valid token, Token falls back to fetching one synchronously.
+type RefreshingTokenSource struct {
+	Source oauth2.TokenSource
+	// EarlyExpiry is how long before expiry tokens are renewed, DefaultEarlyExpiry if zero.
+	EarlyExpiry time.Duration
+	// Jitter is the maximum random duration refreshes happen before EarlyExpiry, DefaultRefreshJitter if zero.
+	Jitter time.Duration
+	// RetryInterval is the delay before retrying a failed refresh, DefaultRefreshRetryInterval if zero. It is also
+	// the minimum delay between background refreshes.
+	RetryInterval time.Duration
+	// OnError is called (from the background goroutine) when a refresh fails, it may be nil.
+	OnError func(err error)
+
+	flight flightGroup
+	mu     sync.RWMutex
+	t      *oauth2.Token
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewRefreshingTokenSource creates a RefreshingTokenSource for src, call Start to begin refreshing.
+func NewRefreshingTokenSource(src oauth2.TokenSource) *RefreshingTokenSource {
+	return &RefreshingTokenSource{Source: src}
+}
+
+// earlyExpiry returns the configured early expiry or DefaultEarlyExpiry.
+func (s *RefreshingTokenSource) earlyExpiry() time.Duration {
+	if s.EarlyExpiry != 0 {
+		return s.EarlyExpiry
+	}
+	return DefaultEarlyExpiry
+}
+
+// valid reports if the token is usable for at least EarlyExpiry.
+func (s *RefreshingTokenSource) valid(t *oauth2.Token) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(s.earlyExpiry()).Before(t.Expiry)
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (s *RefreshingTokenSource) Token() (*oauth2.Token, error) {
+	return s.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the ContextTokenSource interface, ctx is only used if a synchronous refresh is required.
+func (s *RefreshingTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	s.mu.RLock()
+	t := s.t
+	s.mu.RUnlock()
+	if s.valid(t) {
+		return t, nil
+	}
+	return s.flight.do(ctx, s.refresh)
+}
+
+// refresh fetches a new token from Source and stores it.
+func (s *RefreshingTokenSource) refresh(ctx context.Context) (*oauth2.Token, error) {
+	return s.store(TokenWithContext(ctx, s.Source))
+}
+
+// forceRefresh fetches a new token from Source bypassing its cache (the New* constructors return caching token
+// sources, which would return the token being renewed) and stores it.
+func (s *RefreshingTokenSource) forceRefresh(ctx context.Context) (*oauth2.Token, error) {
+	return s.store(ForceRefresh(ctx, s.Source))
+}
+
+// store caches t if err is nil.
+func (s *RefreshingTokenSource) store(t *oauth2.Token, err error) (*oauth2.Token, error) {
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.t = t
+	s.mu.Unlock()
+	return t, nil
+}
+
+// Start fetches the initial token then starts the background refresh goroutine which runs until ctx is done
+// or Stop is called. It returns an error if the initial token could not be fetched or it is already started.
+func (s *RefreshingTokenSource) Start(ctx context.Context) error {
+	s.mu.RLock()
+	started := s.cancel != nil
+	s.mu.RUnlock()
+	if started {
+		return errors.New("eksauth: RefreshingTokenSource is already started")
+	}
+	t, err := s.refresh(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		cancel()
+		return errors.New("eksauth: RefreshingTokenSource is already started")
+	}
+	s.cancel, s.done = cancel, done
+	go s.run(ctx, t, done)
+	return nil
+}
+
+// next returns how long to wait before refreshing t.
+func (s *RefreshingTokenSource) next(t *oauth2.Token) time.Duration {
+	jitter := s.Jitter
+	if jitter == 0 {
+		jitter = DefaultRefreshJitter
+	}
+	d := time.Until(t.Expiry) - s.earlyExpiry()
+	if jitter > 0 {
+		d -= rand.N(jitter)
+	}
+	return max(d, 0)
+}
+
+// run is the background refresh loop, done is closed when it exits.
+func (s *RefreshingTokenSource) run(ctx context.Context, t *oauth2.Token, done chan struct{}) {
+	defer close(done)
+	retryInterval := s.RetryInterval
+	if retryInterval == 0 {
+		retryInterval = DefaultRefreshRetryInterval
+	}
+	wait := s.next(t)
+	for {
+		// Tokens without an expiry never need to be refreshed.
+		if t.Expiry.IsZero() {
+			<-ctx.Done()
+			return
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		next, err := s.flight.do(ctx, s.forceRefresh)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if s.OnError != nil {
+				s.OnError(err)
+			}
+			wait = retryInterval
+			continue
+		}
+		// A token expiring within EarlyExpiry (ie clamped to the credential expiry) is retried after RetryInterval
+		// instead of immediately.
+		t, wait = next, max(s.next(next), retryInterval)
+	}
+}
+
+// Stop stops the background refresh goroutine and waits for it to exit, the last token remains cached.
+// The RefreshingTokenSource may be started again after it is stopped.
+func (s *RefreshingTokenSource) Stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// Close implements the Lifecycle interface, it is equivalent to Stop.
+func (s *RefreshingTokenSource) Close() error {
+	s.Stop()
+	return nil
+}
@@ -0,0 +1,202 @@
+package eksauth
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"golang.org/x/oauth2"
+)
+
+// RegistryStats are the cumulative statistics of a Registry.
+type RegistryStats struct {
+	Hits      uint64
+	Misses    uint64
+	Errors    uint64
+	Evictions uint64
+}
+
+// registryEntry is an element of the Registry LRU list.
+type registryEntry[K comparable] struct {
+	key      K
+	ts       oauth2.TokenSource
+	lastUsed time.Time
+}
+
+// Registry maps arbitrary keys (cluster names, tenant IDs, ClusterConfig values...) to lazily constructed
+// oauth2.TokenSources, evicting the least recently used entries when it grows beyond MaxEntries.
+type Registry[K comparable] struct {
+	// New constructs the oauth2.TokenSource for a key that is not in the registry.
+	// It is called with the registry lock held so should not block.
+	New func(key K) (oauth2.TokenSource, error)
+	// MaxEntries is the maximum number of entries before the least recently used entry is evicted, zero is unlimited.
+	MaxEntries int
+	// IdleTimeout evicts entries that have not been used within the duration, zero disables idle eviction.
+	IdleTimeout time.Duration
+	// OnEvict is called (with the registry lock held) for every evicted entry, it may be nil.
+	OnEvict func(key K, ts oauth2.TokenSource)
+
+	mu      sync.Mutex
+	entries map[K]*list.Element
+	lru     list.List
+	stats   RegistryStats
+}
+
+// NewRegistry creates a new Registry from a constructor function.
+func NewRegistry[K comparable](newFn func(key K) (oauth2.TokenSource, error)) *Registry[K] {
+	return &Registry[K]{New: newFn}
+}
+
+// evict removes the element from the registry, the lock must be held.
+func (r *Registry[K]) evict(elem *list.Element) {
+	entry := r.lru.Remove(elem).(*registryEntry[K])
+	delete(r.entries, entry.key)
+	r.stats.Evictions++
+	if r.OnEvict != nil {
+		r.OnEvict(entry.key, entry.ts)
+	}
+}
+
+// evictIdle removes all entries that have been idle for longer than IdleTimeout, the lock must be held.
+func (r *Registry[K]) evictIdle(now time.Time) {
+	if r.IdleTimeout <= 0 {
+		return
+	}
+	for elem := r.lru.Back(); elem != nil; elem = r.lru.Back() {
+		if now.Sub(elem.Value.(*registryEntry[K]).lastUsed) < r.IdleTimeout {
+			return
+		}
+		r.evict(elem)
+	}
+}
+
+// Get returns the oauth2.TokenSource for the key, constructing it if required.
+func (r *Registry[K]) Get(key K) (oauth2.TokenSource, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.evictIdle(now)
+	if elem, ok := r.entries[key]; ok {
+		r.stats.Hits++
+		r.lru.MoveToFront(elem)
+		entry := elem.Value.(*registryEntry[K])
+		entry.lastUsed = now
+		return entry.ts, nil
+	}
+	r.stats.Misses++
+	if r.New == nil {
+		r.stats.Errors++
+		return nil, errors.New("eksauth: Registry.New is nil")
+	}
+	ts, err := r.New(key)
+	if err != nil {
+		r.stats.Errors++
+		return nil, err
+	}
+	if r.entries == nil {
+		r.entries = make(map[K]*list.Element)
+	}
+	r.entries[key] = r.lru.PushFront(&registryEntry[K]{key: key, ts: ts, lastUsed: now})
+	if r.MaxEntries > 0 {
+		for r.lru.Len() > r.MaxEntries {
+			r.evict(r.lru.Back())
+		}
+	}
+	return ts, nil
+}
+
+// Token returns a token from the oauth2.TokenSource for the key.
+func (r *Registry[K]) Token(key K) (*oauth2.Token, error) {
+	ts, err := r.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return ts.Token()
+}
+
+// Delete removes the key from the registry, it is a no-op if the key is not present.
+func (r *Registry[K]) Delete(key K) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if elem, ok := r.entries[key]; ok {
+		r.evict(elem)
+	}
+}
+
+// Len returns the number of entries in the registry.
+func (r *Registry[K]) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lru.Len()
+}
+
+// Keys returns the keys in the registry from most to least recently used.
+func (r *Registry[K]) Keys() []K {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := make([]K, 0, r.lru.Len())
+	for elem := r.lru.Front(); elem != nil; elem = elem.Next() {
+		keys = append(keys, elem.Value.(*registryEntry[K]).key)
+	}
+	return keys
+}
+
+// Range calls fn for each entry from most to least recently used until fn returns false.
+// The registry lock is held while iterating so fn must not call other methods of the registry.
+func (r *Registry[K]) Range(fn func(key K, ts oauth2.TokenSource) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for elem := r.lru.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*registryEntry[K])
+		if !fn(entry.key, entry.ts) {
+			return
+		}
+	}
+}
+
+// Stats returns the cumulative statistics of the registry.
+func (r *Registry[K]) Stats() RegistryStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// NewClusterRegistry creates a Registry keyed by cluster name from an aws.Config.
+// Clusters with an entry in clusters use that ClusterConfig, pinning the signing region (Region) and
+// STS host (STSEndpoint) of their tokens independently of the region of cfg. Other cluster names use cfg as-is.
+func NewClusterRegistry(cfg aws.Config, clusters []ClusterConfig, opts ...Option) *Registry[string] {
+	byName := make(map[string]ClusterConfig, len(clusters))
+	for _, cluster := range clusters {
+		byName[cluster.Name] = cluster
+	}
+	return NewRegistry(func(name string) (oauth2.TokenSource, error) {
+		cluster, ok := byName[name]
+		if !ok {
+			cluster = ClusterConfig{Name: name}
+		}
+		return NewFromClusterConfig(cfg, cluster, opts...)
+	})
+}
+
+// Start implements the Lifecycle interface, the registry has no background work so it is a no-op.
+func (r *Registry[K]) Start(ctx context.Context) error {
+	return nil
+}
+
+// Close evicts every entry (calling OnEvict) and closes the evicted token sources that implement io.Closer.
+func (r *Registry[K]) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var errs []error
+	for elem := r.lru.Back(); elem != nil; elem = r.lru.Back() {
+		ts := elem.Value.(*registryEntry[K]).ts
+		r.evict(elem)
+		if err := closeTokenSource(ts); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,143 @@
+package eksauth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"golang.org/x/oauth2"
+)
+
+// DefaultReloadInterval is the minimum interval between checks of the shared config files for changes.
+var DefaultReloadInterval = 2 * time.Second
+
+// sharedConfigPaths returns the files and directories whose changes trigger a reload.
+func sharedConfigPaths() []string {
+	configFile := os.Getenv("AWS_CONFIG_FILE")
+	if configFile == "" {
+		configFile = config.DefaultSharedConfigFilename()
+	}
+	credentialsFile := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if credentialsFile == "" {
+		credentialsFile = config.DefaultSharedCredentialsFilename()
+	}
+	return []string{
+		configFile,
+		credentialsFile,
+		filepath.Join(filepath.Dir(config.DefaultSharedConfigFilename()), "sso", "cache"),
+	}
+}
+
+// fingerprint returns a string that changes whenever any of the paths (or files directly within them) change.
+func fingerprint(paths []string) string {
+	var parts []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			parts = append(parts, path+":missing")
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano()))
+		if !info.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil {
+				parts = append(parts, fmt.Sprintf("%s:%d:%d", entry.Name(), info.Size(), info.ModTime().UnixNano()))
+			}
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "|")
+}
+
+// ReloadingTokenSource is an oauth2.TokenSource that reloads the aws.Config (using config.LoadDefaultConfig)
+// and rebuilds the underlying token source whenever ~/.aws/config, ~/.aws/credentials or the SSO cache change,
+// so running `aws sso login` in another terminal fixes a long-running process without restarting it.
+// Changes are detected by polling (at most every Interval) when Token is called, no goroutines are started.
+type ReloadingTokenSource struct {
+	// ClusterName is the EKS cluster name.
+	ClusterName string
+	// LoadOptions are passed to config.LoadDefaultConfig.
+	LoadOptions []func(*config.LoadOptions) error
+	// New creates the token source from the loaded aws.Config, NewFromConfig if nil.
+	New func(cfg aws.Config, clusterName string) oauth2.TokenSource
+	// Interval overrides DefaultReloadInterval if non-zero.
+	Interval time.Duration
+
+	mu          sync.Mutex
+	current     oauth2.TokenSource
+	fingerprint string
+	lastCheck   time.Time
+}
+
+// NewReloading creates a ReloadingTokenSource, loading the initial aws.Config.
+func NewReloading(ctx context.Context, clusterName string, optFns ...func(*config.LoadOptions) error) (*ReloadingTokenSource, error) {
+	r := &ReloadingTokenSource{
+		ClusterName: clusterName,
+		LoadOptions: optFns,
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.reload(ctx, fingerprint(sharedConfigPaths())); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload loads the aws.Config and rebuilds the token source, the lock must be held.
+func (r *ReloadingTokenSource) reload(ctx context.Context, fp string) error {
+	cfg, err := config.LoadDefaultConfig(ctx, r.LoadOptions...)
+	if err != nil {
+		return err
+	}
+	newFn := r.New
+	if newFn == nil {
+		newFn = func(cfg aws.Config, clusterName string) oauth2.TokenSource {
+			return NewFromConfig(cfg, clusterName)
+		}
+	}
+	if r.current != nil {
+		_ = closeTokenSource(r.current)
+	}
+	r.current = newFn(cfg, r.ClusterName)
+	r.fingerprint = fp
+	return nil
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (r *ReloadingTokenSource) Token() (*oauth2.Token, error) {
+	return r.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+func (r *ReloadingTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	r.mu.Lock()
+	interval := r.Interval
+	if interval == 0 {
+		interval = DefaultReloadInterval
+	}
+	if r.current == nil || time.Since(r.lastCheck) >= interval {
+		r.lastCheck = time.Now()
+		if fp := fingerprint(sharedConfigPaths()); r.current == nil || fp != r.fingerprint {
+			if err := r.reload(ctx, fp); err != nil && r.current == nil {
+				r.mu.Unlock()
+				return nil, err
+			}
+		}
+	}
+	current := r.current
+	r.mu.Unlock()
+	return TokenWithContext(ctx, current)
+}
@@ -0,0 +1,94 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// RetryPolicy configures retrying transient token generation failures, ie IMDS timeouts or throttled SSO refreshes.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts (including the first), values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled (with full jitter) for every subsequent retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts, zero is unlimited.
+	MaxDelay time.Duration
+	// Retryable classifies errors, DefaultRetryable if nil.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy is the RetryPolicy used by WithRetry when no policy is provided.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+}
+
+// DefaultRetryable retries errors classified as retryable by IsRetryable (including throttling),
+// except expired credentials which require re-authentication.
+func DefaultRetryable(err error) bool {
+	return !errors.Is(err, ErrCredentialsExpired) && (errors.Is(err, ErrThrottled) || IsRetryable(err))
+}
+
+// delay returns the (jittered) delay before the retry following attempt (1-indexed).
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d) + 1
+}
+
+// RetryTokenSource is an oauth2.TokenSource that retries failed calls to Source according to Policy.
+type RetryTokenSource struct {
+	Source oauth2.TokenSource
+	Policy RetryPolicy
+}
+
+// NewRetryTokenSource creates a RetryTokenSource wrapping src.
+func NewRetryTokenSource(src oauth2.TokenSource, policy RetryPolicy) *RetryTokenSource {
+	return &RetryTokenSource{Source: src, Policy: policy}
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (s *RetryTokenSource) Token() (*oauth2.Token, error) {
+	return s.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the ContextTokenSource interface, ctx bounds every attempt and the delays between them.
+func (s *RetryTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	retryable := s.Policy.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+	for attempt := 1; ; attempt++ {
+		t, err := TokenWithContext(ctx, s.Source)
+		if err == nil || attempt >= s.Policy.MaxAttempts || ctx.Err() != nil || !retryable(err) {
+			return t, err
+		}
+		timer := time.NewTimer(s.Policy.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// Invalidate implements the Invalidator interface.
+func (s *RetryTokenSource) Invalidate() {
+	Invalidate(s.Source)
+}
+
+// Close implements the io.Closer interface.
+func (s *RetryTokenSource) Close() error {
+	return closeTokenSource(s.Source)
+}
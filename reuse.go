@@ -0,0 +1,207 @@
+package eksauth
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ReuseTokenSource is an oauth2.TokenSource that caches a token until it (early) expires.
+// It behaves like oauth2.ReuseTokenSourceWithExpiry but allows the cached token to be inspected.
+//
+// It is safe for concurrent use. Concurrent calls to Token that find the cached token expired are collapsed into
+// a single refresh of the underlying source, callers waiting on the refresh share its result (including errors).
+// The inspection methods (Peek, Valid, Stats...) never block on an in-flight refresh.
+type ReuseTokenSource struct {
+	new         oauth2.TokenSource
+	earlyExpiry time.Duration
+	jitter      time.Duration
+	skew        *ClockSkew
+	ctx         context.Context
+	flight      flightGroup
+
+	mu     sync.Mutex
+	t      *oauth2.Token
+	offset time.Duration
+	stats  ReuseStats
+}
+
+// ReuseStats are the cumulative statistics of a ReuseTokenSource.
+type ReuseStats struct {
+	// Hits is the number of calls to Token that returned the cached token.
+	Hits uint64
+	// Refreshes is the number of successful token refreshes.
+	Refreshes uint64
+	// Failures is the number of failed token refreshes.
+	Failures uint64
+	// LastRefresh is when the last refresh was attempted, zero if never.
+	LastRefresh time.Time
+	// LastError is the error of the last refresh attempt, nil if it succeeded.
+	LastError error
+}
+
+// NewReuseTokenSource creates a ReuseTokenSource that returns t until it is within earlyExpiry of expiring,
+// at which point a new token is retrieved from src. t may be nil.
+func NewReuseTokenSource(t *oauth2.Token, src oauth2.TokenSource, earlyExpiry time.Duration) *ReuseTokenSource {
+	return &ReuseTokenSource{
+		new:         src,
+		earlyExpiry: earlyExpiry,
+		t:           t,
+	}
+}
+
+// NewJitteredReuseTokenSource is NewReuseTokenSource where each token is refreshed a random duration in [0, jitter)
+// earlier than earlyExpiry, so many processes started at the same time do not all refresh at the same instant.
+func NewJitteredReuseTokenSource(t *oauth2.Token, src oauth2.TokenSource, earlyExpiry, jitter time.Duration) *ReuseTokenSource {
+	s := NewReuseTokenSource(t, src, earlyExpiry)
+	s.jitter = jitter
+	s.offset = s.randomOffset()
+	return s
+}
+
+// reuseTokenSource returns the *ReuseTokenSource returned by the New* constructors, unwrapping the guards
+// added by WithMinValidity and WithRefreshOnCredentialRotation.
+func reuseTokenSource(ts oauth2.TokenSource) (*ReuseTokenSource, bool) {
+	if guard, ok := ts.(*MinValidityTokenSource); ok {
+		ts = guard.Source
+	}
+	if guard, ok := ts.(*RotationTokenSource); ok {
+		ts = guard.Source
+	}
+	reuse, ok := ts.(*ReuseTokenSource)
+	return reuse, ok
+}
+
+// randomOffset returns a random duration in [0, jitter).
+func (s *ReuseTokenSource) randomOffset() time.Duration {
+	if s.jitter <= 0 {
+		return 0
+	}
+	return rand.N(s.jitter)
+}
+
+// valid reports if the token is usable for at least earlyExpiry (plus the jitter offset), the lock must be held.
+func (s *ReuseTokenSource) valid(t *oauth2.Token) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	if t.Expiry.IsZero() {
+		return true
+	}
+	return time.Now().Add(s.earlyExpiry + s.offset + s.skew.margin()).Before(t.Expiry)
+}
+
+// SetClockSkew widens the early expiry by the skew of c once it reaches its threshold, see ClockSkew.
+func (s *ReuseTokenSource) SetClockSkew(c *ClockSkew) {
+	s.mu.Lock()
+	s.skew = c
+	s.mu.Unlock()
+}
+
+// SetContext sets the base context used by Token to refresh the token, context.Background() if nil.
+func (s *ReuseTokenSource) SetContext(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+}
+
+// Token implements the oauth2.TokenSource interface using the base context (see SetContext).
+func (s *ReuseTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	ctx := s.ctx
+	s.mu.Unlock()
+	return s.TokenWithContext(baseContext(ctx))
+}
+
+// TokenWithContext implements the ContextTokenSource interface, ctx is only used if a refresh is required.
+func (s *ReuseTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	s.mu.Lock()
+	if t := s.t; s.valid(t) {
+		s.stats.Hits++
+		s.mu.Unlock()
+		return t, nil
+	}
+	s.mu.Unlock()
+	return s.flight.do(ctx, s.refresh)
+}
+
+// refresh retrieves a new token from the underlying source and caches it.
+func (s *ReuseTokenSource) refresh(ctx context.Context) (*oauth2.Token, error) {
+	t, err := TokenWithContext(ctx, s.new)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.LastRefresh = time.Now()
+	s.stats.LastError = err
+	if err != nil {
+		s.stats.Failures++
+		return nil, err
+	}
+	s.stats.Refreshes++
+	s.t = t
+	s.offset = s.randomOffset()
+	return t, nil
+}
+
+// Stats returns the cumulative statistics of the ReuseTokenSource.
+func (s *ReuseTokenSource) Stats() ReuseStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Peek returns the currently cached token without triggering a refresh, ok is false if no token is cached.
+// The returned token may be expired, use Valid to check if it would be reused by Token.
+func (s *ReuseTokenSource) Peek() (t *oauth2.Token, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.t, s.t != nil
+}
+
+// Valid reports if the cached token would be returned by Token without a refresh.
+func (s *ReuseTokenSource) Valid() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.valid(s.t)
+}
+
+// Expiry returns the expiry of the cached token, or the zero time.Time if no token is cached.
+func (s *ReuseTokenSource) Expiry() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.t == nil {
+		return time.Time{}
+	}
+	return s.t.Expiry
+}
+
+// NextRefresh returns the time after which the next call to Token will refresh the cached token.
+// It is the zero time.Time if no token is cached (or the token never expires).
+func (s *ReuseTokenSource) NextRefresh() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.t == nil || s.t.Expiry.IsZero() {
+		return time.Time{}
+	}
+	return s.t.Expiry.Add(-s.earlyExpiry - s.offset - s.skew.margin())
+}
+
+// Invalidate implements the Invalidator interface, the cached token is discarded.
+func (s *ReuseTokenSource) Invalidate() {
+	s.mu.Lock()
+	s.t = nil
+	s.mu.Unlock()
+	Invalidate(s.new)
+}
+
+// Close implements the io.Closer interface.
+func (s *ReuseTokenSource) Close() error {
+	return closeTokenSource(s.new)
+}
+
+// ForceRefresh discards the cached token and immediately generates (and caches) a new token.
+func (s *ReuseTokenSource) ForceRefresh(ctx context.Context) (*oauth2.Token, error) {
+	return ForceRefresh(ctx, s)
+}
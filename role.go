@@ -0,0 +1,331 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/aws/smithy-go"
+	"golang.org/x/oauth2"
+)
+
+// MinRoleDuration and MaxRoleDuration are the bounds STS enforces on the DurationSeconds of an assumed role session.
+const (
+	MinRoleDuration = 15 * time.Minute
+	MaxRoleDuration = 12 * time.Hour
+)
+
+// WithRoleDuration sets the duration of the assumed role session, clamped to [MinRoleDuration, MaxRoleDuration].
+// If the duration exceeds the MaxSessionDuration of the role, the session falls back to the longest whole number of
+// hours accepted by the role (or stscreds.DefaultDuration), which is then used for every later session.
+func WithRoleDuration(d time.Duration) func(*stscreds.AssumeRoleOptions) {
+	if d < MinRoleDuration {
+		d = MinRoleDuration
+	} else if d > MaxRoleDuration {
+		d = MaxRoleDuration
+	}
+	return func(opts *stscreds.AssumeRoleOptions) {
+		opts.Duration = d
+	}
+}
+
+// WithRoleSessionName sets the session name of the assumed role session, which appears in CloudTrail
+// and in the assumed-role ARN (and therefore Kubernetes username) of the caller.
+func WithRoleSessionName(name string) func(*stscreds.AssumeRoleOptions) {
+	return func(opts *stscreds.AssumeRoleOptions) {
+		opts.RoleSessionName = name
+	}
+}
+
+// WithRolePolicy sets an inline session policy further restricting the permissions of the assumed role session.
+func WithRolePolicy(policy string) func(*stscreds.AssumeRoleOptions) {
+	return func(opts *stscreds.AssumeRoleOptions) {
+		opts.Policy = aws.String(policy)
+	}
+}
+
+// WithRoleExternalID sets the external ID required by the trust policy of the role, ie for third-party
+// cross-account access.
+func WithRoleExternalID(externalID string) func(*stscreds.AssumeRoleOptions) {
+	return func(opts *stscreds.AssumeRoleOptions) {
+		opts.ExternalID = aws.String(externalID)
+	}
+}
+
+// WithRoleTags sets the session tags of the assumed role session, ie for aws-auth templates or ABAC policies.
+func WithRoleTags(tags map[string]string) func(*stscreds.AssumeRoleOptions) {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return func(opts *stscreds.AssumeRoleOptions) {
+		for _, key := range keys {
+			opts.Tags = append(opts.Tags, ststypes.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+		}
+	}
+}
+
+// WithRoleTransitiveTagKeys marks session tags as transitive, so they persist when the session assumes another role.
+func WithRoleTransitiveTagKeys(keys ...string) func(*stscreds.AssumeRoleOptions) {
+	return func(opts *stscreds.AssumeRoleOptions) {
+		opts.TransitiveTagKeys = append(opts.TransitiveTagKeys, keys...)
+	}
+}
+
+// WithSourceIdentity sets the source identity of the assumed role session, which is recorded in CloudTrail and
+// persists across role chaining.
+func WithSourceIdentity(sourceIdentity string) func(*stscreds.AssumeRoleOptions) {
+	return func(opts *stscreds.AssumeRoleOptions) {
+		opts.SourceIdentity = aws.String(sourceIdentity)
+	}
+}
+
+// WithRoleMFA sets the serial number (or ARN) of the MFA device required by the trust policy of the role and the
+// function returning the current code of the device, ie stscreds.StdinTokenProvider. The function is only
+// called when the role is (re-)assumed.
+func WithRoleMFA(serialNumber string, tokenProvider func() (string, error)) func(*stscreds.AssumeRoleOptions) {
+	return func(opts *stscreds.AssumeRoleOptions) {
+		opts.SerialNumber = aws.String(serialNumber)
+		opts.TokenProvider = tokenProvider
+	}
+}
+
+// assumeRoleSourcePrefix prefixes the role ARN in the aws.Credentials.Source of assumed role credentials.
+const assumeRoleSourcePrefix = "AssumeRoleProvider["
+
+// transitiveTagsKey is the key of the transitive tag keys in the session tags of a credential source hop.
+const transitiveTagsKey = "transitive"
+
+// sessionTagsSuffix encodes the session tags (and transitive tag keys) of an assumed role session, appended to its
+// hop of the aws.Credentials.Source (after the "]") so the Provenance of tokens records them.
+func sessionTagsSuffix(tags []ststypes.Tag, transitive []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	values := url.Values{}
+	for _, tag := range tags {
+		values.Set("tag:"+aws.ToString(tag.Key), aws.ToString(tag.Value))
+	}
+	for _, key := range transitive {
+		values.Add(transitiveTagsKey, key)
+	}
+	return "{" + values.Encode() + "}"
+}
+
+// assumeRoleProvider wraps stscreds.AssumeRoleProvider retrying with shorter durations if the requested duration
+// exceeds the MaxSessionDuration of the role, the first accepted duration is used for every later session.
+// The aws.Credentials.Source of the credentials records the chain of sources they were obtained from.
+type assumeRoleProvider struct {
+	roleARN string
+	tags    string
+	parent  aws.CredentialsProvider
+	client  stscreds.AssumeRoleAPIClient
+	optFns  []func(*stscreds.AssumeRoleOptions)
+	// mfa is set if the role requires an MFA code, which is prompted for on every attempt.
+	mfa bool
+
+	mu       sync.Mutex
+	duration time.Duration
+	provider *stscreds.AssumeRoleProvider
+}
+
+// fallbackDurations returns the durations to retry when d exceeds the MaxSessionDuration of the role, longest first:
+// whole hours below d (MaxSessionDuration is at least an hour) then stscreds.DefaultDuration. Only an hour is tried
+// for roles requiring MFA, so the user is not prompted for a code on every attempt.
+func fallbackDurations(d time.Duration, mfa bool) []time.Duration {
+	var durations []time.Duration
+	start := d.Truncate(time.Hour)
+	if start == d {
+		start -= time.Hour
+	}
+	if mfa {
+		start = min(start, time.Hour)
+	}
+	for candidate := start; candidate >= time.Hour; candidate -= time.Hour {
+		durations = append(durations, candidate)
+	}
+	if stscreds.DefaultDuration < d {
+		durations = append(durations, stscreds.DefaultDuration)
+	}
+	return durations
+}
+
+// retrieve assumes the role with the accepted duration, falling back to shorter durations (see fallbackDurations).
+func (p *assumeRoleProvider) retrieve(ctx context.Context) (aws.Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	creds, err := p.provider.Retrieve(ctx)
+	if err == nil || !isDurationError(err) {
+		return creds, err
+	}
+	for _, d := range fallbackDurations(p.duration, p.mfa) {
+		provider := stscreds.NewAssumeRoleProvider(p.client, p.roleARN, append(p.optFns, func(opts *stscreds.AssumeRoleOptions) {
+			opts.Duration = d
+		})...)
+		fallback, fallbackErr := provider.Retrieve(ctx)
+		if fallbackErr == nil {
+			p.duration, p.provider = d, provider
+			return fallback, nil
+		}
+		if !isDurationError(fallbackErr) {
+			return fallback, fallbackErr
+		}
+	}
+	return creds, err
+}
+
+// Retrieve implements the aws.CredentialsProvider interface.
+func (p *assumeRoleProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	creds, err := p.retrieve(ctx)
+	if err != nil {
+		return creds, wrapThrottled("AssumeRole", err)
+	}
+	creds.Source = assumeRoleSourcePrefix + p.roleARN + "]" + p.tags
+	if p.parent != nil {
+		// The parent credentials are cached, so this does not result in another call
+		if parent, err := p.parent.Retrieve(ctx); err == nil && parent.Source != "" {
+			creds.Source = parent.Source + credentialSourceSeparator + creds.Source
+		}
+	}
+	return creds, nil
+}
+
+// isDurationError reports if err is the ValidationError returned when DurationSeconds exceeds MaxSessionDuration.
+func isDurationError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.ErrorCode() == "ValidationError" && strings.Contains(apiErr.ErrorMessage(), "DurationSeconds")
+}
+
+// AssumeRoleCredentials returns a cached aws.CredentialsProvider that assumes roleARN using the credentials of cfg.
+func AssumeRoleCredentials(cfg aws.Config, roleARN string, optFns ...func(*stscreds.AssumeRoleOptions)) aws.CredentialsProvider {
+	client := sts.NewFromConfig(cfg, addUserAgent, func(o *sts.Options) {
+		// The retryer of cfg, if configured, takes precedence.
+		if cfg.Retryer == nil {
+			o.Retryer = NewRetryer()
+		}
+	})
+	opts := stscreds.AssumeRoleOptions{Duration: stscreds.DefaultDuration}
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+	return aws.NewCredentialsCache(&assumeRoleProvider{
+		roleARN:  roleARN,
+		tags:     sessionTagsSuffix(opts.Tags, opts.TransitiveTagKeys),
+		parent:   cfg.Credentials,
+		client:   client,
+		optFns:   optFns,
+		mfa:      opts.SerialNumber != nil,
+		duration: opts.Duration,
+		provider: stscreds.NewAssumeRoleProvider(client, roleARN, optFns...),
+	})
+}
+
+// RoleSpec is a single hop of a role chain.
+type RoleSpec struct {
+	// RoleARN is the IAM role to assume.
+	RoleARN string
+	// Duration is the duration of the assumed session, see WithRoleDuration.
+	Duration time.Duration
+	// ExternalID is the external ID required by the trust policy of the role, if any.
+	ExternalID string
+	// SessionName is the session name of the assumed session, see WithRoleSessionName.
+	SessionName string
+	// Tags are the session tags of the assumed session, see WithRoleTags.
+	Tags map[string]string
+	// TransitiveTagKeys are the session tags that persist when the session assumes the next hop.
+	TransitiveTagKeys []string
+	// SourceIdentity is the source identity of the assumed session, see WithSourceIdentity.
+	SourceIdentity string
+	// SerialNumber is the MFA device required by the trust policy of the role, see WithRoleMFA.
+	SerialNumber string
+	// TokenProvider returns the current code of the MFA device, it is required if SerialNumber is set.
+	TokenProvider func() (string, error)
+	// Options are additional options for the stscreds.AssumeRoleProvider of this hop.
+	Options []func(*stscreds.AssumeRoleOptions)
+}
+
+// options returns the stscreds.AssumeRoleOptions functions for the hop.
+func (spec RoleSpec) options() []func(*stscreds.AssumeRoleOptions) {
+	optFns := make([]func(*stscreds.AssumeRoleOptions), 0, len(spec.Options)+7)
+	if spec.Duration != 0 {
+		optFns = append(optFns, WithRoleDuration(spec.Duration))
+	}
+	if spec.ExternalID != "" {
+		optFns = append(optFns, WithRoleExternalID(spec.ExternalID))
+	}
+	if spec.SessionName != "" {
+		optFns = append(optFns, WithRoleSessionName(spec.SessionName))
+	}
+	if len(spec.Tags) > 0 {
+		optFns = append(optFns, WithRoleTags(spec.Tags))
+	}
+	if len(spec.TransitiveTagKeys) > 0 {
+		optFns = append(optFns, WithRoleTransitiveTagKeys(spec.TransitiveTagKeys...))
+	}
+	if spec.SourceIdentity != "" {
+		optFns = append(optFns, WithSourceIdentity(spec.SourceIdentity))
+	}
+	if spec.SerialNumber != "" {
+		optFns = append(optFns, WithRoleMFA(spec.SerialNumber, spec.TokenProvider))
+	}
+	return append(optFns, spec.Options...)
+}
+
+// AssumeRoleChainCredentials returns an aws.CredentialsProvider that assumes each role in the chain
+// using the credentials of the previous hop, starting with the credentials of cfg.
+// Each hop is cached independently, so a hop is only re-assumed once its own credentials expire.
+// NOTE: STS limits the duration of chained role sessions to 1 hour.
+func AssumeRoleChainCredentials(cfg aws.Config, chain []RoleSpec) aws.CredentialsProvider {
+	for _, spec := range chain {
+		cfg.Credentials = AssumeRoleCredentials(cfg, spec.RoleARN, spec.options()...)
+	}
+	return cfg.Credentials
+}
+
+// parseRoleChain parses a comma separated list of role ARNs into a role chain.
+func parseRoleChain(s string, duration time.Duration) []RoleSpec {
+	var chain []RoleSpec
+	for _, roleARN := range strings.Split(s, ",") {
+		if roleARN = strings.TrimSpace(roleARN); roleARN != "" {
+			chain = append(chain, RoleSpec{RoleARN: roleARN, Duration: duration})
+		}
+	}
+	return chain
+}
+
+// NewFromRole creates a new oauth2.TokenSource that assumes roleARN (using the credentials of cfg)
+// before generating tokens for the EKS cluster. The role takes precedence over EKSAUTH_ROLE_ARN.
+// Use WithAssumeRoleOptions to set the session name, duration, external ID, policy, tags or source identity
+// of the assumed role session.
+func NewFromRole(cfg aws.Config, roleARN, clusterName string, opts ...Option) oauth2.TokenSource {
+	env := loadEnv()
+	env.RoleARN = ""
+	o := newOptions(env, opts)
+	o.applyConfig(&cfg)
+	cfg.Credentials = AssumeRoleCredentials(cfg, roleARN, o.assumeRoleOptions...)
+	return newFromPresignClient(presignClientFromConfig(cfg, env, o), clusterName, env, o)
+}
+
+// NewFromRoleChain creates a new oauth2.TokenSource that assumes each role in the chain (see AssumeRoleChainCredentials)
+// before generating tokens for the EKS cluster, ie a bastion account role followed by a target account role.
+// The chain takes precedence over EKSAUTH_ROLE_ARN.
+func NewFromRoleChain(cfg aws.Config, chain []RoleSpec, clusterName string, opts ...Option) oauth2.TokenSource {
+	env := loadEnv()
+	env.RoleARN = ""
+	o := newOptions(env, opts)
+	o.applyConfig(&cfg)
+	cfg.Credentials = AssumeRoleChainCredentials(cfg, chain)
+	return newFromPresignClient(presignClientFromConfig(cfg, env, o), clusterName, env, o)
+}
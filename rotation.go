@@ -0,0 +1,57 @@
+package eksauth
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"golang.org/x/oauth2"
+)
+
+// RotationTokenSource is an oauth2.TokenSource that discards the cached token of Source when Credentials produce
+// credentials with a different access key ID than the one that signed it (ie after IMDS rotation or an SSO
+// refresh), so tokens are never signed by credentials that may have since been revoked.
+// Credentials should be cached (aws.CredentialsCache), it is retrieved on every call to Token.
+type RotationTokenSource struct {
+	Source      oauth2.TokenSource
+	Credentials aws.CredentialsProvider
+	// Context is the base context used by Token, context.Background() if nil.
+	Context context.Context
+}
+
+// NewRotationTokenSource wraps src in a RotationTokenSource, credentials must be the provider signing the tokens of src.
+func NewRotationTokenSource(src oauth2.TokenSource, credentials aws.CredentialsProvider) *RotationTokenSource {
+	return &RotationTokenSource{Source: src, Credentials: credentials}
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (s *RotationTokenSource) Token() (*oauth2.Token, error) {
+	return s.TokenWithContext(baseContext(s.Context))
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+// If the credentials cannot be retrieved the cached token is returned, it may still be valid.
+func (s *RotationTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	t, err := TokenWithContext(ctx, s.Source)
+	if err != nil {
+		return nil, err
+	}
+	provenance, ok := TokenProvenance(t)
+	if !ok || provenance.AccessKeyID == "" {
+		return t, nil
+	}
+	creds, err := s.Credentials.Retrieve(ctx)
+	if err != nil || creds.AccessKeyID == provenance.AccessKeyID {
+		return t, nil
+	}
+	return ForceRefresh(ctx, s.Source)
+}
+
+// Invalidate implements the Invalidator interface.
+func (s *RotationTokenSource) Invalidate() {
+	Invalidate(s.Source)
+}
+
+// Close implements the io.Closer interface.
+func (s *RotationTokenSource) Close() error {
+	return closeTokenSource(s.Source)
+}
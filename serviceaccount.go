@@ -0,0 +1,100 @@
+package eksauth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// ErrWebIdentityTokenExpired is returned when the web identity (service account) token is already expired,
+// ie the kubelet stopped rotating a projected token file.
+var ErrWebIdentityTokenExpired = errors.New("eksauth: web identity token is expired")
+
+// ServiceAccountTokenFile returns a stscreds.IdentityTokenRetriever reading a (projected) Kubernetes service account
+// token from path. The file is re-read on every call so tokens rotated by the kubelet are picked up, and tokens whose
+// JWT "exp" claim is in the past are rejected with ErrWebIdentityTokenExpired instead of being sent to STS.
+func ServiceAccountTokenFile(path string) stscreds.IdentityTokenRetriever {
+	return serviceAccountTokenFile(path)
+}
+
+// serviceAccountTokenFile implements ServiceAccountTokenFile.
+type serviceAccountTokenFile string
+
+// GetIdentityToken implements the stscreds.IdentityTokenRetriever interface.
+func (path serviceAccountTokenFile) GetIdentityToken() ([]byte, error) {
+	token, err := os.ReadFile(string(path))
+	if err != nil {
+		return nil, fmt.Errorf("eksauth: failed to read web identity token: %w", err)
+	}
+	token = bytes.TrimSpace(token)
+	if len(token) == 0 {
+		return nil, fmt.Errorf("eksauth: web identity token file %s is empty", path)
+	}
+	if exp, ok := jwtExpiry(token); ok && !time.Now().Before(exp) {
+		return nil, fmt.Errorf("%w: %s expired at %s", ErrWebIdentityTokenExpired, path, exp.Format(time.RFC3339))
+	}
+	return token, nil
+}
+
+// jwtClaims are the claims of a JWT used by this package.
+type jwtClaims struct {
+	Exp int64  `json:"exp"`
+	Iss string `json:"iss"`
+}
+
+// parseJWTClaims returns the claims of a JWT without verifying it, ok is false if it cannot be parsed.
+func parseJWTClaims(token []byte) (claims jwtClaims, ok bool) {
+	parts := bytes.Split(token, []byte("."))
+	if len(parts) != 3 {
+		return claims, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(string(bytes.TrimRight(parts[1], "=")))
+	if err != nil {
+		return claims, false
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, false
+	}
+	return claims, true
+}
+
+// jwtExpiry returns the "exp" claim of a JWT without verifying it, ok is false if it cannot be parsed.
+func jwtExpiry(token []byte) (exp time.Time, ok bool) {
+	claims, ok := parseJWTClaims(token)
+	if !ok || claims.Exp == 0 {
+		return exp, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
+
+// jwtIssuer returns the "iss" claim of a JWT without verifying it, ok is false if it cannot be parsed.
+func jwtIssuer(token []byte) (iss string, ok bool) {
+	claims, ok := parseJWTClaims(token)
+	return claims.Iss, ok && claims.Iss != ""
+}
+
+// ServiceAccountCredentials returns an aws.CredentialsProvider exchanging the Kubernetes service account token in
+// tokenFile for credentials of roleARN with sts:AssumeRoleWithWebIdentity, ie a pod with a projected token
+// (audience "sts.amazonaws.com") whose cluster OIDC issuer is an IAM identity provider, without IRSA injecting
+// AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE. The credentials are cached and re-assumed (re-reading the token file)
+// DefaultEarlyExpiry before they expire. The credentials of cfg are not used, only its region and HTTP settings.
+func ServiceAccountCredentials(cfg aws.Config, roleARN, tokenFile string, optFns ...func(*stscreds.WebIdentityRoleOptions)) aws.CredentialsProvider {
+	client := sts.NewFromConfig(cfg, addUserAgent, func(o *sts.Options) {
+		// The retryer of cfg, if configured, takes precedence.
+		if cfg.Retryer == nil {
+			o.Retryer = NewRetryer()
+		}
+	})
+	provider := stscreds.NewWebIdentityRoleProvider(client, roleARN, ServiceAccountTokenFile(tokenFile), optFns...)
+	return aws.NewCredentialsCache(provider, func(o *aws.CredentialsCacheOptions) {
+		o.ExpiryWindow = DefaultEarlyExpiry
+	})
+}
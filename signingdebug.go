@@ -0,0 +1,70 @@
+package eksauth
+
+import (
+	"fmt"
+	"strings"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/smithy-go/logging"
+	"golang.org/x/oauth2"
+)
+
+// SigningDebugExtraKey is the oauth2.Token.Extra key containing the *SigningDebug of a token generated with
+// WithSigningDebug.
+const SigningDebugExtraKey = "eksauth.signingDebug"
+
+// SigningDebug is the SigV4 signing input of a token, to diagnose signature mismatches (ie a proxy rewriting the
+// host or headers). It contains no secret: the derived signature is only in URL.
+type SigningDebug struct {
+	// CanonicalRequest is the SigV4 canonical request.
+	CanonicalRequest string
+	// StringToSign is the SigV4 string to sign.
+	StringToSign string
+	// SignedHeaders are the lowercase names of the signed headers.
+	SignedHeaders []string
+	// URL is the presigned URL.
+	URL string
+}
+
+// String formats the debug information like the AWS SDK signing log.
+func (d *SigningDebug) String() string {
+	return fmt.Sprintf("---[ CANONICAL REQUEST ]---\n%s\n---[ STRING TO SIGN ]---\n%s\n---[ SIGNED HEADERS ]---\n%s\n---[ PRESIGNED URL ]---\n%s\n",
+		d.CanonicalRequest, d.StringToSign, strings.Join(d.SignedHeaders, ";"), d.URL)
+}
+
+// TokenSigningDebug returns the SigningDebug of a token generated with WithSigningDebug, if present.
+func TokenSigningDebug(t *oauth2.Token) (*SigningDebug, bool) {
+	if t == nil {
+		return nil, false
+	}
+	d, ok := t.Extra(SigningDebugExtraKey).(*SigningDebug)
+	return d, ok
+}
+
+// signingDebugLogger captures the signing log of the SDK v4 signer (v4.SignerOptions.LogSigning).
+type signingDebugLogger struct {
+	debug *SigningDebug
+}
+
+// Logf implements the logging.Logger interface, the arguments are the canonical string, the string to sign and the
+// signed URL message of the v4 signer.
+func (l signingDebugLogger) Logf(_ logging.Classification, _ string, v ...interface{}) {
+	if len(v) < 2 {
+		return
+	}
+	canonical, _ := v[0].(string)
+	stringToSign, _ := v[1].(string)
+	l.debug.CanonicalRequest, l.debug.StringToSign = canonical, stringToSign
+	// The signed headers are the line before the payload hash, the last line of the canonical request.
+	if lines := strings.Split(canonical, "\n"); len(lines) >= 2 {
+		l.debug.SignedHeaders = strings.Split(lines[len(lines)-2], ";")
+	}
+}
+
+// withSigningDebug returns the v4.SignerOptions function capturing the signing log into debug.
+func withSigningDebug(debug *SigningDebug) func(*v4.SignerOptions) {
+	return func(o *v4.SignerOptions) {
+		o.Logger = signingDebugLogger{debug}
+		o.LogSigning = true
+	}
+}
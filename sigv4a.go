@@ -0,0 +1,264 @@
+package eksauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go/logging"
+)
+
+// SigV4aAlgorithm is the X-Amz-Algorithm of requests signed with SigV4a (asymmetric, multi-region SigV4).
+const SigV4aAlgorithm = "AWS4-ECDSA-P256-SHA256"
+
+// sigV4aIgnoredHeaders are never signed, like the SDK v4 signer.
+var sigV4aIgnoredHeaders = map[string]bool{
+	"authorization":     true,
+	"user-agent":        true,
+	"x-amzn-trace-id":   true,
+	"expect":            true,
+	"transfer-encoding": true,
+}
+
+// SigV4aPresigner is a sts.HTTPPresignerV4 presigning requests with SigV4a, whose signatures are valid in every
+// region of RegionSet, ie for multi-region STS access points. The ECDSA P-256 key is derived from the credentials.
+// Use it with WithSigV4a (or WithPresigner), the Verifier accepts SigV4a tokens.
+type SigV4aPresigner struct {
+	// RegionSet are the regions the signature is valid in, "*" (every region) if empty.
+	RegionSet []string
+
+	mu   sync.Mutex
+	keys map[[2]string]*ecdsa.PrivateKey
+}
+
+var _ sts.HTTPPresignerV4 = (*SigV4aPresigner)(nil)
+
+// NewSigV4aPresigner creates a SigV4aPresigner for the region set, "*" if empty.
+func NewSigV4aPresigner(regionSet ...string) *SigV4aPresigner {
+	return &SigV4aPresigner{RegionSet: regionSet}
+}
+
+// regionSet returns the X-Amz-Region-Set value.
+func (p *SigV4aPresigner) regionSet() string {
+	if len(p.RegionSet) == 0 {
+		return "*"
+	}
+	return strings.Join(p.RegionSet, ",")
+}
+
+// privateKey returns the (cached) ECDSA key derived from the credentials.
+func (p *SigV4aPresigner) privateKey(credentials aws.Credentials) (*ecdsa.PrivateKey, error) {
+	id := [2]string{credentials.AccessKeyID, credentials.SecretAccessKey}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[id]; ok {
+		return key, nil
+	}
+	key, err := deriveSigV4aKey(credentials.AccessKeyID, credentials.SecretAccessKey)
+	if err != nil {
+		return nil, err
+	}
+	// Only the current credentials (and the previous ones, briefly) are in use.
+	if len(p.keys) >= 8 || p.keys == nil {
+		p.keys = make(map[[2]string]*ecdsa.PrivateKey)
+	}
+	p.keys[id] = key
+	return key, nil
+}
+
+// PresignHTTP implements the sts.HTTPPresignerV4 interface. v4.SignerOptions.LogSigning (see WithSigningDebug) logs
+// the canonical request and string to sign like the SDK v4 signer.
+func (p *SigV4aPresigner) PresignHTTP(
+	ctx context.Context, credentials aws.Credentials, r *http.Request,
+	payloadHash string, service string, region string, signingTime time.Time,
+	optFns ...func(*v4.SignerOptions),
+) (signedURI string, signedHeaders http.Header, err error) {
+	key, err := p.privateKey(credentials)
+	if err != nil {
+		return "", nil, err
+	}
+	var options v4.SignerOptions
+	for _, fn := range optFns {
+		fn(&options)
+	}
+	signingTime = signingTime.UTC()
+	req := r.Clone(ctx)
+	query := req.URL.Query()
+	// Like the v4 presigner, X-Amz-* headers (ie X-Amz-Expires) are moved to the query.
+	for name, values := range req.Header {
+		if strings.HasPrefix(strings.ToLower(name), "x-amz-") && len(values) > 0 {
+			query.Set(name, values[0])
+			req.Header.Del(name)
+		}
+	}
+	date := signingTime.Format(amzDateFormat)
+	scope := strings.Join([]string{signingTime.Format("20060102"), service, "aws4_request"}, "/")
+	query.Set("X-Amz-Algorithm", SigV4aAlgorithm)
+	query.Set("X-Amz-Credential", credentials.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", date)
+	query.Set("X-Amz-Region-Set", p.regionSet())
+	if credentials.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	signedHeaders = http.Header{"Host": {host}}
+	for name, values := range req.Header {
+		if !sigV4aIgnoredHeaders[strings.ToLower(name)] {
+			signedHeaders[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	query.Set("X-Amz-SignedHeaders", strings.Join(sigV4aHeaderNames(signedHeaders), ";"))
+	canonical := sigV4aCanonicalRequest(req.Method, req.URL, query, signedHeaders, payloadHash)
+	stringToSign := sigV4aStringToSign(date, scope, canonical)
+	digest := sha256.Sum256([]byte(stringToSign))
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", nil, err
+	}
+	if options.LogSigning && options.Logger != nil {
+		options.Logger.Logf(logging.Debug, "Request Signature:\n%s\n%s%s", canonical, stringToSign, "")
+	}
+	u := *req.URL
+	u.RawQuery = sigV4aEncodeQuery(query) + "&X-Amz-Signature=" + hex.EncodeToString(signature)
+	return u.String(), signedHeaders, nil
+}
+
+// Verify checks the SigV4a signature of a presigned request (as received by STS) with the credentials, ie for fakes
+// of STS such as the eksauthtest package. It does not check the expiry.
+func (p *SigV4aPresigner) Verify(credentials aws.Credentials, r *http.Request) error {
+	key, err := p.privateKey(credentials)
+	if err != nil {
+		return err
+	}
+	query := r.URL.Query()
+	if query.Get("X-Amz-Algorithm") != SigV4aAlgorithm {
+		return fmt.Errorf("eksauth: unexpected X-Amz-Algorithm %q", query.Get("X-Amz-Algorithm"))
+	}
+	signature, err := hex.DecodeString(query.Get("X-Amz-Signature"))
+	if err != nil {
+		return fmt.Errorf("eksauth: invalid X-Amz-Signature: %w", err)
+	}
+	query.Del("X-Amz-Signature")
+	scope, ok := strings.CutPrefix(query.Get("X-Amz-Credential"), credentials.AccessKeyID+"/")
+	if !ok {
+		return errors.New("eksauth: unknown X-Amz-Credential")
+	}
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	signedHeaders := http.Header{"Host": {host}}
+	for _, name := range strings.Split(query.Get("X-Amz-SignedHeaders"), ";") {
+		if name != "host" {
+			signedHeaders[http.CanonicalHeaderKey(name)] = r.Header.Values(name)
+		}
+	}
+	canonical := sigV4aCanonicalRequest(r.Method, r.URL, query, signedHeaders, hex.EncodeToString(sha256.New().Sum(nil)))
+	digest := sha256.Sum256([]byte(sigV4aStringToSign(query.Get("X-Amz-Date"), scope, canonical)))
+	if !ecdsa.VerifyASN1(&key.PublicKey, digest[:], signature) {
+		return errors.New("eksauth: the SigV4a signature does not match")
+	}
+	return nil
+}
+
+// sigV4aHeaderNames returns the sorted lowercase names of the headers.
+func sigV4aHeaderNames(header http.Header) []string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sigV4aCanonicalRequest returns the SigV4 canonical request (the SigV4a canonical request is identical).
+func sigV4aCanonicalRequest(method string, u *url.URL, query url.Values, header http.Header, payloadHash string) string {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	var headers strings.Builder
+	names := sigV4aHeaderNames(header)
+	for _, name := range names {
+		var values []string
+		for _, value := range header.Values(name) {
+			values = append(values, strings.Join(strings.Fields(value), " "))
+		}
+		headers.WriteString(name + ":" + strings.Join(values, ",") + "\n")
+	}
+	return strings.Join([]string{method, path, sigV4aEncodeQuery(query), headers.String(), strings.Join(names, ";"), payloadHash}, "\n")
+}
+
+// sigV4aStringToSign returns the SigV4a string to sign of a canonical request.
+func sigV4aStringToSign(date, scope, canonical string) string {
+	hash := sha256.Sum256([]byte(canonical))
+	return strings.Join([]string{SigV4aAlgorithm, date, scope, hex.EncodeToString(hash[:])}, "\n")
+}
+
+// sigV4aEncodeQuery encodes the query sorted by key with RFC 3986 escaping, as required by SigV4.
+func sigV4aEncodeQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+// p256NMinusTwo is the order of the P-256 curve minus two, candidates of the key derivation must be below it.
+var p256NMinusTwo = new(big.Int).Sub(elliptic.P256().Params().N, big.NewInt(2))
+
+// deriveSigV4aKey derives the SigV4a ECDSA P-256 private key of an access key pair: candidates are generated with
+// the NIST SP 800-108 HMAC-SHA256 counter mode KDF (keyed by "AWS4A" + secret, labelled with the algorithm and
+// bound to the access key ID and an attempt counter) until one is below N-2 (N is the order of the curve), the key
+// is the candidate plus one.
+func deriveSigV4aKey(accessKeyID, secretAccessKey string) (*ecdsa.PrivateKey, error) {
+	curve := elliptic.P256()
+	inputKey := []byte("AWS4A" + secretAccessKey)
+	for counter := 1; counter <= 0xFF; counter++ {
+		var context bytes.Buffer
+		context.WriteString(accessKeyID)
+		context.WriteByte(byte(counter))
+		candidate := new(big.Int).SetBytes(hmacKDF(inputKey, []byte(SigV4aAlgorithm), context.Bytes(), curve.Params().BitSize))
+		if candidate.Cmp(p256NMinusTwo) >= 0 {
+			continue
+		}
+		d := candidate.Add(candidate, big.NewInt(1))
+		key := &ecdsa.PrivateKey{D: d}
+		key.PublicKey.Curve = curve
+		key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(d.FillBytes(make([]byte, 32)))
+		return key, nil
+	}
+	return nil, errors.New("eksauth: failed to derive the SigV4a key")
+}
+
+// hmacKDF is the NIST SP 800-108 counter mode KDF with HMAC-SHA256 producing bitLen bits.
+func hmacKDF(key, label, context []byte, bitLen int) []byte {
+	var out []byte
+	for i := uint32(1); len(out)*8 < bitLen; i++ {
+		h := hmac.New(sha256.New, key)
+		_ = binary.Write(h, binary.BigEndian, i)
+		h.Write(label)
+		h.Write([]byte{0})
+		h.Write(context)
+		_ = binary.Write(h, binary.BigEndian, uint32(bitLen))
+		out = h.Sum(out)
+	}
+	return out[:bitLen/8]
+}
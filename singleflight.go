@@ -0,0 +1,59 @@
+package eksauth
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// flightCall is an in-flight (or completed) token refresh.
+type flightCall struct {
+	done chan struct{}
+	t    *oauth2.Token
+	err  error
+	// canceled is set if the context of the caller that ran fn was done when it returned.
+	canceled bool
+}
+
+// flightGroup collapses concurrent token refreshes into a single call (singleflight semantics).
+// The zero value is ready to use.
+type flightGroup struct {
+	mu   sync.Mutex
+	call *flightCall
+}
+
+// do calls fn unless a call is already in flight, in which case it waits for (and returns) its result.
+// fn runs with the context of the caller that started it, waiters return early if their own ctx is done.
+// If that caller's context is canceled (or its deadline passes) the failed result is not shared,
+// waiters whose own ctx is still live start (or wait for) another call instead.
+func (g *flightGroup) do(ctx context.Context, fn func(ctx context.Context) (*oauth2.Token, error)) (*oauth2.Token, error) {
+	for {
+		g.mu.Lock()
+		c := g.call
+		if c == nil {
+			break
+		}
+		g.mu.Unlock()
+		select {
+		case <-c.done:
+			if c.err != nil && c.canceled && ctx.Err() == nil {
+				continue
+			}
+			return c.t, c.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	c := &flightCall{done: make(chan struct{})}
+	g.call = c
+	g.mu.Unlock()
+
+	c.t, c.err = fn(ctx)
+	c.canceled = ctx.Err() != nil
+	g.mu.Lock()
+	g.call = nil
+	g.mu.Unlock()
+	close(c.done)
+	return c.t, c.err
+}
@@ -0,0 +1,84 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestFlightGroupLeaderCanceled(t *testing.T) {
+	var g flightGroup
+	started := make(chan struct{})
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := g.do(leaderCtx, func(ctx context.Context) (*oauth2.Token, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		leaderErr <- err
+	}()
+	<-started
+
+	waiter := make(chan error, 1)
+	var calls int
+	go func() {
+		tok, err := g.do(context.Background(), func(ctx context.Context) (*oauth2.Token, error) {
+			calls++
+			return &oauth2.Token{AccessToken: "token"}, nil
+		})
+		if err == nil && tok.AccessToken != "token" {
+			err = errors.New("unexpected token " + tok.AccessToken)
+		}
+		waiter <- err
+	}()
+	// Give the waiter time to block on the leader's call before canceling it.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader: got %v, want %v", err, context.Canceled)
+	}
+	if err := <-waiter; err != nil {
+		t.Errorf("waiter: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("waiter ran fn %d times, want 1", calls)
+	}
+}
+
+func TestFlightGroupSharesErrors(t *testing.T) {
+	var g flightGroup
+	errRefresh := errors.New("refresh failed")
+	started, release := make(chan struct{}), make(chan struct{})
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := g.do(context.Background(), func(ctx context.Context) (*oauth2.Token, error) {
+			close(started)
+			<-release
+			return nil, errRefresh
+		})
+		leaderErr <- err
+	}()
+	<-started
+
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := g.do(context.Background(), func(ctx context.Context) (*oauth2.Token, error) {
+			return nil, errors.New("waiter must not run fn")
+		})
+		waiter <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	for _, ch := range []chan error{leaderErr, waiter} {
+		if err := <-ch; !errors.Is(err, errRefresh) {
+			t.Errorf("got %v, want %v", err, errRefresh)
+		}
+	}
+}
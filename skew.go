@@ -0,0 +1,131 @@
+package eksauth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+// DefaultSkewThreshold is the clock skew below which a ClockSkew does not widen the early expiry, the Date header
+// only has a resolution of one second.
+var DefaultSkewThreshold = 5 * time.Second
+
+// ClockSkew tracks the offset of the local clock from AWS, measured from the Date header of AWS responses.
+// A ReuseTokenSource using it (see WithClockSkew) refreshes tokens earlier by the skew, since a local clock behind
+// AWS makes tokens expire sooner than their (local) expiry. It is safe for concurrent use.
+type ClockSkew struct {
+	// Threshold overrides DefaultSkewThreshold if non-zero.
+	Threshold time.Duration
+
+	mu       sync.Mutex
+	skew     time.Duration
+	observed time.Time
+}
+
+// Observe records an AWS response with the given Date received at local time, ie from the Date header.
+func (c *ClockSkew) Observe(serverTime, localTime time.Time) {
+	if serverTime.IsZero() {
+		return
+	}
+	c.mu.Lock()
+	c.skew = localTime.Sub(serverTime)
+	c.observed = localTime
+	c.mu.Unlock()
+}
+
+// Skew returns the last measured offset of the local clock (positive if it is ahead of AWS), zero if none.
+func (c *ClockSkew) Skew() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.skew
+}
+
+// Observed returns when the skew was last measured, zero if never.
+func (c *ClockSkew) Observed() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.observed
+}
+
+// margin returns the duration to add to the early expiry, the absolute skew if it reaches the threshold.
+func (c *ClockSkew) margin() time.Duration {
+	if c == nil {
+		return 0
+	}
+	threshold := c.Threshold
+	if threshold == 0 {
+		threshold = DefaultSkewThreshold
+	}
+	skew := c.Skew()
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew < threshold {
+		return 0
+	}
+	return skew
+}
+
+// observeResponse records the Date header of resp, the midpoint of the request is used as the local time.
+func (c *ClockSkew) observeResponse(resp *http.Response, start time.Time) {
+	if resp == nil {
+		return
+	}
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+	end := time.Now()
+	c.Observe(date, start.Add(end.Sub(start)/2))
+}
+
+// HTTPClient wraps an aws.HTTPClient (nil is the SDK default) so the Date header of every response is observed.
+func (c *ClockSkew) HTTPClient(client aws.HTTPClient) aws.HTTPClient {
+	if client == nil {
+		client = awshttp.NewBuildableClient()
+	}
+	if wrapped, ok := client.(*skewHTTPClient); ok && wrapped.skew == c {
+		return wrapped
+	}
+	return &skewHTTPClient{client: client, skew: c}
+}
+
+// skewHTTPClient is the aws.HTTPClient returned by ClockSkew.HTTPClient.
+type skewHTTPClient struct {
+	client aws.HTTPClient
+	skew   *ClockSkew
+}
+
+// Do implements the aws.HTTPClient interface.
+func (c *skewHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err == nil {
+		c.skew.observeResponse(resp, start)
+	}
+	return resp, err
+}
+
+// Measure sends a HEAD request to url (ie "https://sts.amazonaws.com/") and observes the Date header of the
+// response, a nil client is http.DefaultClient. The status code of the response is irrelevant.
+func (c *ClockSkew) Measure(ctx context.Context, client *http.Client, url string) (time.Duration, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	c.observeResponse(resp, start)
+	return c.Skew(), nil
+}
@@ -0,0 +1,192 @@
+package eksauth
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// SocketRequest is a request of the token socket protocol, a single line of JSON.
+type SocketRequest struct {
+	// ClusterName selects the cluster, empty selects the default cluster of the server.
+	ClusterName string `json:"cluster_name,omitempty"`
+}
+
+// SocketResponse is a response of the token socket protocol, a single line of JSON.
+type SocketResponse struct {
+	Token string `json:"token,omitempty"`
+	// Expiration is nil for errors and tokens that do not expire.
+	Expiration *time.Time `json:"expiration,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// TokenServer serves tokens over a local (unix domain) socket, so sidecars and non-Go processes on the same host
+// can fetch tokens without AWS credentials. Each connection sends newline delimited SocketRequest values and
+// receives a SocketResponse for each of them, ie:
+//
+//	$ echo '{"cluster_name":"eks-cluster-name"}' | nc -U /run/eks-auth.sock
+//	{"token":"k8s-aws-v1.aHR0cHM6Ly9zdHMu...","expiration":"2024-07-30T12:15:00Z"}
+type TokenServer struct {
+	// Source returns the token source of a cluster, ie Manager.TokenSource. The name is empty for the default cluster.
+	Source func(clusterName string) (oauth2.TokenSource, error)
+}
+
+// NewTokenServer creates a TokenServer serving tokens of a single cluster from ts, requests for other clusters fail.
+func NewTokenServer(ts oauth2.TokenSource, clusterName string) *TokenServer {
+	return &TokenServer{Source: func(name string) (oauth2.TokenSource, error) {
+		if name != "" && name != clusterName {
+			return nil, errors.New("eksauth: unknown cluster " + name)
+		}
+		return ts, nil
+	}}
+}
+
+// ListenAndServe listens on the unix domain socket at path (mode 0600, replacing a stale socket) and serves tokens
+// until ctx is done, the socket is removed when it returns.
+// The socket is created in a private (0700) directory next to path and only renamed to path once its mode is 0600,
+// so other local users can never connect to it.
+func (s *TokenServer) ListenAndServe(ctx context.Context, path string) error {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".eks-auth-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "sock")
+	l, err := net.Listen("unix", tmp)
+	if err != nil {
+		return err
+	}
+	// The listener would remove its (renamed) socket on Close.
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		l.Close()
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		l.Close()
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		l.Close()
+		return err
+	}
+	defer os.Remove(path)
+	return s.Serve(ctx, l)
+}
+
+// Serve serves tokens on l until ctx is done, it closes l and waits for open connections before returning.
+func (s *TokenServer) Serve(ctx context.Context, l net.Listener) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	stop := context.AfterFunc(ctx, func() { l.Close() })
+	defer stop()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+// serveConn serves the requests of a single connection.
+func (s *TokenServer) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	scanner := bufio.NewScanner(conn)
+	enc := json.NewEncoder(conn)
+	for scanner.Scan() {
+		if err := enc.Encode(s.handle(ctx, scanner.Bytes())); err != nil {
+			return
+		}
+	}
+}
+
+// handle answers a single request.
+func (s *TokenServer) handle(ctx context.Context, line []byte) SocketResponse {
+	var req SocketRequest
+	if len(line) > 0 {
+		if err := json.Unmarshal(line, &req); err != nil {
+			return SocketResponse{Error: "invalid request: " + err.Error()}
+		}
+	}
+	ts, err := s.Source(req.ClusterName)
+	if err != nil {
+		return SocketResponse{Error: err.Error()}
+	}
+	t, err := TokenWithContext(ctx, ts)
+	if err != nil {
+		return SocketResponse{Error: err.Error()}
+	}
+	resp := SocketResponse{Token: t.AccessToken}
+	if !t.Expiry.IsZero() {
+		resp.Expiration = &t.Expiry
+	}
+	return resp
+}
+
+// SocketTokenSource is an oauth2.TokenSource fetching tokens from a TokenServer listening on a unix domain socket.
+// It does not cache tokens, wrap it in a ReuseTokenSource.
+type SocketTokenSource struct {
+	// Path is the path of the unix domain socket.
+	Path string
+	// ClusterName selects the cluster, empty selects the default cluster of the server.
+	ClusterName string
+}
+
+// NewSocketTokenSource creates a SocketTokenSource for the socket at path.
+func NewSocketTokenSource(path, clusterName string) *SocketTokenSource {
+	return &SocketTokenSource{Path: path, ClusterName: clusterName}
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (s *SocketTokenSource) Token() (*oauth2.Token, error) {
+	return s.TokenWithContext(context.Background())
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+func (s *SocketTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if err := json.NewEncoder(conn).Encode(SocketRequest{ClusterName: s.ClusterName}); err != nil {
+		return nil, err
+	}
+	var resp SocketResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New("eksauth: token server: " + resp.Error)
+	}
+	t := &oauth2.Token{AccessToken: resp.Token}
+	if resp.Expiration != nil {
+		t.Expiry = *resp.Expiration
+	}
+	return t, nil
+}
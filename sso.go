@@ -0,0 +1,85 @@
+package eksauth
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	ssotypes "github.com/aws/aws-sdk-go-v2/service/sso/types"
+	ssooidctypes "github.com/aws/aws-sdk-go-v2/service/ssooidc/types"
+)
+
+// ErrSSOSessionExpired matches (with errors.Is) a *SSOSessionError.
+var ErrSSOSessionExpired = errors.New("eksauth: AWS SSO session is expired or invalid")
+
+// SSOSessionError is returned (wrapped) when the credentials come from IAM Identity Center (AWS SSO) and the SSO
+// session is expired, revoked or was never started, the user must run `aws sso login` (see LoginCommand).
+// It matches ErrSSOSessionExpired, ErrCredentialsExpired and ErrCredentialRetrieval with errors.Is.
+type SSOSessionError struct {
+	// Profile is the AWS profile using the SSO session, if known.
+	Profile string
+	// StartURL is the SSO start URL, if known.
+	StartURL string
+	// Err is the underlying SDK error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *SSOSessionError) Error() string {
+	msg := "AWS SSO session"
+	if e.StartURL != "" {
+		msg += " for " + e.StartURL
+	}
+	msg += " is expired or invalid, run `" + e.LoginCommand() + "`"
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// LoginCommand returns the command refreshing the SSO session, ie "aws sso login --profile name".
+func (e *SSOSessionError) LoginCommand() string {
+	if e.Profile != "" {
+		return "aws sso login --profile " + e.Profile
+	}
+	return "aws sso login"
+}
+
+// Unwrap returns the underlying error.
+func (e *SSOSessionError) Unwrap() error {
+	return e.Err
+}
+
+// Is implements matching ErrSSOSessionExpired, ErrCredentialsExpired and ErrCredentialRetrieval.
+func (e *SSOSessionError) Is(target error) bool {
+	return target == ErrSSOSessionExpired || target == ErrCredentialsExpired || target == ErrCredentialRetrieval
+}
+
+// isSSOSessionError reports if err is caused by an expired or invalid SSO session.
+func isSSOSessionError(err error) bool {
+	var invalidToken *ssocreds.InvalidTokenError
+	var unauthorized *ssotypes.UnauthorizedException
+	var invalidGrant *ssooidctypes.InvalidGrantException
+	return errors.As(err, &invalidToken) || errors.As(err, &unauthorized) || errors.As(err, &invalidGrant)
+}
+
+// ssoSession returns the profile and start URL of the SSO session used by the shared config of cfg, if any.
+// Source profiles are followed since the SSO session is used by the base credentials of a role chain.
+func ssoSession(cfg aws.Config) (profile string, startURL string) {
+	for _, source := range cfg.ConfigSources {
+		shared, ok := source.(config.SharedConfig)
+		if !ok {
+			continue
+		}
+		for sc := &shared; sc != nil; sc = sc.Source {
+			if sc.SSOSession != nil && sc.SSOSession.SSOStartURL != "" {
+				return sc.Profile, sc.SSOSession.SSOStartURL
+			}
+			if sc.SSOStartURL != "" {
+				return sc.Profile, sc.SSOStartURL
+			}
+		}
+	}
+	return "", ""
+}
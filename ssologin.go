@@ -0,0 +1,214 @@
+package eksauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+	ssooidctypes "github.com/aws/aws-sdk-go-v2/service/ssooidc/types"
+)
+
+// ErrNoSSOSession is returned by SSOLogin if the shared config of the aws.Config does not use IAM Identity Center.
+var ErrNoSSOSession = errors.New("eksauth: the AWS profile does not use an SSO session")
+
+// SSODeviceAuthorization is the device authorization the user must approve to complete SSOLogin.
+type SSODeviceAuthorization struct {
+	// Profile is the AWS profile using the SSO session, if known.
+	Profile string
+	// StartURL is the SSO start URL.
+	StartURL string
+	// VerificationURI is the page where UserCode must be entered.
+	VerificationURI string
+	// VerificationURIComplete is VerificationURI with UserCode pre-filled, it should be opened in a browser.
+	VerificationURIComplete string
+	// UserCode is the code the user must confirm.
+	UserCode string
+	// ExpiresAt is when the authorization expires.
+	ExpiresAt time.Time
+}
+
+// SSOLoginPrompt presents a device authorization to the user (ie printing the URL and opening a browser).
+// It must return promptly, SSOLogin then waits for the user to approve the authorization.
+type SSOLoginPrompt func(ctx context.Context, auth SSODeviceAuthorization) error
+
+// ssoLoginClientName is the name of the OIDC client registered by SSOLogin.
+const ssoLoginClientName = "eks-auth"
+
+// ssoLoginScopes are the registration scopes of an sso-session login, the default of `aws sso login`.
+var ssoLoginScopes = []string{"sso:account:access"}
+
+// SSOLogin is `aws sso login` for the SSO session used by the shared config of cfg: it runs the OIDC device
+// authorization flow, calls prompt with the verification URL, waits until the user approves it (or ctx is done) and
+// stores the access token in the SSO token cache (~/.aws/sso/cache) used by the SDK and the AWS CLI.
+// It returns ErrNoSSOSession if cfg was not loaded from a profile using IAM Identity Center.
+func SSOLogin(ctx context.Context, cfg aws.Config, prompt SSOLoginPrompt) error {
+	sc := ssoSharedConfig(cfg)
+	if sc == nil {
+		return ErrNoSSOSession
+	}
+	cacheKey, region, startURL, scopes := sc.SSOStartURL, sc.SSORegion, sc.SSOStartURL, []string(nil)
+	if sc.SSOSession != nil {
+		cacheKey, region, startURL, scopes = sc.SSOSession.Name, sc.SSOSession.SSORegion, sc.SSOSession.SSOStartURL, ssoLoginScopes
+	}
+	cachePath, err := ssocreds.StandardCachedTokenFilepath(cacheKey)
+	if err != nil {
+		return fmt.Errorf("eksauth: SSO login: %w", err)
+	}
+	client := ssooidc.NewFromConfig(cfg, func(o *ssooidc.Options) {
+		o.Region = region
+	})
+	reg, err := client.RegisterClient(ctx, &ssooidc.RegisterClientInput{
+		ClientName: aws.String(ssoLoginClientName),
+		ClientType: aws.String("public"),
+		Scopes:     scopes,
+	})
+	if err != nil {
+		return fmt.Errorf("eksauth: SSO login: RegisterClient: %w", err)
+	}
+	device, err := client.StartDeviceAuthorization(ctx, &ssooidc.StartDeviceAuthorizationInput{
+		ClientId:     reg.ClientId,
+		ClientSecret: reg.ClientSecret,
+		StartUrl:     aws.String(startURL),
+	})
+	if err != nil {
+		return fmt.Errorf("eksauth: SSO login: StartDeviceAuthorization: %w", err)
+	}
+	expiresAt := time.Now().Add(time.Duration(device.ExpiresIn) * time.Second)
+	if err := prompt(ctx, SSODeviceAuthorization{
+		Profile:                 sc.Profile,
+		StartURL:                startURL,
+		VerificationURI:         aws.ToString(device.VerificationUri),
+		VerificationURIComplete: aws.ToString(device.VerificationUriComplete),
+		UserCode:                aws.ToString(device.UserCode),
+		ExpiresAt:               expiresAt,
+	}); err != nil {
+		return fmt.Errorf("eksauth: SSO login: %w", err)
+	}
+	interval := time.Duration(device.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		token, err := client.CreateToken(ctx, &ssooidc.CreateTokenInput{
+			ClientId:     reg.ClientId,
+			ClientSecret: reg.ClientSecret,
+			DeviceCode:   device.DeviceCode,
+			GrantType:    aws.String("urn:ietf:params:oauth:grant-type:device_code"),
+		})
+		var pending *ssooidctypes.AuthorizationPendingException
+		var slowDown *ssooidctypes.SlowDownException
+		switch {
+		case errors.As(err, &pending):
+			continue
+		case errors.As(err, &slowDown):
+			interval += 5 * time.Second
+			continue
+		case err != nil:
+			return fmt.Errorf("eksauth: SSO login: CreateToken: %w", err)
+		}
+		cached := ssoCachedToken{
+			StartURL:              startURL,
+			Region:                region,
+			AccessToken:           aws.ToString(token.AccessToken),
+			ExpiresAt:             time.Now().Add(time.Duration(token.ExpiresIn) * time.Second).UTC().Format(time.RFC3339),
+			RefreshToken:          aws.ToString(token.RefreshToken),
+			ClientID:              aws.ToString(reg.ClientId),
+			ClientSecret:          aws.ToString(reg.ClientSecret),
+			RegistrationExpiresAt: time.Unix(reg.ClientSecretExpiresAt, 0).UTC().Format(time.RFC3339),
+		}
+		if err := cached.write(cachePath); err != nil {
+			return fmt.Errorf("eksauth: SSO login: %w", err)
+		}
+		return nil
+	}
+}
+
+// ssoCachedToken is the SSO token cache file format shared by the SDK and the AWS CLI.
+type ssoCachedToken struct {
+	StartURL              string `json:"startUrl"`
+	Region                string `json:"region"`
+	AccessToken           string `json:"accessToken"`
+	ExpiresAt             string `json:"expiresAt"`
+	RefreshToken          string `json:"refreshToken,omitempty"`
+	ClientID              string `json:"clientId,omitempty"`
+	ClientSecret          string `json:"clientSecret,omitempty"`
+	RegistrationExpiresAt string `json:"registrationExpiresAt,omitempty"`
+}
+
+// write atomically replaces the cache file at path.
+func (t ssoCachedToken) write(path string) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0o600)
+}
+
+// ssoSharedConfig returns the shared config (or source profile) of cfg using an SSO session, nil if none.
+func ssoSharedConfig(cfg aws.Config) *config.SharedConfig {
+	for _, source := range cfg.ConfigSources {
+		shared, ok := source.(config.SharedConfig)
+		if !ok {
+			continue
+		}
+		for sc := &shared; sc != nil; sc = sc.Source {
+			if (sc.SSOSession != nil && sc.SSOSession.SSOStartURL != "") || sc.SSOStartURL != "" {
+				return sc
+			}
+		}
+	}
+	return nil
+}
+
+// ssoLoginProvider is an aws.CredentialsProvider running SSOLogin when the SSO session of the wrapped provider is
+// expired, then retrying once. Concurrent failures share a single login.
+type ssoLoginProvider struct {
+	aws.CredentialsProvider
+	cfg    aws.Config
+	prompt SSOLoginPrompt
+
+	mu sync.Mutex
+}
+
+// ssoLoginCredentials wraps the credentials of cfg in an ssoLoginProvider if they use an SSO session.
+func ssoLoginCredentials(cfg aws.Config, prompt SSOLoginPrompt) aws.CredentialsProvider {
+	if cfg.Credentials == nil || prompt == nil || ssoSharedConfig(cfg) == nil {
+		return cfg.Credentials
+	}
+	return &ssoLoginProvider{CredentialsProvider: cfg.Credentials, cfg: cfg, prompt: prompt}
+}
+
+// Retrieve implements the aws.CredentialsProvider interface.
+func (p *ssoLoginProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	creds, err := p.CredentialsProvider.Retrieve(ctx)
+	if err == nil || !isSSOSessionError(err) {
+		return creds, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Another caller may have completed the login while we waited.
+	if creds, err = p.CredentialsProvider.Retrieve(ctx); err == nil || !isSSOSessionError(err) {
+		return creds, err
+	}
+	if loginErr := SSOLogin(ctx, p.cfg, p.prompt); loginErr != nil {
+		return creds, errors.Join(err, loginErr)
+	}
+	return p.CredentialsProvider.Retrieve(ctx)
+}
@@ -0,0 +1,71 @@
+package eksauth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrNoCachedToken is returned by ExportTokenState when the token source has no valid cached token.
+var ErrNoCachedToken = errors.New("eksauth: no valid cached token")
+
+// ExportTokenState serializes the cached token (value and expiry) of ts so it can be restored into a new token source
+// with WithTokenState, ie stashed in /tmp by a Lambda function to skip the credential chain on the next cold start.
+// Only *ReuseTokenSource (returned by the New* constructors) caches tokens. The state is only valid for the cluster
+// (and cluster ID) the token was generated for, callers must key stored states by cluster.
+func ExportTokenState(ts oauth2.TokenSource) ([]byte, error) {
+	reuse, ok := reuseTokenSource(ts)
+	if !ok {
+		return nil, ErrNoCachedToken
+	}
+	return reuse.ExportState()
+}
+
+// ExportState serializes the cached token, see ExportTokenState.
+func (s *ReuseTokenSource) ExportState() ([]byte, error) {
+	s.mu.Lock()
+	t := s.t
+	valid := s.valid(t)
+	s.mu.Unlock()
+	if !valid {
+		return nil, ErrNoCachedToken
+	}
+	return json.Marshal(cachedToken{
+		AccessToken: t.AccessToken,
+		TokenType:   t.TokenType,
+		Expiry:      t.Expiry,
+	})
+}
+
+// ImportState restores a token serialized by ExportState into the cache, replacing the cached token.
+// A token that would not be reused (because it is within the early expiry of expiring) is ignored.
+func (s *ReuseTokenSource) ImportState(data []byte) error {
+	t, err := parseTokenState(data)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.valid(t) {
+		s.t = t
+	}
+	return nil
+}
+
+// parseTokenState decodes a token serialized by ExportState.
+func parseTokenState(data []byte) (*oauth2.Token, error) {
+	var state cachedToken
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("eksauth: invalid token state: %w", err)
+	}
+	if state.AccessToken == "" {
+		return nil, errors.New("eksauth: invalid token state: missing access_token")
+	}
+	return &oauth2.Token{
+		AccessToken: state.AccessToken,
+		TokenType:   state.TokenType,
+		Expiry:      state.Expiry,
+	}, nil
+}
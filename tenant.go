@@ -0,0 +1,200 @@
+package eksauth
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"golang.org/x/oauth2"
+)
+
+// DefaultTenantCacheSize is the maximum number of tokens cached by a TenantCache.
+var DefaultTenantCacheSize = 10000
+
+// TenantKey identifies the tokens of a TenantCache.
+type TenantKey struct {
+	// ClusterName is the name (or ARN) of the EKS cluster.
+	ClusterName string
+	// RoleARN is the (customer) IAM role assumed to sign the token.
+	RoleARN string
+	// SourcePrincipal is the principal the token is minted on behalf of, ie a tenant or user ID, so tenants sharing
+	// a role never share tokens. NewTenantCacheFromConfig sets it as the source identity of the role session.
+	SourcePrincipal string
+}
+
+// tenantEntry is an element of the TenantCache LRU list.
+type tenantEntry struct {
+	key    TenantKey
+	t      *oauth2.Token
+	flight flightGroup
+}
+
+// TenantCache caches tokens minted on behalf of many tenants (ie a SaaS service assuming customer roles) in a single
+// bounded LRU keyed by TenantKey, instead of one token source (and its reuse wrapper) per combination. Tokens are
+// cached until EarlyExpiry before they expire, concurrent misses for a key share a single Mint call.
+// It is safe for concurrent use.
+type TenantCache struct {
+	// Mint generates a new token for the key.
+	Mint func(ctx context.Context, key TenantKey) (*oauth2.Token, error)
+	// MaxEntries bounds the cache, the least recently used token is evicted first. DefaultTenantCacheSize if zero.
+	MaxEntries int
+	// EarlyExpiry is how long before expiry cached tokens are replaced, DefaultEarlyExpiry if zero.
+	EarlyExpiry time.Duration
+
+	mu      sync.Mutex
+	entries map[TenantKey]*list.Element
+	lru     list.List
+}
+
+// NewTenantCache creates a TenantCache from a mint function.
+func NewTenantCache(mint func(ctx context.Context, key TenantKey) (*oauth2.Token, error)) *TenantCache {
+	return &TenantCache{Mint: mint}
+}
+
+// NewTenantCacheFromConfig creates a TenantCache minting tokens by assuming the RoleARN of the key (with the
+// credentials of cfg), see NewFromRole. A non-empty SourcePrincipal is the source identity of the role session.
+// Roles are assumed again for every new token, use WithAssumeRoleOptions to set the external ID or session name.
+func NewTenantCacheFromConfig(cfg aws.Config, opts ...Option) *TenantCache {
+	if cfg.Credentials != nil && !aws.IsCredentialsProvider(cfg.Credentials, (*aws.CredentialsCache)(nil)) {
+		cfg.Credentials = aws.NewCredentialsCache(cfg.Credentials)
+	}
+	return NewTenantCache(func(ctx context.Context, key TenantKey) (*oauth2.Token, error) {
+		if key.RoleARN == "" {
+			return nil, errors.New("eksauth: TenantKey has no RoleARN")
+		}
+		keyOpts := opts
+		if key.SourcePrincipal != "" {
+			keyOpts = append(append([]Option{}, opts...), WithAssumeRoleOptions(WithSourceIdentity(key.SourcePrincipal)))
+		}
+		return TokenWithContext(ctx, NewFromRole(cfg, key.RoleARN, key.ClusterName, keyOpts...))
+	})
+}
+
+// earlyExpiry returns the configured early expiry or DefaultEarlyExpiry.
+func (c *TenantCache) earlyExpiry() time.Duration {
+	if c.EarlyExpiry != 0 {
+		return c.EarlyExpiry
+	}
+	return DefaultEarlyExpiry
+}
+
+// entry returns the entry of the key, creating it and evicting the least recently used entries if required.
+func (c *TenantCache) entry(key TenantKey) *tenantEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		return elem.Value.(*tenantEntry)
+	}
+	if c.entries == nil {
+		c.entries = make(map[TenantKey]*list.Element)
+	}
+	entry := &tenantEntry{key: key}
+	c.entries[key] = c.lru.PushFront(entry)
+	maxEntries := c.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = DefaultTenantCacheSize
+	}
+	for c.lru.Len() > maxEntries {
+		c.remove(c.lru.Back())
+	}
+	return entry
+}
+
+// remove removes the element from the cache, the lock must be held.
+func (c *TenantCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*tenantEntry)
+	delete(c.entries, entry.key)
+}
+
+// valid reports if the token can be returned (it is not within EarlyExpiry of expiring).
+func (c *TenantCache) valid(t *oauth2.Token) bool {
+	return t != nil && (t.Expiry.IsZero() || time.Now().Add(c.earlyExpiry()).Before(t.Expiry))
+}
+
+// Token returns the cached token of the key, or mints (and caches) a new one.
+func (c *TenantCache) Token(ctx context.Context, key TenantKey) (*oauth2.Token, error) {
+	entry := c.entry(key)
+	c.mu.Lock()
+	t := entry.t
+	c.mu.Unlock()
+	if c.valid(t) {
+		return t, nil
+	}
+	if c.Mint == nil {
+		return nil, errors.New("eksauth: TenantCache.Mint is nil")
+	}
+	return entry.flight.do(ctx, func(ctx context.Context) (*oauth2.Token, error) {
+		t, err := c.Mint(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		entry.t = t
+		c.mu.Unlock()
+		return t, nil
+	})
+}
+
+// TokenSource returns an oauth2.TokenSource for the key backed by the cache, ctx is the base context of Token.
+func (c *TenantCache) TokenSource(ctx context.Context, key TenantKey) oauth2.TokenSource {
+	return &tenantTokenSource{cache: c, key: key, ctx: ctx}
+}
+
+// Delete discards the cached token of the key, ie after the API server rejected it.
+func (c *TenantCache) Delete(key TenantKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// Purge removes the tokens that can no longer be returned, it may be called periodically to release memory early.
+func (c *TenantCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for elem := c.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		if entry := elem.Value.(*tenantEntry); entry.t != nil && !c.valid(entry.t) {
+			c.remove(elem)
+		}
+		elem = prev
+	}
+}
+
+// Len returns the number of cached entries.
+func (c *TenantCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// tenantTokenSource is the oauth2.TokenSource returned by TenantCache.TokenSource.
+type tenantTokenSource struct {
+	cache *TenantCache
+	key   TenantKey
+	ctx   context.Context
+}
+
+// Token implements the oauth2.TokenSource interface.
+func (ts *tenantTokenSource) Token() (*oauth2.Token, error) {
+	ctx := ts.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return ts.TokenWithContext(ctx)
+}
+
+// TokenWithContext implements the ContextTokenSource interface.
+func (ts *tenantTokenSource) TokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	return ts.cache.Token(ctx, ts.key)
+}
+
+// Invalidate implements the Invalidator interface.
+func (ts *tenantTokenSource) Invalidate() {
+	ts.cache.Delete(ts.key)
+}
@@ -0,0 +1,116 @@
+package eksauth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// ErrThrottled is matched (using errors.Is) by every *ThrottledError.
+var ErrThrottled = errors.New("eksauth: request was throttled")
+
+// ThrottledError is returned when an AWS API call made by this package was throttled.
+type ThrottledError struct {
+	// Op is the operation that was throttled, ie "DescribeCluster".
+	Op string
+	// RetryAfter is the delay requested by the Retry-After response header, zero if not present.
+	RetryAfter time.Duration
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ThrottledError) Error() string {
+	return "eksauth: " + e.Op + " was throttled: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ThrottledError) Unwrap() error {
+	return e.Err
+}
+
+// Is allows errors.Is(err, ErrThrottled).
+func (e *ThrottledError) Is(target error) bool {
+	return target == ErrThrottled
+}
+
+// Retryable reports that throttled operations can be retried.
+func (e *ThrottledError) Retryable() bool {
+	return true
+}
+
+// isThrottle reports if err is a throttling error using the AWS SDK classification (retry.DefaultThrottles).
+func isThrottle(err error) bool {
+	for _, throttle := range retry.DefaultThrottles {
+		if v := throttle.IsErrorThrottle(err); v != aws.UnknownTernary {
+			return v.Bool()
+		}
+	}
+	return false
+}
+
+// retryAfter extracts the Retry-After response header from an AWS SDK error.
+func retryAfter(err error) time.Duration {
+	var respErr *smithyhttp.ResponseError
+	if !errors.As(err, &respErr) || respErr.Response == nil {
+		return 0
+	}
+	value := respErr.Response.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// wrapThrottled wraps err in a *ThrottledError if it is a throttling error, otherwise it is returned as-is.
+func wrapThrottled(op string, err error) error {
+	if err == nil || !isThrottle(err) {
+		return err
+	}
+	return &ThrottledError{Op: op, RetryAfter: retryAfter(err), Err: err}
+}
+
+// RetryAfterBackoff is a retry.BackoffDelayer that honors the Retry-After header of throttled responses,
+// falling back to exponential backoff with jitter. Delays are capped at MaxBackoff.
+type RetryAfterBackoff struct {
+	MaxBackoff time.Duration
+	Fallback   retry.BackoffDelayer
+}
+
+// BackoffDelay implements the retry.BackoffDelayer interface.
+func (b *RetryAfterBackoff) BackoffDelay(attempt int, err error) (time.Duration, error) {
+	maxBackoff := b.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = retry.DefaultMaxBackoff
+	}
+	if d := retryAfter(err); d > 0 {
+		return min(d, maxBackoff), nil
+	}
+	fallback := b.Fallback
+	if fallback == nil {
+		fallback = retry.NewExponentialJitterBackoff(maxBackoff)
+	}
+	return fallback.BackoffDelay(attempt, err)
+}
+
+// NewRetryer creates an aws.Retryer (the SDK standard retryer) whose backoff honors Retry-After headers.
+// It is used for the STS and EKS API calls made by this package unless aws.Config.Retryer is set, and may be used
+// for aws.Config.Retryer.
+func NewRetryer(optFns ...func(*retry.StandardOptions)) aws.Retryer {
+	return retry.NewStandard(append([]func(*retry.StandardOptions){func(o *retry.StandardOptions) {
+		o.Backoff = &RetryAfterBackoff{MaxBackoff: o.MaxBackoff}
+	}}, optFns...)...)
+}
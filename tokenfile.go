@@ -0,0 +1,178 @@
+package eksauth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// DefaultTokenFilePerm is the permissions of token files written by a TokenFileWriter.
+const DefaultTokenFilePerm os.FileMode = 0o600
+
+// TokenFileWriter keeps a fresh token written to a file, ie for embedded components (Envoy, Fluent Bit...) that
+// only read bearer tokens from files. Every write replaces the file atomically (write then rename) so readers never
+// see a partial token. Tokens are rewritten EarlyExpiry before they expire, Refresh forces a rewrite.
+type TokenFileWriter struct {
+	Source oauth2.TokenSource
+	// Path of the token file.
+	Path string
+	// Perm is the permissions of the file, DefaultTokenFilePerm if zero.
+	Perm os.FileMode
+	// Encode returns the file contents for a token, the access token if nil (ie an ExecCredential for other formats).
+	Encode func(t *oauth2.Token) ([]byte, error)
+	// EarlyExpiry is how long before expiry the token is rewritten, DefaultEarlyExpiry if zero.
+	EarlyExpiry time.Duration
+	// RetryInterval is the delay before retrying a failed refresh, DefaultRefreshRetryInterval if zero.
+	RetryInterval time.Duration
+	// OnWrite is called after every write of the file with the new token, it may be nil.
+	OnWrite func(t *oauth2.Token)
+	// OnError is called when a refresh or write fails, it may be nil.
+	OnError func(err error)
+
+	mu      sync.Mutex
+	t       *oauth2.Token
+	refresh chan struct{}
+}
+
+// NewTokenFileWriter creates a TokenFileWriter writing the tokens of ts to path, call Run to start writing.
+func NewTokenFileWriter(ts oauth2.TokenSource, path string) *TokenFileWriter {
+	return &TokenFileWriter{Source: ts, Path: path}
+}
+
+// Token returns the token currently in the file, nil if none was written yet.
+func (w *TokenFileWriter) Token() *oauth2.Token {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.t
+}
+
+// Healthy reports if the file holds a token that is not expired.
+func (w *TokenFileWriter) Healthy() bool {
+	t := w.Token()
+	return t != nil && (t.Expiry.IsZero() || time.Now().Before(t.Expiry))
+}
+
+// Refresh invalidates Source and makes Run rewrite the file immediately, ie on SIGHUP. It does not block.
+func (w *TokenFileWriter) Refresh() {
+	Invalidate(w.Source)
+	select {
+	case w.refreshChan() <- struct{}{}:
+	default:
+	}
+}
+
+// refreshChan returns the channel signaling Refresh calls.
+func (w *TokenFileWriter) refreshChan() chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.refresh == nil {
+		w.refresh = make(chan struct{}, 1)
+	}
+	return w.refresh
+}
+
+// Write fetches a token from Source and writes it to the file once.
+func (w *TokenFileWriter) Write(ctx context.Context) (*oauth2.Token, error) {
+	if w.Path == "" {
+		return nil, errors.New("eksauth: TokenFileWriter has no path")
+	}
+	t, err := TokenWithContext(ctx, w.Source)
+	if err != nil {
+		return nil, err
+	}
+	data := []byte(t.AccessToken)
+	if w.Encode != nil {
+		if data, err = w.Encode(t); err != nil {
+			return nil, err
+		}
+	}
+	perm := w.Perm
+	if perm == 0 {
+		perm = DefaultTokenFilePerm
+	}
+	if err := writeFileAtomic(w.Path, data, perm); err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	w.t = t
+	w.mu.Unlock()
+	if w.OnWrite != nil {
+		w.OnWrite(t)
+	}
+	return t, nil
+}
+
+// Run writes the file then keeps it fresh until ctx is done, it returns nil once ctx is done.
+// Failures are reported to OnError and retried after RetryInterval.
+func (w *TokenFileWriter) Run(ctx context.Context) error {
+	earlyExpiry := w.EarlyExpiry
+	if earlyExpiry == 0 {
+		earlyExpiry = DefaultEarlyExpiry
+	}
+	retryInterval := w.RetryInterval
+	if retryInterval == 0 {
+		retryInterval = DefaultRefreshRetryInterval
+	}
+	refresh := w.refreshChan()
+	for {
+		wait := retryInterval
+		t, err := w.Write(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil:
+			if w.OnError != nil {
+				w.OnError(err)
+			}
+		case t.Expiry.IsZero():
+			// Tokens without an expiry are only rewritten by Refresh.
+			wait = -1
+		default:
+			wait = max(time.Until(t.Expiry)-earlyExpiry, time.Second)
+		}
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if wait >= 0 {
+			timer = time.NewTimer(wait)
+			expired = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-refresh:
+		case <-expired:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// writeFileAtomic writes data to a temporary file next to path then renames it over path, so readers never see a
+// partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,47 @@
+package eksauth
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation name of the OpenTelemetry tracer.
+const tracerName = "github.com/bored-engineer/aws-eks-auth"
+
+// Span attributes recorded when generating tokens.
+const (
+	AttributeClusterName      = attribute.Key("eksauth.cluster_name")
+	AttributeRegion           = attribute.Key("eksauth.region")
+	AttributeCredentialSource = attribute.Key("eksauth.credential_source")
+)
+
+// startSpan starts the span of a token generation, if tp is nil a no-op span is returned.
+func startSpan(ctx context.Context, tp trace.TracerProvider, clusterName string) (context.Context, trace.Span) {
+	if tp == nil {
+		return ctx, noop.Span{}
+	}
+	return tp.Tracer(tracerName, trace.WithInstrumentationVersion(Version())).Start(ctx, "eksauth.Token",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(AttributeClusterName.String(clusterName)),
+	)
+}
+
+// endSpan records the provenance and error (if any) of a token generation then ends the span.
+func endSpan(span trace.Span, provenance *Provenance, err error) {
+	if provenance.Region != "" {
+		span.SetAttributes(AttributeRegion.String(provenance.Region))
+	}
+	if len(provenance.CredentialSources) > 0 {
+		span.SetAttributes(AttributeCredentialSource.String(strings.Join(provenance.CredentialSources, credentialSourceSeparator)))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
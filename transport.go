@@ -0,0 +1,81 @@
+package eksauth
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// Invalidator is implemented by token sources that cache tokens and can discard the cached token,
+// so the next call to Token generates a new one.
+type Invalidator interface {
+	Invalidate()
+}
+
+// Transport is an http.RoundTripper that sets the bearer token of every request from Source.
+// Unlike oauth2.Transport, if the API server responds 401 Unauthorized (ie the token was rejected early due to
+// clock skew or an aws-auth mapping change) and Source implements Invalidator, the cached token is discarded
+// and the request is retried once with a fresh token.
+type Transport struct {
+	Source oauth2.TokenSource
+	// Base is the underlying http.RoundTripper, http.DefaultTransport if nil.
+	Base http.RoundTripper
+}
+
+// NewTransport creates a Transport using ts and base.
+func NewTransport(ts oauth2.TokenSource, base http.RoundTripper) *Transport {
+	return &Transport{Source: ts, Base: base}
+}
+
+// base returns the underlying http.RoundTripper.
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// roundTrip sends a clone of req with the bearer token of the current token.
+func (t *Transport) roundTrip(req *http.Request) (*http.Response, error) {
+	token, err := TokenWithContext(req.Context(), t.Source)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	token.SetAuthHeader(req)
+	return t.base().RoundTrip(req)
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Source == nil {
+		return nil, errors.New("eksauth: Transport's Source is nil")
+	}
+	resp, err := t.roundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	invalidator, ok := t.Source.(Invalidator)
+	if !ok {
+		return resp, nil
+	}
+	// The request can only be retried if the body can be replayed.
+	retry := req
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return resp, nil
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry = req.Clone(req.Context())
+		retry.Body = body
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	invalidator.Invalidate()
+	return t.roundTrip(retry)
+}
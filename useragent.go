@@ -0,0 +1,55 @@
+package eksauth
+
+import (
+	"runtime/debug"
+	"sync"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// modulePath is the Go module path of this package.
+const modulePath = "github.com/bored-engineer/aws-eks-auth"
+
+// userAgentKey is the key added to the User-Agent of AWS API calls made by this package.
+const userAgentKey = "eks-auth"
+
+// Version returns the version of this module (from the build info of the binary), or "devel" if unknown.
+var Version = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	if info.Main.Path == modulePath && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return "devel"
+})
+
+// addUserAgent adds "eks-auth/<version>" to the User-Agent of the AWS API call.
+func addUserAgent(o *sts.Options) {
+	o.APIOptions = append(o.APIOptions, awsmiddleware.AddUserAgentKeyValue(userAgentKey, Version()))
+}
+
+// WithAppID sets the application identifier appended to the User-Agent of STS calls (aws.Config.AppID).
+func WithAppID(appID string) func(*sts.Options) {
+	return func(o *sts.Options) {
+		o.AppID = appID
+	}
+}
+
+// WithEKSUserAgent adds "eks-auth/<version>" and an optional application identifier to the User-Agent of EKS calls.
+func WithEKSUserAgent(appID string) func(*eks.Options) {
+	return func(o *eks.Options) {
+		if appID != "" {
+			o.AppID = appID
+		}
+		o.APIOptions = append(o.APIOptions, awsmiddleware.AddUserAgentKeyValue(userAgentKey, Version()))
+	}
+}
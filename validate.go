@@ -0,0 +1,214 @@
+package eksauth
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rules of the Violation values reported by Validate.
+const (
+	RuleEncoding        = "encoding"
+	RuleScheme          = "scheme"
+	RuleHost            = "host"
+	RulePartition       = "partition"
+	RulePath            = "path"
+	RuleQuery           = "query"
+	RuleAction          = "action"
+	RuleSignedHeaders   = "signed-headers"
+	RuleCredentialScope = "credential-scope"
+	RuleSigningRegion   = "signing-region"
+	RuleExpires         = "expires"
+	RuleDate            = "date"
+	RuleExpired         = "expired"
+)
+
+// Violation is a single reason a token failed offline validation.
+type Violation struct {
+	// Rule is the check that failed, one of the Rule* constants.
+	Rule string
+	// Message describes the violation.
+	Message string
+}
+
+// String implements the fmt.Stringer interface.
+func (v Violation) String() string {
+	return v.Rule + ": " + v.Message
+}
+
+// ValidationError is returned by Validate, it lists every violation and matches ErrInvalidToken with errors.Is.
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for idx, v := range e.Violations {
+		msgs[idx] = v.String()
+	}
+	return ErrInvalidToken.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+// Is implements matching ErrInvalidToken.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidToken
+}
+
+// ValidateOption configures the Verifier used by Validate.
+type ValidateOption func(*Verifier)
+
+// ValidateClusterIDHeader overrides the name of the signed cluster ID header, "X-K8s-Aws-Id" by default.
+func ValidateClusterIDHeader(header string) ValidateOption {
+	return func(v *Verifier) {
+		v.ClusterIDHeader = header
+	}
+}
+
+// ValidatePartition restricts tokens to an AWS partition (ie "aws-cn").
+func ValidatePartition(partition string) ValidateOption {
+	return func(v *Verifier) {
+		v.Partition = partition
+	}
+}
+
+// ValidateHostRegexp restricts the STS hosts a token may target, STSHostRegexp by default.
+func ValidateHostRegexp(re *regexp.Regexp) ValidateOption {
+	return func(v *Verifier) {
+		v.HostRegexp = re
+	}
+}
+
+// ValidateMaxClockSkew overrides DefaultMaxClockSkew.
+func ValidateMaxClockSkew(d time.Duration) ValidateOption {
+	return func(v *Verifier) {
+		v.MaxClockSkew = d
+	}
+}
+
+// ValidateClock sets the function returning the current time, time.Now by default.
+func ValidateClock(now func() time.Time) ValidateOption {
+	return func(v *Verifier) {
+		v.Now = now
+	}
+}
+
+// Validate performs the offline checks aws-iam-authenticator applies to a token without calling STS: the encoding,
+// the STS host of the partition, Action=GetCallerIdentity, a single X-Amz-Expires of at most 15 minutes, no
+// unexpected (or repeated) query parameters, the signed cluster ID header and the signing time. It returns nil or a
+// *ValidationError listing every violation. A valid token may still be rejected by STS, see Verifier.Verify.
+func Validate(token string, opts ...ValidateOption) error {
+	var v Verifier
+	for _, opt := range opts {
+		opt(&v)
+	}
+	if violations := v.Violations(token); len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// Violations returns every reason the token fails the offline checks of Validate, nil if it passes them.
+// Unlike Validate (which stops at the first failure) it reports all of them, ie for admission middleware and tests.
+func (v *Verifier) Violations(token string) []Violation {
+	var violations []Violation
+	add := func(rule, format string, args ...any) {
+		violations = append(violations, Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+	presignedURL, err := DecodeToken(token)
+	if err != nil {
+		add(RuleEncoding, "%v", err)
+		return violations
+	}
+	u, err := url.Parse(presignedURL)
+	if err != nil {
+		add(RuleEncoding, "failed to parse presigned URL: %v", err)
+		return violations
+	}
+	if u.Scheme != "https" {
+		add(RuleScheme, "unexpected scheme %q", u.Scheme)
+	}
+	hostRegexp := v.HostRegexp
+	if hostRegexp == nil {
+		hostRegexp = STSHostRegexp
+	}
+	host := u.Hostname()
+	if !hostRegexp.MatchString(host) || u.Port() != "" {
+		add(RuleHost, "unexpected host %q", u.Host)
+	}
+	if v.Partition != "" && PartitionForHost(host) != v.Partition {
+		add(RulePartition, "host %q is not in partition %s", host, v.Partition)
+	}
+	if u.Path != "/" && u.Path != "" {
+		add(RulePath, "unexpected path %q", u.Path)
+	}
+
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		add(RuleQuery, "invalid query: %v", err)
+	}
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !allowedQueryParams[key] {
+			add(RuleQuery, "unexpected query parameter %q", key)
+		} else if len(query[key]) != 1 {
+			add(RuleQuery, "query parameter %q is repeated", key)
+		}
+	}
+	if action := query.Get("Action"); action != "GetCallerIdentity" {
+		add(RuleAction, "unexpected action %q", action)
+	}
+	signedHeaders := strings.Split(strings.ToLower(query.Get("X-Amz-SignedHeaders")), ";")
+	if header := v.clusterIDHeader(); !slices.Contains(signedHeaders, strings.ToLower(header)) {
+		add(RuleSignedHeaders, "%s is not a signed header", header)
+	}
+
+	// X-Amz-Credential is <access key>/<date>/<region>/<service>/aws4_request, SigV4a scopes have no region
+	// (the signature is valid in every region of X-Amz-Region-Set).
+	scope := strings.Split(query.Get("X-Amz-Credential"), "/")
+	if query.Get("X-Amz-Algorithm") == SigV4aAlgorithm {
+		if len(scope) != 4 || scope[2] != "sts" || query.Get("X-Amz-Region-Set") == "" {
+			add(RuleCredentialScope, "invalid SigV4a X-Amz-Credential scope")
+		}
+	} else if len(scope) != 5 || scope[3] != "sts" {
+		add(RuleCredentialScope, "invalid X-Amz-Credential scope")
+	} else {
+		if region := regionForHost(host); region != "" && region != scope[2] {
+			add(RuleSigningRegion, "signing region %q does not match host %q", scope[2], host)
+		}
+		if PartitionForRegion(scope[2]) != PartitionForHost(host) {
+			add(RuleSigningRegion, "signing region %q is not in the partition of host %q", scope[2], host)
+		}
+	}
+
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires <= 0 || time.Duration(expires)*time.Second > MaxExpiration {
+		add(RuleExpires, "invalid X-Amz-Expires %q", query.Get("X-Amz-Expires"))
+	}
+	signingTime, err := time.Parse(amzDateFormat, query.Get("X-Amz-Date"))
+	if err != nil {
+		add(RuleDate, "invalid X-Amz-Date %q", query.Get("X-Amz-Date"))
+		return violations
+	}
+	skew := v.MaxClockSkew
+	if skew == 0 {
+		skew = DefaultMaxClockSkew
+	}
+	now := v.now()
+	if signingTime.After(now.Add(skew)) {
+		add(RuleDate, "X-Amz-Date %s is in the future", signingTime.Format(time.RFC3339))
+	}
+	if !now.Before(signingTime.Add(MaxExpiration)) {
+		add(RuleExpired, "token expired at %s", signingTime.Add(MaxExpiration).Format(time.RFC3339))
+	}
+	return violations
+}
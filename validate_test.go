@@ -0,0 +1,103 @@
+package eksauth_test
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"github.com/bored-engineer/aws-eks-auth/eksauthtest"
+)
+
+// testToken returns a valid token for the cluster signed by the fake STS at its frozen time.
+func testToken(t *testing.T, fake *eksauthtest.STS, clusterName string, opts ...eksauth.Option) string {
+	t.Helper()
+	tok, err := fake.TokenSource(clusterName, opts...).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok.AccessToken
+}
+
+// mutateToken returns the token with its presigned URL modified by fn.
+func mutateToken(t *testing.T, token string, fn func(u *url.URL, query url.Values)) string {
+	t.Helper()
+	presignedURL, err := eksauth.DecodeToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(presignedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	fn(u, query)
+	u.RawQuery = query.Encode()
+	return eksauth.V1Format.Encode(u.String())
+}
+
+func TestValidate(t *testing.T) {
+	fake := eksauthtest.NewSTS()
+	defer fake.Close()
+	token := testToken(t, fake, "eks-cluster-name")
+	now := eksauthtest.DefaultTime
+
+	tests := []struct {
+		name  string
+		token string
+		now   time.Time
+		opts  []eksauth.ValidateOption
+		rule  string
+	}{
+		{name: "valid", token: token, now: now},
+		{name: "unknown format", token: "k8s-aws-v2.x", now: now, rule: eksauth.RuleEncoding},
+		{name: "wrong host", token: mutateToken(t, token, func(u *url.URL, _ url.Values) { u.Host = "sts.example.com" }), now: now, rule: eksauth.RuleHost},
+		{name: "port", token: mutateToken(t, token, func(u *url.URL, _ url.Values) { u.Host += ":8443" }), now: now, rule: eksauth.RuleHost},
+		{name: "scheme", token: mutateToken(t, token, func(u *url.URL, _ url.Values) { u.Scheme = "http" }), now: now, rule: eksauth.RuleScheme},
+		{name: "path", token: mutateToken(t, token, func(u *url.URL, _ url.Values) { u.Path = "/redirect" }), now: now, rule: eksauth.RulePath},
+		{name: "wrong action", token: mutateToken(t, token, func(_ *url.URL, q url.Values) { q.Set("Action", "AssumeRole") }), now: now, rule: eksauth.RuleAction},
+		{name: "unexpected query parameter", token: mutateToken(t, token, func(_ *url.URL, q url.Values) { q.Set("RoleArn", "x") }), now: now, rule: eksauth.RuleQuery},
+		{name: "repeated query parameter", token: mutateToken(t, token, func(_ *url.URL, q url.Values) { q.Add("Action", "GetCallerIdentity") }), now: now, rule: eksauth.RuleQuery},
+		{name: "cluster ID header not signed", token: mutateToken(t, token, func(_ *url.URL, q url.Values) { q.Set("X-Amz-SignedHeaders", "host") }), now: now, rule: eksauth.RuleSignedHeaders},
+		{name: "cluster ID header override", token: token, now: now, opts: []eksauth.ValidateOption{eksauth.ValidateClusterIDHeader("X-Other-Id")}, rule: eksauth.RuleSignedHeaders},
+		{name: "credential scope", token: mutateToken(t, token, func(_ *url.URL, q url.Values) { q.Set("X-Amz-Credential", "AKIA/20240101/us-east-1/iam/aws4_request") }), now: now, rule: eksauth.RuleCredentialScope},
+		{name: "signing region", token: mutateToken(t, token, func(u *url.URL, _ url.Values) { u.Host = "sts.eu-west-1.amazonaws.com" }), now: now, rule: eksauth.RuleSigningRegion},
+		{name: "expires too long", token: mutateToken(t, token, func(_ *url.URL, q url.Values) { q.Set("X-Amz-Expires", "3600") }), now: now, rule: eksauth.RuleExpires},
+		{name: "invalid date", token: mutateToken(t, token, func(_ *url.URL, q url.Values) { q.Set("X-Amz-Date", "yesterday") }), now: now, rule: eksauth.RuleDate},
+		{name: "expired", token: token, now: now.Add(eksauth.MaxExpiration), rule: eksauth.RuleExpired},
+		{name: "nearly expired", token: token, now: now.Add(eksauth.MaxExpiration - time.Second)},
+		{name: "signed in the future", token: token, now: now.Add(-eksauth.DefaultMaxClockSkew - time.Second), rule: eksauth.RuleDate},
+		{name: "tolerated skew", token: token, now: now.Add(-eksauth.DefaultMaxClockSkew)},
+		{name: "custom skew", token: token, now: now.Add(-time.Minute), opts: []eksauth.ValidateOption{eksauth.ValidateMaxClockSkew(time.Second)}, rule: eksauth.RuleDate},
+		{name: "partition mismatch", token: token, now: now, opts: []eksauth.ValidateOption{eksauth.ValidatePartition("aws-cn")}, rule: eksauth.RulePartition},
+		{name: "partition", token: token, now: now, opts: []eksauth.ValidateOption{eksauth.ValidatePartition("aws")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := tt.now
+			err := eksauth.Validate(tt.token, append([]eksauth.ValidateOption{eksauth.ValidateClock(func() time.Time { return now })}, tt.opts...)...)
+			if tt.rule == "" {
+				if err != nil {
+					t.Fatalf("got %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, eksauth.ErrInvalidToken) {
+				t.Fatalf("got %v, want %v", err, eksauth.ErrInvalidToken)
+			}
+			var verr *eksauth.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("got %T, want *eksauth.ValidationError", err)
+			}
+			var rules []string
+			for _, v := range verr.Violations {
+				rules = append(rules, v.Rule)
+			}
+			if !slices.Contains(rules, tt.rule) {
+				t.Errorf("got violations %v, want rule %q", verr.Violations, tt.rule)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package eksauth
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// ErrInvalidToken is returned (wrapped) by Verifier.Verify when a token is rejected.
+var ErrInvalidToken = errors.New("eksauth: invalid token")
+
+// ErrSTSUnavailable is returned (wrapped) by Verifier.Verify when sts:GetCallerIdentity could not be called or was
+// throttled or failed (HTTP 429 or 5xx), the token was neither accepted nor rejected and the call may be retried.
+var ErrSTSUnavailable = errors.New("eksauth: sts:GetCallerIdentity unavailable")
+
+// DefaultVerifyTimeout is the timeout of the sts:GetCallerIdentity call made by a Verifier without a Client.
+var DefaultVerifyTimeout = 10 * time.Second
+
+// DefaultVerifyMaxIdleConnsPerHost is the number of idle connections per STS host kept by NewVerifyTransport,
+// http.DefaultTransport only keeps 2 so a busy webhook would dial (and TLS handshake) for most verifications.
+var DefaultVerifyMaxIdleConnsPerHost = 100
+
+// DefaultMaxClockSkew is the (future) clock skew tolerated by a Verifier when checking the X-Amz-Date of a token.
+var DefaultMaxClockSkew = 5 * time.Minute
+
+// STSHostRegexp matches the regional, global, FIPS and dual-stack STS endpoints of every AWS partition.
+var STSHostRegexp = regexp.MustCompile(`^sts(-fips)?(\.[a-z0-9-]+)?\.(amazonaws\.com(\.cn)?|api\.aws|api\.amazonwebservices\.com\.cn|c2s\.ic\.gov|sc2s\.sgov\.gov)$`)
+
+// allowedQueryParams are the only query parameters accepted in a presigned sts:GetCallerIdentity URL.
+var allowedQueryParams = map[string]bool{
+	"Action":               true,
+	"Version":              true,
+	"X-Amz-Algorithm":      true,
+	"X-Amz-Credential":     true,
+	"X-Amz-Date":           true,
+	"X-Amz-Expires":        true,
+	"X-Amz-Region-Set":     true,
+	"X-Amz-Security-Token": true,
+	"X-Amz-Signature":      true,
+	"X-Amz-SignedHeaders":  true,
+}
+
+// invalidTokenCodes are the STS error codes of a 4xx response rejecting the presigned request itself.
+var invalidTokenCodes = map[string]bool{
+	"AccessDenied":          true,
+	"ExpiredToken":          true,
+	"IncompleteSignature":   true,
+	"InvalidClientTokenId":  true,
+	"RequestExpired":        true,
+	"SignatureDoesNotMatch": true,
+}
+
+// throttlingCodes are the STS error codes of a (400) throttling response.
+var throttlingCodes = map[string]bool{
+	"Throttling":           true,
+	"ThrottlingException":  true,
+	"RequestLimitExceeded": true,
+}
+
+// Verifier verifies tokens generated by a TokenSource (or aws-iam-authenticator/aws eks get-token), it is the server
+// side mirror image of TokenSource: the presigned URL is validated then executed to obtain the caller identity.
+type Verifier struct {
+	// ClusterName is the cluster ID (or EKS cluster ARN) the token must be signed for (the x-k8s-aws-id header).
+	ClusterName string
+	// Partition restricts tokens (and caller identities) to an AWS partition (ie "aws-cn") if non-empty.
+	Partition string
+	// ClusterIDHeader overrides the name of the signed cluster ID header, "X-K8s-Aws-Id" by default.
+	ClusterIDHeader string
+	// Client is used to execute the presigned request, if nil a client with DefaultVerifyTimeout sharing the
+	// transport of NewVerifyTransport between every Verifier is used.
+	// Redirects are never followed (a token could otherwise send the request anywhere), the CheckRedirect of Client
+	// is replaced by one returning http.ErrUseLastResponse.
+	Client *http.Client
+	// HostRegexp restricts the STS hosts a token may target, if nil STSHostRegexp is used.
+	HostRegexp *regexp.Regexp
+	// MaxClockSkew is the tolerated difference between the X-Amz-Date and now, if zero DefaultMaxClockSkew is used.
+	MaxClockSkew time.Duration
+	// Now returns the current time, if nil time.Now is used.
+	Now func() time.Time
+}
+
+// NewVerifier creates a new Verifier for the named cluster.
+func NewVerifier(clusterName string) *Verifier {
+	return &Verifier{ClusterName: clusterName}
+}
+
+// invalidToken returns an error wrapping ErrInvalidToken.
+func invalidToken(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidToken, fmt.Sprintf(format, args...))
+}
+
+// now returns the current time using the Now func (if set).
+func (v *Verifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+// clusterIDHeader returns the name of the cluster ID header.
+func (v *Verifier) clusterIDHeader() string {
+	if v.ClusterIDHeader != "" {
+		return v.ClusterIDHeader
+	}
+	return clusterIDHeader
+}
+
+// Validate checks the presigned URL of a token without executing it, returning the parsed URL.
+// It fails with the first of the Violations of the token.
+func (v *Verifier) Validate(token string) (*url.URL, error) {
+	if violations := v.Violations(token); len(violations) > 0 {
+		return nil, invalidToken("%s", violations[0].Message)
+	}
+	presignedURL, err := DecodeToken(token)
+	if err != nil {
+		return nil, invalidToken("%v", err)
+	}
+	u, err := url.Parse(presignedURL)
+	if err != nil {
+		return nil, invalidToken("failed to parse presigned URL: %v", err)
+	}
+	return u, nil
+}
+
+// getCallerIdentityResponse is the JSON response of sts:GetCallerIdentity.
+type getCallerIdentityResponse struct {
+	GetCallerIdentityResponse struct {
+		GetCallerIdentityResult struct {
+			Account string `json:"Account"`
+			Arn     string `json:"Arn"`
+			UserID  string `json:"UserId"`
+		} `json:"GetCallerIdentityResult"`
+	} `json:"GetCallerIdentityResponse"`
+}
+
+// stsError is the error of an STS response, in JSON (as requested by Verify) or XML.
+type stsError struct {
+	Code    string
+	Message string
+}
+
+// parseSTSError returns the error code and message of an STS error response body, empty if they cannot be parsed.
+func parseSTSError(body []byte) stsError {
+	var out struct {
+		Error stsError
+	}
+	if json.Unmarshal(body, &out) == nil && out.Error.Code != "" {
+		return out.Error
+	}
+	_ = xml.Unmarshal(body, &out)
+	return out.Error
+}
+
+// NewVerifyTransport returns a clone of http.DefaultTransport tuned for the sts:GetCallerIdentity calls of a
+// Verifier: connections are kept alive and reused (up to DefaultVerifyMaxIdleConnsPerHost idle connections per STS
+// host) and HTTP/2 is attempted. Adjust the returned transport (ie MaxIdleConnsPerHost, IdleConnTimeout or
+// MaxConnsPerHost) and use it as the Transport of Verifier.Client to tune it.
+func NewVerifyTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConnsPerHost = DefaultVerifyMaxIdleConnsPerHost
+	t.ForceAttemptHTTP2 = true
+	return t
+}
+
+// defaultVerifyTransport is the transport of the client of a Verifier without a Client.
+var defaultVerifyTransport = sync.OnceValue(NewVerifyTransport)
+
+// noRedirect is the CheckRedirect of the HTTP client used by Verify.
+func noRedirect(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// client returns the HTTP client executing presigned requests, never following redirects.
+func (v *Verifier) client() *http.Client {
+	if v.Client == nil {
+		return &http.Client{Transport: defaultVerifyTransport(), Timeout: DefaultVerifyTimeout, CheckRedirect: noRedirect}
+	}
+	client := *v.Client
+	client.CheckRedirect = noRedirect
+	return &client
+}
+
+// Verify validates the token then executes the presigned sts:GetCallerIdentity request returning the caller Identity.
+// Only GET requests are executed, with the cluster ID header and the signed x-amz-* headers of an Encoder format.
+// Rejected tokens fail with ErrInvalidToken, STS failures (throttling, 5xx or network errors) with ErrSTSUnavailable.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	u, err := v.Validate(token)
+	if err != nil {
+		return nil, err
+	}
+	var signedHeader http.Header
+	if presigned, err := DecodeTokenRequest(token); err == nil {
+		signedHeader = presigned.SignedHeader
+		if presigned.Method != "" && presigned.Method != http.MethodGet {
+			return nil, invalidToken("unexpected method %q", presigned.Method)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Only the x-amz-* headers that are signed are sent, the cluster ID header is always the verified one.
+	signedHeaders := strings.Split(strings.ToLower(u.Query().Get("X-Amz-SignedHeaders")), ";")
+	for name, values := range signedHeader {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") && slices.Contains(signedHeaders, lower) {
+			req.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	clusterName, _ := splitClusterName(v.ClusterName)
+	req.Header.Set(v.clusterIDHeader(), clusterName)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := v.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSTSUnavailable, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read response: %w", ErrSTSUnavailable, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		stsErr := parseSTSError(body)
+		status := strings.TrimSpace(resp.Status + " " + stsErr.Code)
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 || throttlingCodes[stsErr.Code]:
+			return nil, fmt.Errorf("%w: returned %s", ErrSTSUnavailable, status)
+		case resp.StatusCode >= 400 && resp.StatusCode < 500 && invalidTokenCodes[stsErr.Code]:
+			return nil, invalidToken("sts:GetCallerIdentity returned %s: %s", stsErr.Code, stsErr.Message)
+		default:
+			return nil, fmt.Errorf("eksauth: sts:GetCallerIdentity returned %s", status)
+		}
+	}
+
+	var out getCallerIdentityResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("eksauth: failed to parse sts:GetCallerIdentity response: %w", err)
+	}
+	result := out.GetCallerIdentityResponse.GetCallerIdentityResult
+	if result.Arn == "" {
+		return nil, errors.New("eksauth: sts:GetCallerIdentity response is missing the caller ARN")
+	}
+	if parsed, err := arn.Parse(result.Arn); err != nil {
+		return nil, fmt.Errorf("eksauth: sts:GetCallerIdentity returned an invalid caller ARN: %w", err)
+	} else if partition := PartitionForHost(u.Hostname()); parsed.Partition != partition {
+		return nil, invalidToken("caller %s is not in partition %s", result.Arn, partition)
+	}
+	return &Identity{
+		Account: result.Account,
+		ARN:     result.Arn,
+		UserID:  result.UserID,
+	}, nil
+}
@@ -0,0 +1,254 @@
+package eksauth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"github.com/bored-engineer/aws-eks-auth/eksauthtest"
+)
+
+// requestFormat is an Encoder carrying the whole presigned request as JSON.
+type requestFormat struct{}
+
+func (requestFormat) Prefix() string { return "eksauth-test-request." }
+
+func (f requestFormat) Encode(presignedURL string) string {
+	token, _ := f.EncodeRequest(&eksauth.PresignedRequest{URL: presignedURL})
+	return token
+}
+
+func (f requestFormat) Decode(token string) (string, error) {
+	req, err := f.DecodeRequest(token)
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
+
+func (f requestFormat) EncodeRequest(req *eksauth.PresignedRequest) (string, error) {
+	b, err := json.Marshal(req)
+	return f.Prefix() + base64.RawURLEncoding.EncodeToString(b), err
+}
+
+func (f requestFormat) DecodeRequest(token string) (*eksauth.PresignedRequest, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, f.Prefix()))
+	if err != nil {
+		return nil, err
+	}
+	var req eksauth.PresignedRequest
+	return &req, json.Unmarshal(b, &req)
+}
+
+func init() {
+	if err := eksauth.RegisterTokenFormat(requestFormat{}); err != nil {
+		panic(err)
+	}
+}
+
+// stubSTS is an httptest STS answering every request with handler, its client routes the real STS hosts to it.
+type stubSTS struct {
+	*httptest.Server
+	requests atomic.Int32
+}
+
+func newStubSTS(t *testing.T, handler http.HandlerFunc) *stubSTS {
+	s := &stubSTS{}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// roundTripperFunc implements http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// verifier returns a Verifier for the cluster executing presigned requests against the stub.
+func (s *stubSTS) verifier(clusterName string) *eksauth.Verifier {
+	target, _ := url.Parse(s.URL)
+	base := s.Client().Transport
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+		return base.RoundTrip(req)
+	})}
+	return &eksauth.Verifier{ClusterName: clusterName, Client: client, Now: func() time.Time { return eksauthtest.DefaultTime }}
+}
+
+// writeIdentity writes a successful JSON sts:GetCallerIdentity response.
+func writeIdentity(w http.ResponseWriter, identity eksauth.Identity) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"GetCallerIdentityResponse":{"GetCallerIdentityResult":{"Account":"` + identity.Account +
+		`","Arn":"` + identity.ARN + `","UserId":"` + identity.UserID + `"}}}`))
+}
+
+func TestVerify(t *testing.T) {
+	fake := eksauthtest.NewSTS()
+	defer fake.Close()
+	identity, err := fake.Verifier("eks-cluster-name").Verify(context.Background(), testToken(t, fake, "eks-cluster-name"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *identity != eksauthtest.DefaultIdentity {
+		t.Errorf("got %+v, want %+v", *identity, eksauthtest.DefaultIdentity)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	fake := eksauthtest.NewSTS()
+	defer fake.Close()
+	token := testToken(t, fake, "eks-cluster-name")
+
+	t.Run("wrong cluster", func(t *testing.T) {
+		// The signature of the x-k8s-aws-id header does not match the verified cluster.
+		if _, err := fake.Verifier("other-cluster").Verify(context.Background(), token); !errors.Is(err, eksauth.ErrInvalidToken) {
+			t.Errorf("got %v, want %v", err, eksauth.ErrInvalidToken)
+		}
+	})
+	t.Run("offline violation", func(t *testing.T) {
+		before := fake.Requests()
+		wrongAction := mutateToken(t, token, func(_ *url.URL, q url.Values) { q.Set("Action", "AssumeRole") })
+		if _, err := fake.Verifier("eks-cluster-name").Verify(context.Background(), wrongAction); !errors.Is(err, eksauth.ErrInvalidToken) {
+			t.Errorf("got %v, want %v", err, eksauth.ErrInvalidToken)
+		}
+		if fake.Requests() != before {
+			t.Error("a token failing the offline checks was sent to STS")
+		}
+	})
+	t.Run("caller partition mismatch", func(t *testing.T) {
+		stub := newStubSTS(t, func(w http.ResponseWriter, r *http.Request) {
+			writeIdentity(w, eksauth.Identity{Account: "123456789012", ARN: "arn:aws-cn:iam::123456789012:user/eksauthtest", UserID: "AIDA"})
+		})
+		if _, err := stub.verifier("eks-cluster-name").Verify(context.Background(), token); !errors.Is(err, eksauth.ErrInvalidToken) {
+			t.Errorf("got %v, want %v", err, eksauth.ErrInvalidToken)
+		}
+	})
+}
+
+func TestVerifyResponseStatus(t *testing.T) {
+	fake := eksauthtest.NewSTS()
+	defer fake.Close()
+	token := testToken(t, fake, "eks-cluster-name")
+
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		invalid     bool
+		unavailable bool
+	}{
+		{name: "signature mismatch", status: http.StatusForbidden, body: `{"Error":{"Code":"SignatureDoesNotMatch","Message":"mismatch","Type":"Sender"}}`, invalid: true},
+		{name: "access denied xml", status: http.StatusForbidden, body: `<ErrorResponse><Error><Code>AccessDenied</Code><Message>denied</Message></Error></ErrorResponse>`, invalid: true},
+		{name: "expired", status: http.StatusForbidden, body: `{"Error":{"Code":"ExpiredToken","Message":"expired"}}`, invalid: true},
+		{name: "throttling", status: http.StatusBadRequest, body: `{"Error":{"Code":"Throttling","Message":"Rate exceeded"}}`, unavailable: true},
+		{name: "too many requests", status: http.StatusTooManyRequests, unavailable: true},
+		{name: "internal error", status: http.StatusInternalServerError, body: `{"Error":{"Code":"InternalFailure"}}`, unavailable: true},
+		{name: "service unavailable", status: http.StatusServiceUnavailable, unavailable: true},
+		{name: "unknown client error", status: http.StatusBadRequest, body: `{"Error":{"Code":"InvalidAction"}}`},
+		{name: "unparsable forbidden", status: http.StatusForbidden, body: "forbidden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newStubSTS(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			})
+			_, err := stub.verifier("eks-cluster-name").Verify(context.Background(), token)
+			if err == nil {
+				t.Fatal("got nil error")
+			}
+			if got := errors.Is(err, eksauth.ErrInvalidToken); got != tt.invalid {
+				t.Errorf("errors.Is(%v, ErrInvalidToken) = %v, want %v", err, got, tt.invalid)
+			}
+			if got := errors.Is(err, eksauth.ErrSTSUnavailable); got != tt.unavailable {
+				t.Errorf("errors.Is(%v, ErrSTSUnavailable) = %v, want %v", err, got, tt.unavailable)
+			}
+		})
+	}
+}
+
+func TestVerifyDoesNotFollowRedirects(t *testing.T) {
+	fake := eksauthtest.NewSTS()
+	defer fake.Close()
+	stub := newStubSTS(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "https://sts.amazonaws.com/elsewhere", http.StatusFound)
+			return
+		}
+		writeIdentity(w, eksauthtest.DefaultIdentity)
+	})
+	// The client of the stub has no CheckRedirect, the Verifier must not follow redirects regardless.
+	if _, err := stub.verifier("eks-cluster-name").Verify(context.Background(), testToken(t, fake, "eks-cluster-name")); err == nil {
+		t.Error("got nil error for a redirect")
+	}
+	if n := stub.requests.Load(); n != 1 {
+		t.Errorf("got %d requests, want 1 (redirect followed)", n)
+	}
+}
+
+func TestVerifySignedHeaders(t *testing.T) {
+	fake := eksauthtest.NewSTS()
+	defer fake.Close()
+	presignedURL, err := eksauth.DecodeToken(mutateToken(t, testToken(t, fake, "eks-cluster-name"), func(_ *url.URL, q url.Values) {
+		q.Set("X-Amz-SignedHeaders", "host;x-amz-signed;x-k8s-aws-id")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var header http.Header
+	stub := newStubSTS(t, func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		writeIdentity(w, eksauthtest.DefaultIdentity)
+	})
+	format := requestFormat{}
+
+	token, _ := format.EncodeRequest(&eksauth.PresignedRequest{
+		URL:    presignedURL,
+		Method: http.MethodGet,
+		SignedHeader: http.Header{
+			"X-Amz-Signed":      {"signed"},
+			"X-Amz-Unsigned":    {"unsigned"},
+			"X-K8s-Aws-Id":      {"other-cluster"},
+			"Cookie":            {"session=1"},
+			"Content-Type":      {"application/x-www-form-urlencoded"},
+			"X-Forwarded-For":   {"10.0.0.1"},
+			"Transfer-Encoding": {"chunked"},
+		},
+	})
+	if _, err := stub.verifier("eks-cluster-name").Verify(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("X-Amz-Signed"); got != "signed" {
+		t.Errorf("X-Amz-Signed: got %q, want %q", got, "signed")
+	}
+	if got := header.Get("X-K8s-Aws-Id"); got != "eks-cluster-name" {
+		t.Errorf("X-K8s-Aws-Id: got %q, want %q", got, "eks-cluster-name")
+	}
+	for _, name := range []string{"X-Amz-Unsigned", "Cookie", "Content-Type", "X-Forwarded-For"} {
+		if got := header.Get(name); got != "" {
+			t.Errorf("%s: got %q, want it not to be sent", name, got)
+		}
+	}
+
+	post, _ := format.EncodeRequest(&eksauth.PresignedRequest{URL: presignedURL, Method: http.MethodPost})
+	before := stub.requests.Load()
+	if _, err := stub.verifier("eks-cluster-name").Verify(context.Background(), post); !errors.Is(err, eksauth.ErrInvalidToken) {
+		t.Errorf("POST: got %v, want %v", err, eksauth.ErrInvalidToken)
+	}
+	if stub.requests.Load() != before {
+		t.Error("a POST token was sent to STS")
+	}
+}
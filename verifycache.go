@@ -0,0 +1,207 @@
+package eksauth
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultVerifyCacheSize is the maximum number of results cached by a CachingVerifier.
+var DefaultVerifyCacheSize = 10000
+
+// DefaultVerifyBatchConcurrency is the number of tokens verified concurrently by CachingVerifier.VerifyBatch.
+var DefaultVerifyBatchConcurrency = 16
+
+// Limiter limits the rate of sts:GetCallerIdentity calls made by a CachingVerifier, *rate.Limiter
+// (golang.org/x/time/rate) implements it.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// verifyEntry is an element of the CachingVerifier LRU list.
+type verifyEntry struct {
+	key      [sha256.Size]byte
+	identity *Identity
+	expires  time.Time
+}
+
+// CachingVerifier wraps a Verifier caching the Identity of every verified token until the token expires and
+// rate limiting (and bounding the concurrency of) the sts:GetCallerIdentity calls, so an authentication webhook
+// survives request storms. Only successful verifications are cached. It is safe for concurrent use.
+type CachingVerifier struct {
+	Verifier *Verifier
+	// MaxEntries bounds the cache, the least recently used result is evicted first. DefaultVerifyCacheSize if zero.
+	MaxEntries int
+	// Limiter (optional) is waited on before every sts:GetCallerIdentity call.
+	Limiter Limiter
+	// BatchConcurrency is the number of tokens VerifyBatch verifies concurrently, DefaultVerifyBatchConcurrency if zero.
+	BatchConcurrency int
+	// MaxConcurrent (optional) bounds the number of in-flight sts:GetCallerIdentity calls, further calls queue.
+	// It must not be changed after the first call to Verify.
+	MaxConcurrent int
+	// Timeout (optional) is the deadline of a verification that is not cached, including the time spent waiting for
+	// the Limiter and in the MaxConcurrent queue. Verifications still queued when it passes fail with ErrSTSUnavailable.
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	lru     list.List
+	sem     chan struct{}
+}
+
+// NewCachingVerifier creates a CachingVerifier for v, limiter may be nil.
+func NewCachingVerifier(v *Verifier, limiter Limiter) *CachingVerifier {
+	return &CachingVerifier{Verifier: v, Limiter: limiter}
+}
+
+// maxEntries returns the configured size or DefaultVerifyCacheSize.
+func (c *CachingVerifier) maxEntries() int {
+	if c.MaxEntries > 0 {
+		return c.MaxEntries
+	}
+	return DefaultVerifyCacheSize
+}
+
+// Verify returns the cached Identity of the token, or verifies it using Verifier.Verify.
+// The token is always validated (see Verifier.Validate), so expired tokens are rejected even if cached.
+func (c *CachingVerifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	u, err := c.Verifier.Validate(token)
+	if err != nil {
+		return nil, err
+	}
+	key := sha256.Sum256([]byte(token))
+	now := c.Verifier.now()
+
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*verifyEntry)
+		if now.Before(entry.expires) {
+			c.lru.MoveToFront(elem)
+			c.mu.Unlock()
+			return entry.identity, nil
+		}
+		c.remove(elem)
+	}
+	c.mu.Unlock()
+
+	identity, err := c.verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := parsePresignedURL(u.String())
+	if err != nil {
+		return identity, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[[sha256.Size]byte]*list.Element)
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	c.entries[key] = c.lru.PushFront(&verifyEntry{key: key, identity: identity, expires: parsed.Expiry()})
+	for c.lru.Len() > c.maxEntries() {
+		c.remove(c.lru.Back())
+	}
+	return identity, nil
+}
+
+// semaphore returns the semaphore bounding the in-flight calls, nil if MaxConcurrent is not set.
+func (c *CachingVerifier) semaphore() chan struct{} {
+	if c.MaxConcurrent <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sem == nil {
+		c.sem = make(chan struct{}, c.MaxConcurrent)
+	}
+	return c.sem
+}
+
+// verify calls Verifier.Verify within Timeout once the Limiter and the MaxConcurrent semaphore allow it.
+func (c *CachingVerifier) verify(ctx context.Context, token string) (*Identity, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	if c.Limiter != nil {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if sem := c.semaphore(); sem != nil {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %d sts:GetCallerIdentity calls in flight: %w", ErrSTSUnavailable, c.MaxConcurrent, ctx.Err())
+		}
+	}
+	return c.Verifier.Verify(ctx, token)
+}
+
+// remove removes the element from the cache, the lock must be held.
+func (c *CachingVerifier) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*verifyEntry)
+	delete(c.entries, entry.key)
+}
+
+// Len returns the number of cached results.
+func (c *CachingVerifier) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// VerifyResult is the result of verifying one of the tokens of CachingVerifier.VerifyBatch.
+type VerifyResult struct {
+	Identity *Identity
+	Err      error
+}
+
+// VerifyBatch verifies the tokens concurrently (BatchConcurrency at a time) with Verify, ie for audit tooling
+// scanning captured tokens, sharing the cache and the Limiter. Repeated tokens are only verified once.
+// The results are in the order of tokens, tokens not verified before ctx is done fail with its error.
+func (c *CachingVerifier) VerifyBatch(ctx context.Context, tokens []string) []VerifyResult {
+	results := make([]VerifyResult, len(tokens))
+	first := make(map[string]int, len(tokens))
+	var unique []int
+	for idx, token := range tokens {
+		if _, ok := first[token]; !ok {
+			first[token] = idx
+			unique = append(unique, idx)
+		}
+	}
+	concurrency := c.BatchConcurrency
+	if concurrency <= 0 {
+		concurrency = DefaultVerifyBatchConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, idx := range unique {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[idx].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[idx].Identity, results[idx].Err = c.Verify(ctx, tokens[idx])
+		}()
+	}
+	wg.Wait()
+	for idx, token := range tokens {
+		results[idx] = results[first[token]]
+	}
+	return results
+}
@@ -0,0 +1,221 @@
+package eksauth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	eksauth "github.com/bored-engineer/aws-eks-auth"
+	"github.com/bored-engineer/aws-eks-auth/eksauthtest"
+)
+
+func TestCachingVerifier(t *testing.T) {
+	ctx := context.Background()
+	fake := eksauthtest.NewSTS()
+	defer fake.Close()
+	// tokens signed a second apart are distinct.
+	token := func(idx int) string {
+		return testToken(t, fake, "eks-cluster-name", eksauth.WithSigningTime(eksauthtest.DefaultTime.Add(time.Duration(idx)*time.Second)))
+	}
+	verify := func(c *eksauth.CachingVerifier, token string, wantRequests int) {
+		t.Helper()
+		identity, err := c.Verify(ctx, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *identity != eksauthtest.DefaultIdentity {
+			t.Errorf("got %+v, want %+v", *identity, eksauthtest.DefaultIdentity)
+		}
+		if got := fake.Requests(); got != wantRequests {
+			t.Errorf("got %d sts:GetCallerIdentity calls, want %d", got, wantRequests)
+		}
+	}
+
+	t.Run("hit and miss", func(t *testing.T) {
+		base := fake.Requests()
+		c := eksauth.NewCachingVerifier(fake.Verifier("eks-cluster-name"), nil)
+		verify(c, token(0), base+1)
+		verify(c, token(0), base+1)
+		verify(c, token(1), base+2)
+		if c.Len() != 2 {
+			t.Errorf("got %d entries, want 2", c.Len())
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		defer func(now time.Time) { fake.Now = now }(fake.Now)
+		base := fake.Requests()
+		c := eksauth.NewCachingVerifier(fake.Verifier("eks-cluster-name"), nil)
+		verify(c, token(0), base+1)
+		fake.Now = eksauthtest.DefaultTime.Add(eksauth.MaxExpiration - time.Second)
+		verify(c, token(0), base+1)
+		fake.Now = eksauthtest.DefaultTime.Add(eksauth.MaxExpiration)
+		if _, err := c.Verify(ctx, token(0)); !errors.Is(err, eksauth.ErrInvalidToken) {
+			t.Errorf("got %v, want %v", err, eksauth.ErrInvalidToken)
+		}
+		if got := fake.Requests(); got != base+1 {
+			t.Errorf("got %d sts:GetCallerIdentity calls, want %d", got, base+1)
+		}
+	})
+
+	t.Run("LRU eviction", func(t *testing.T) {
+		base := fake.Requests()
+		c := &eksauth.CachingVerifier{Verifier: fake.Verifier("eks-cluster-name"), MaxEntries: 2}
+		verify(c, token(0), base+1)
+		verify(c, token(1), base+2)
+		// token 0 becomes the most recently used, token 1 is evicted by token 2.
+		verify(c, token(0), base+2)
+		verify(c, token(2), base+3)
+		if c.Len() != 2 {
+			t.Errorf("got %d entries, want 2", c.Len())
+		}
+		verify(c, token(0), base+3)
+		verify(c, token(1), base+4)
+	})
+
+	t.Run("failures are not cached", func(t *testing.T) {
+		base := fake.Requests()
+		c := eksauth.NewCachingVerifier(fake.Verifier("other-cluster"), nil)
+		for range 2 {
+			if _, err := c.Verify(ctx, token(0)); !errors.Is(err, eksauth.ErrInvalidToken) {
+				t.Errorf("got %v, want %v", err, eksauth.ErrInvalidToken)
+			}
+		}
+		if got := fake.Requests(); got != base+2 {
+			t.Errorf("got %d sts:GetCallerIdentity calls, want %d", got, base+2)
+		}
+		if c.Len() != 0 {
+			t.Errorf("got %d entries, want 0", c.Len())
+		}
+	})
+}
+
+func TestCachingVerifierVerifyBatch(t *testing.T) {
+	fake := eksauthtest.NewSTS()
+	defer fake.Close()
+	valid := testToken(t, fake, "eks-cluster-name")
+	other := testToken(t, fake, "eks-cluster-name", eksauth.WithSigningTime(eksauthtest.DefaultTime.Add(time.Second)))
+	wrongCluster := testToken(t, fake, "other-cluster")
+
+	c := &eksauth.CachingVerifier{Verifier: fake.Verifier("eks-cluster-name"), BatchConcurrency: 2}
+	results := c.VerifyBatch(context.Background(), []string{valid, wrongCluster, "garbage", valid, other, valid})
+	if len(results) != 6 {
+		t.Fatalf("got %d results, want 6", len(results))
+	}
+	for _, idx := range []int{0, 3, 4, 5} {
+		if results[idx].Err != nil || *results[idx].Identity != eksauthtest.DefaultIdentity {
+			t.Errorf("result %d: got %+v", idx, results[idx])
+		}
+	}
+	for _, idx := range []int{1, 2} {
+		if !errors.Is(results[idx].Err, eksauth.ErrInvalidToken) {
+			t.Errorf("result %d: got %v, want %v", idx, results[idx].Err, eksauth.ErrInvalidToken)
+		}
+	}
+	// The repeated token is verified once, the garbage token is rejected offline.
+	if got := fake.Requests(); got != 3 {
+		t.Errorf("got %d sts:GetCallerIdentity calls, want 3", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for idx, result := range c.VerifyBatch(ctx, []string{wrongCluster, wrongCluster}) {
+		if result.Err == nil {
+			t.Errorf("result %d: got nil error for a canceled context", idx)
+		}
+	}
+}
+
+func TestCachingVerifierMaxConcurrent(t *testing.T) {
+	fake := eksauthtest.NewSTS()
+	defer fake.Close()
+	var (
+		mu                    sync.Mutex
+		inFlight, maxInFlight int
+	)
+	inFlightNow := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return inFlight
+	}
+	release := make(chan struct{})
+	stub := newStubSTS(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		writeIdentity(w, eksauthtest.DefaultIdentity)
+	})
+	token := func(idx int) string {
+		return testToken(t, fake, "eks-cluster-name", eksauth.WithSigningTime(eksauthtest.DefaultTime.Add(time.Duration(idx)*time.Second)))
+	}
+	c := &eksauth.CachingVerifier{Verifier: stub.verifier("eks-cluster-name"), MaxConcurrent: 2}
+
+	errs := make(chan error, 3)
+	for _, idx := range []int{0, 1} {
+		go func(token string) {
+			_, err := c.Verify(context.Background(), token)
+			errs <- err
+		}(token(idx))
+	}
+	for deadline := time.Now().Add(2 * time.Second); inFlightNow() < 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the calls did not start")
+		}
+	}
+
+	// A call queued past its deadline fails without reaching STS.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Verify(ctx, token(2)); !errors.Is(err, eksauth.ErrSTSUnavailable) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, eksauth.ErrSTSUnavailable)
+	}
+
+	// A queued call proceeds once a call completes.
+	go func(token string) {
+		_, err := c.Verify(context.Background(), token)
+		errs <- err
+	}(token(3))
+	close(release)
+	for range 3 {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if n := maxInFlight; n != 2 {
+		t.Errorf("got %d concurrent sts:GetCallerIdentity calls, want 2", n)
+	}
+	if n := stub.requests.Load(); n != 3 {
+		t.Errorf("got %d sts:GetCallerIdentity calls, want 3", n)
+	}
+}
+
+func TestCachingVerifierTimeout(t *testing.T) {
+	fake := eksauthtest.NewSTS()
+	defer fake.Close()
+	stub := newStubSTS(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	c := &eksauth.CachingVerifier{Verifier: stub.verifier("eks-cluster-name"), Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	if _, err := c.Verify(context.Background(), testToken(t, fake, "eks-cluster-name")); !errors.Is(err, eksauth.ErrSTSUnavailable) {
+		t.Errorf("got %v, want %v", err, eksauth.ErrSTSUnavailable)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Verify returned after %s", elapsed)
+	}
+}
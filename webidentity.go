@@ -0,0 +1,39 @@
+package eksauth
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"golang.org/x/oauth2"
+)
+
+// WebIdentityCredentials returns a cached aws.CredentialsProvider that assumes roleARN with sts:AssumeRoleWithWebIdentity
+// using the web identity token returned by token, ie a projected service account token (IRSA).
+// The credentials of cfg are not used, only its region and HTTP settings.
+func WebIdentityCredentials(cfg aws.Config, roleARN string, token stscreds.IdentityTokenRetriever, optFns ...func(*stscreds.WebIdentityRoleOptions)) aws.CredentialsProvider {
+	client := sts.NewFromConfig(cfg, addUserAgent, func(o *sts.Options) {
+		// The retryer of cfg, if configured, takes precedence.
+		if cfg.Retryer == nil {
+			o.Retryer = NewRetryer()
+		}
+	})
+	return aws.NewCredentialsCache(stscreds.NewWebIdentityRoleProvider(client, roleARN, token, optFns...))
+}
+
+// NewFromWebIdentity creates a new oauth2.TokenSource that assumes roleARN with a web identity token before generating
+// tokens for the EKS cluster, ie a pod using IRSA in one cluster that needs tokens for another cluster.
+// The token is retrieved every time the role is (re-)assumed. The role takes precedence over EKSAUTH_ROLE_ARN.
+func NewFromWebIdentity(cfg aws.Config, roleARN string, token stscreds.IdentityTokenRetriever, clusterName string, opts ...Option) oauth2.TokenSource {
+	env := loadEnv()
+	env.RoleARN = ""
+	o := newOptions(env, opts)
+	o.applyConfig(&cfg)
+	cfg.Credentials = WebIdentityCredentials(cfg, roleARN, token, o.webIdentityOptions...)
+	return newFromPresignClient(presignClientFromConfig(cfg, env, o), clusterName, env, o)
+}
+
+// NewFromWebIdentityFile is NewFromWebIdentity reading the web identity token from tokenFile,
+// which is re-read whenever the role is (re-)assumed so rotated tokens are picked up, see ServiceAccountTokenFile.
+func NewFromWebIdentityFile(cfg aws.Config, roleARN, tokenFile, clusterName string, opts ...Option) oauth2.TokenSource {
+	return NewFromWebIdentity(cfg, roleARN, ServiceAccountTokenFile(tokenFile), clusterName, opts...)
+}